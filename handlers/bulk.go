@@ -0,0 +1,432 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"example.com/tasksapi/models"
+	"example.com/tasksapi/store"
+)
+
+// BulkCreateTasks cria as tarefas de {"tasks": [...]}. Cada item é validado pelo
+// TaskService; os válidos são gravados num único lote e o resultado vem por item.
+// Com ?atomic=true, um item inválido impede a criação de todos
+func (a *API) BulkCreateTasks(w http.ResponseWriter, r *http.Request) {
+	atomic := r.URL.Query().Get("atomic") == "true"
+	var req models.BulkCreateRequest
+	if models.HandleError(w, decodeBulkRequest(r, &req), http.StatusBadRequest) {
+		return
+	}
+	if models.HandleError(w, checkBulkSize(len(req.Tasks), "tasks"), http.StatusBadRequest) {
+		return
+	}
+
+	items := make([]models.BulkItemResult, len(req.Tasks))
+	var valid []models.Task
+	var positions []int
+	for i, t := range req.Tasks {
+		items[i].Index = i
+		t.Tags = models.NormalizeTags(t.Tags)
+		err := a.service.ValidateCreate(t)
+		if err == nil && t.ParentID != "" {
+			_, err = a.parentChain(r.Context(), t.ParentID)
+		}
+		if err == nil && t.ProjectID != "" {
+			err = a.checkProject(r.Context(), t.ProjectID)
+		}
+		if err != nil {
+			items[i].Status, items[i].Error = a.bulkError(r, err)
+			continue
+		}
+		valid = append(valid, t)
+		positions = append(positions, i)
+	}
+	if atomic && len(valid) < len(req.Tasks) {
+		a.writeAbortedBatch(w, r, items)
+		return
+	}
+
+	if len(valid) > 0 {
+		create := store.CreateMany
+		if atomic {
+			create = store.CreateManyAtomic
+		}
+		results, err := create(r.Context(), a.store, valid)
+		if models.HandleError(w, a.storeError(err), http.StatusInternalServerError) {
+			return
+		}
+		failed := false
+		for j, res := range results {
+			if res.Err == nil {
+				results[j].Err = a.confirmCreated(r.Context(), res.Task)
+				failed = failed || results[j].Err != nil
+			}
+		}
+		if atomic && failed {
+			// O lote atômico é desfeito inteiro: as demais tarefas criadas são removidas
+			for j, res := range results {
+				if res.Err != nil {
+					continue
+				}
+				if err := a.store.Purge(r.Context(), res.Task.ID); err != nil && !errors.Is(err, store.ErrNotFound) {
+					results[j].Err = err
+					continue
+				}
+				results[j].Err = store.ErrBatchAborted
+			}
+		}
+		for j, res := range results {
+			a.fillBulkItem(r, &items[positions[j]], res, http.StatusCreated)
+		}
+	}
+	a.writeBulkResponse(w, items, atomic)
+}
+
+// BulkUpdateTasks aplica o mesmo merge patch às tarefas de "ids" e/ou às que casam com os
+// filtros da query string (os mesmos de GET /tasks). Com os dois, só as tarefas listadas
+// que casam com os filtros são alteradas. Com ?atomic=true, basta um item falhar
+// (validação, PreventCompletedTaskEdits, filtro) para nenhum ser alterado
+func (a *API) BulkUpdateTasks(w http.ResponseWriter, r *http.Request) {
+	atomic := r.URL.Query().Get("atomic") == "true"
+	var req models.BulkUpdateRequest
+	if models.HandleError(w, decodeBulkRequest(r, &req), http.StatusBadRequest) {
+		return
+	}
+	if len(req.Patch) == 0 {
+		models.WriteError(w, models.NewValidationError("patch must have at least one field"), http.StatusBadRequest)
+		return
+	}
+	q, ids, err := a.bulkTargets(r, req.IDs)
+	if models.HandleError(w, err, http.StatusBadRequest) {
+		return
+	}
+	checks, err := a.checkBulkUpdate(r.Context(), ids, req.Patch)
+	if models.HandleError(w, a.storeError(err), http.StatusInternalServerError) {
+		return
+	}
+
+	update := store.UpdateMany
+	if atomic {
+		update = store.UpdateManyAtomic
+	}
+	before := make(map[string]models.Task, len(ids))
+	applied := make(map[string]map[string]interface{}, len(ids))
+	results, err := update(r.Context(), a.store, ids, func(current models.Task) (map[string]interface{}, error) {
+		if !q.Match(current) {
+			return nil, models.NewBusinessRuleError("task does not match the filter")
+		}
+		if err := checks.rejected[current.ID]; err != nil {
+			return nil, err
+		}
+		if !checks.covers(current) {
+			return nil, store.ErrVersionConflict
+		}
+		// Os validadores podem normalizar o patch; cada item parte de uma cópia
+		p := make(map[string]interface{}, len(req.Patch))
+		for k, v := range req.Patch {
+			p[k] = v
+		}
+		if err := a.service.ValidateUpdateWith(current, checks.rels[current.ID], p); err != nil {
+			return nil, err
+		}
+		before[current.ID], applied[current.ID] = current, p
+		return p, nil
+	})
+	if models.HandleError(w, a.storeError(err), http.StatusInternalServerError) {
+		return
+	}
+	a.confirmBulkUpdate(r.Context(), results, before, applied, atomic)
+	items := make([]models.BulkItemResult, len(results))
+	for i, res := range results {
+		items[i].Index = i
+		a.fillBulkItem(r, &items[i], res, http.StatusOK)
+	}
+	a.writeBulkResponse(w, items, atomic)
+}
+
+// BulkDeleteTasks move para a lixeira as tarefas de "ids" e/ou as que casam com os filtros.
+// Com ?atomic=true, só exclui se todas as tarefas listadas puderem ser excluídas
+func (a *API) BulkDeleteTasks(w http.ResponseWriter, r *http.Request) {
+	atomic := r.URL.Query().Get("atomic") == "true"
+	var req models.BulkDeleteRequest
+	if models.HandleError(w, decodeBulkRequest(r, &req), http.StatusBadRequest) {
+		return
+	}
+	q, ids, err := a.bulkTargets(r, req.IDs)
+	if models.HandleError(w, err, http.StatusBadRequest) {
+		return
+	}
+
+	// Com ids e filtros, as tarefas listadas que não casam com os filtros não são excluídas
+	items := make([]models.BulkItemResult, len(ids))
+	targets := ids
+	var positions []int
+	if len(req.IDs) > 0 && len(q.Filters) > 0 {
+		matched, err := a.matchingIDs(r, q)
+		if models.HandleError(w, a.storeError(err), http.StatusInternalServerError) {
+			return
+		}
+		targets = nil
+		for i, id := range ids {
+			if !matched[id] {
+				items[i].Status, items[i].Error = a.bulkError(r, models.NewBusinessRuleError("task does not match the filter"))
+				continue
+			}
+			targets = append(targets, id)
+			positions = append(positions, i)
+		}
+	}
+
+	for i, id := range ids {
+		items[i].Index = i
+		items[i].ID = id
+	}
+	// Subtarefas ativas que ficam fora do lote impedem a exclusão do pai
+	rejected, err := a.rejectedDeletes(r.Context(), targets)
+	if models.HandleError(w, a.storeError(err), http.StatusInternalServerError) {
+		return
+	}
+	if len(rejected) > 0 {
+		var kept []string
+		var keptPositions []int
+		for j, id := range targets {
+			i := j
+			if positions != nil {
+				i = positions[j]
+			}
+			if err := rejected[id]; err != nil {
+				items[i].Status, items[i].Error = a.bulkError(r, err)
+				continue
+			}
+			kept = append(kept, id)
+			keptPositions = append(keptPositions, i)
+		}
+		targets, positions = kept, keptPositions
+	}
+	if atomic && len(targets) < len(ids) {
+		a.writeAbortedBatch(w, r, items)
+		return
+	}
+	if len(targets) > 0 {
+		del := store.DeleteMany
+		if atomic {
+			del = store.DeleteManyAtomic
+		}
+		results, err := del(r.Context(), a.store, targets)
+		if models.HandleError(w, a.storeError(err), http.StatusInternalServerError) {
+			return
+		}
+		if models.HandleError(w, a.storeError(a.confirmBulkDelete(r.Context(), results, atomic)), http.StatusInternalServerError) {
+			return
+		}
+		for j, res := range results {
+			i := j
+			if positions != nil {
+				i = positions[j]
+			}
+			a.fillBulkItem(r, &items[i], res, http.StatusNoContent)
+			items[i].Task = nil
+		}
+	}
+	a.writeBulkResponse(w, items, atomic)
+}
+
+// confirmBulkUpdate confere cada item gravado com confirmUpdate. Num lote atômico, basta
+// um item desfeito para que os demais também sejam
+func (a *API) confirmBulkUpdate(ctx context.Context, results []store.BulkResult, before map[string]models.Task, applied map[string]map[string]interface{}, atomic bool) {
+	failed := false
+	for i, res := range results {
+		if res.Err == nil {
+			results[i].Err = a.confirmUpdate(ctx, before[res.Task.ID], res.Task, applied[res.Task.ID])
+			failed = failed || results[i].Err != nil
+		}
+	}
+	if !atomic || !failed {
+		return
+	}
+	for i, res := range results {
+		if res.Err != nil {
+			continue
+		}
+		revert, err := models.RevertPatch(before[res.Task.ID], applied[res.Task.ID])
+		if err == nil {
+			_, err = a.store.UpdateIfVersion(ctx, res.Task.ID, res.Task.Version, revert)
+		}
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		results[i].Err = store.ErrBatchAborted
+	}
+}
+
+// confirmBulkDelete confere com confirmDeleted as tarefas excluídas do lote; as
+// restauradas saem com erro. Num lote atômico, as demais também são restauradas
+func (a *API) confirmBulkDelete(ctx context.Context, results []store.BulkResult, atomic bool) error {
+	var deleted []string
+	for _, res := range results {
+		if res.Err == nil {
+			deleted = append(deleted, res.Task.ID)
+		}
+	}
+	if len(deleted) == 0 {
+		return nil
+	}
+	restored, err := a.confirmDeleted(ctx, deleted...)
+	if err != nil || len(restored) == 0 {
+		return err
+	}
+	for i, res := range results {
+		if res.Err != nil {
+			continue
+		}
+		if err := restored[res.Task.ID]; err != nil {
+			results[i].Err = err
+			continue
+		}
+		if atomic {
+			if _, err := a.store.Restore(ctx, res.Task.ID); err != nil && !errors.Is(err, store.ErrNotFound) {
+				results[i].Err = err
+				continue
+			}
+			results[i].Err = store.ErrBatchAborted
+		}
+	}
+	return nil
+}
+
+// bulkTargets interpreta os filtros da query string e escolhe as tarefas do lote: os ids
+// recebidos ou, sem eles, as tarefas ativas que casam com os filtros
+func (a *API) bulkTargets(r *http.Request, ids []string) (models.TaskQuery, []string, error) {
+	filters, err := models.ParseFilters(r.URL.Query())
+	if err != nil {
+		return models.TaskQuery{}, nil, err
+	}
+	q := models.TaskQuery{Filters: filters}
+	if len(ids) > 0 {
+		if err := checkBulkSize(len(ids), "ids"); err != nil {
+			return q, nil, err
+		}
+		seen := make(map[string]struct{}, len(ids))
+		for _, id := range ids {
+			if _, dup := seen[id]; dup {
+				return q, nil, models.NewValidationError("duplicated id: " + id)
+			}
+			seen[id] = struct{}{}
+		}
+		return q, ids, nil
+	}
+	if len(filters) == 0 {
+		return q, nil, models.NewValidationError("ids or at least one filter is required")
+	}
+
+	sel := q
+	sel.Limit = models.MaxBulkItems
+	page, err := a.store.Query(r.Context(), sel)
+	if err != nil {
+		return q, nil, a.storeError(err)
+	}
+	if page.Total > models.MaxBulkItems {
+		return q, nil, models.NewValidationError("filter matches " + strconv.Itoa(page.Total) + " tasks, the limit is " + strconv.Itoa(models.MaxBulkItems))
+	}
+	ids = make([]string, len(page.Tasks))
+	for i, t := range page.Tasks {
+		ids[i] = t.ID
+	}
+	return q, ids, nil
+}
+
+// matchingIDs retorna os ids das tarefas ativas que casam com os filtros
+func (a *API) matchingIDs(r *http.Request, q models.TaskQuery) (map[string]bool, error) {
+	page, err := a.store.Query(r.Context(), q)
+	if err != nil {
+		return nil, err
+	}
+	matched := make(map[string]bool, len(page.Tasks))
+	for _, t := range page.Tasks {
+		matched[t.ID] = true
+	}
+	return matched, nil
+}
+
+// fillBulkItem converte o resultado do Store no resultado do item
+func (a *API) fillBulkItem(r *http.Request, item *models.BulkItemResult, res store.BulkResult, status int) {
+	item.ID = res.Task.ID
+	if res.Err != nil {
+		item.Status, item.Error = a.bulkError(r, res.Err)
+		return
+	}
+	t := res.Task
+	item.Status = status
+	item.Task = &t
+}
+
+// bulkError converte o erro de um item em APIError, como as rotas de uma tarefa fazem
+func (a *API) bulkError(r *http.Request, err error) (int, *models.APIError) {
+	var apiErr *models.APIError
+	if !errors.As(a.versionError(r, err), &apiErr) {
+		apiErr = &models.APIError{Code: http.StatusInternalServerError, Message: err.Error()}
+	}
+	return apiErr.Code, apiErr
+}
+
+// decodeBulkRequest lê o corpo JSON de uma operação em lote; corpo vazio é aceito
+func decodeBulkRequest(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+func checkBulkSize(n int, field string) error {
+	if n == 0 {
+		return models.NewValidationError(field + " must not be empty")
+	}
+	if n > models.MaxBulkItems {
+		return models.NewValidationError(field + " must have at most " + strconv.Itoa(models.MaxBulkItems) + " items")
+	}
+	return nil
+}
+
+// writeAbortedBatch responde um lote atômico rejeitado antes de chegar ao Store: os itens
+// sem erro próprio são marcados como não aplicados
+func (a *API) writeAbortedBatch(w http.ResponseWriter, r *http.Request, items []models.BulkItemResult) {
+	for i := range items {
+		if items[i].Error == nil {
+			items[i].Task = nil
+			items[i].Status, items[i].Error = a.bulkError(r, store.ErrBatchAborted)
+		}
+	}
+	a.writeBulkResponse(w, items, true)
+}
+
+// writeBulkResponse responde 200 com os resultados por item. Um lote atômico com falhas
+// não foi aplicado: a resposta é 422 e traz em error o relatório combinado das falhas
+func (a *API) writeBulkResponse(w http.ResponseWriter, items []models.BulkItemResult, atomic bool) {
+	resp := models.BulkResponse{Results: make([]models.BulkItemResult, 0, len(items)), Atomic: atomic}
+	var reasons []string
+	for _, item := range items {
+		resp.Add(item)
+		if item.Error != nil && item.Status != http.StatusFailedDependency {
+			reasons = append(reasons, "item "+strconv.Itoa(item.Index)+": "+item.Error.Message)
+		}
+	}
+	status := http.StatusOK
+	if atomic && resp.Failed > 0 {
+		status = http.StatusUnprocessableEntity
+		resp.Succeeded = 0
+		resp.Error = &models.APIError{
+			Code:    status,
+			Message: "atomic batch aborted: " + strconv.Itoa(len(reasons)) + " of " + strconv.Itoa(len(items)) + " items failed",
+			Detail:  strings.Join(reasons, "; "),
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(resp)
+}
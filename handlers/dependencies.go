@@ -0,0 +1,446 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"example.com/tasksapi/models"
+	"example.com/tasksapi/store"
+)
+
+var errDependencyCycle = models.NewBusinessRuleError("dependency would create a cycle")
+
+// AddDependency marca a tarefa como bloqueada pela tarefa de task_id. Adicionar uma
+// dependência que já existe não altera nada
+func (a *API) AddDependency(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	var req models.DependencyRequest
+	if models.HandleError(w, json.NewDecoder(r.Body).Decode(&req), http.StatusBadRequest) {
+		return
+	}
+	if strings.TrimSpace(req.TaskID) == "" {
+		models.WriteError(w, models.NewValidationError("task_id is required"), http.StatusBadRequest)
+		return
+	}
+	current, err := a.store.Get(r.Context(), id)
+	if models.HandleError(w, a.storeError(err), http.StatusInternalServerError) {
+		return
+	}
+	if !ifMatch(r, current.Version) {
+		models.WriteError(w, errPreconditionFailed, http.StatusPreconditionFailed)
+		return
+	}
+	if containsID(current.BlockedBy, req.TaskID) {
+		writeTask(w, current)
+		return
+	}
+	if models.HandleError(w, a.storeError(a.checkDependency(r.Context(), id, req.TaskID)), http.StatusInternalServerError) {
+		return
+	}
+
+	var before models.Task
+	t, err := a.store.UpdateWith(r.Context(), id, func(current models.Task) (map[string]interface{}, error) {
+		if !ifMatch(r, current.Version) {
+			return nil, errPreconditionFailed
+		}
+		before = current
+		if containsID(current.BlockedBy, req.TaskID) {
+			return map[string]interface{}{"blocked_by": current.BlockedBy}, nil
+		}
+		blockedBy := append(append([]string(nil), current.BlockedBy...), req.TaskID)
+		return map[string]interface{}{"blocked_by": blockedBy}, nil
+	})
+	if models.HandleError(w, a.versionError(r, err), http.StatusInternalServerError) {
+		return
+	}
+	if !containsID(before.BlockedBy, req.TaskID) {
+		if models.HandleError(w, a.storeError(a.confirmDependency(r.Context(), before, t, req.TaskID)), http.StatusInternalServerError) {
+			return
+		}
+	}
+	writeTask(w, t)
+}
+
+// confirmDependency procura ciclos de novo depois de gravar a dependência. A busca não é
+// serializada com outras escritas: se duas dependências opostas forem adicionadas ao mesmo
+// tempo, pelo menos uma enxerga a outra aqui, é desfeita e responde 409
+func (a *API) confirmDependency(ctx context.Context, before, after models.Task, blocker string) error {
+	cause := a.checkDependency(ctx, after.ID, blocker)
+	if cause == nil {
+		return nil
+	}
+	revert, err := models.RevertPatch(before, map[string]interface{}{"blocked_by": nil})
+	if err != nil {
+		return err
+	}
+	if _, err := a.store.UpdateIfVersion(ctx, after.ID, after.Version, revert); err != nil {
+		return err
+	}
+	return concurrentError(cause)
+}
+
+// RemoveDependency desfaz o bloqueio da tarefa pela tarefa de blocker_id
+func (a *API) RemoveDependency(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	_, err := a.store.UpdateWith(r.Context(), vars["id"], func(current models.Task) (map[string]interface{}, error) {
+		if !ifMatch(r, current.Version) {
+			return nil, errPreconditionFailed
+		}
+		if !containsID(current.BlockedBy, vars["blocker_id"]) {
+			return nil, models.NewNotFoundError("dependency not found: " + vars["blocker_id"])
+		}
+		var blockedBy []string
+		for _, b := range current.BlockedBy {
+			if b != vars["blocker_id"] {
+				blockedBy = append(blockedBy, b)
+			}
+		}
+		if len(blockedBy) == 0 {
+			return map[string]interface{}{"blocked_by": nil}, nil
+		}
+		return map[string]interface{}{"blocked_by": blockedBy}, nil
+	})
+	if models.HandleError(w, a.versionError(r, err), http.StatusInternalServerError) {
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TaskDependencies responde o grafo de dependências da tarefa nos dois sentidos
+func (a *API) TaskDependencies(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	task, err := a.store.Get(r.Context(), id)
+	if models.HandleError(w, a.storeError(err), http.StatusInternalServerError) {
+		return
+	}
+	g, err := a.loadDependencyGraph(r.Context())
+	if models.HandleError(w, a.storeError(err), http.StatusInternalServerError) {
+		return
+	}
+	g.tasks[id] = task
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(models.DependencyGraphResponse{
+		TaskID:     id,
+		Blocked:    g.openBlockers(task) > 0,
+		Upstream:   g.walk(id, g.blockers),
+		Downstream: g.walk(id, func(id string) []string { return g.blocks[id] }),
+	})
+}
+
+// ListActionable lista as tarefas abertas que podem começar agora, isto é, sem bloqueios
+// abertos: o primeiro nível da ordem topológica das tarefas abertas. As que destravam mais
+// tarefas vêm primeiro; empates seguem a ordem de criação. Só as tarefas com dependências
+// são carregadas inteiras; as demais abertas são lidas em ordem de criação até completar
+// a página
+func (a *API) ListActionable(w http.ResponseWriter, r *http.Request) {
+	limit := models.DefaultPageLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > models.MaxPageLimit {
+			models.WriteError(w, models.NewValidationError("limit must be between 1 and "+strconv.Itoa(models.MaxPageLimit)), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	g, err := a.loadDependencyGraph(r.Context())
+	if models.HandleError(w, a.storeError(err), http.StatusInternalServerError) {
+		return
+	}
+
+	// Tarefas abertas bloqueadas e as que destravam outras estão todas no grafo
+	unblocks := g.openDownstreamCounts()
+	blocked := 0
+	actionable := []models.ActionableTask{}
+	for _, id := range g.order {
+		t := g.tasks[id]
+		switch {
+		case !models.IsOpenTask(t.Status):
+		case g.openBlockers(t) > 0:
+			blocked++
+		case unblocks[id] > 0:
+			actionable = append(actionable, models.ActionableTask{Task: t, Unblocks: unblocks[id]})
+		}
+	}
+	// SliceStable mantém a ordem de criação de g.order nos empates
+	sort.SliceStable(actionable, func(i, j int) bool { return actionable[i].Unblocks > actionable[j].Unblocks })
+
+	open := []models.TaskFilter{{Field: "status", Op: models.FilterNotIn, Values: []string{models.StatusCompleted, models.StatusCancelled}}}
+	page, err := a.store.Query(r.Context(), models.TaskQuery{Filters: open, Limit: 1})
+	if models.HandleError(w, a.storeError(err), http.StatusInternalServerError) {
+		return
+	}
+	resp := models.ActionableResponse{Tasks: actionable, TotalItems: page.Total - blocked}
+	if len(resp.Tasks) > limit {
+		resp.Tasks = resp.Tasks[:limit]
+	}
+	if len(resp.Tasks) < limit {
+		// Completa a página com as tarefas abertas que não destravam nenhuma outra
+		err = a.eachTask(r.Context(), open, func(t models.Task) bool {
+			if _, inGraph := g.tasks[t.ID]; inGraph && (g.openBlockers(t) > 0 || unblocks[t.ID] > 0) {
+				return true
+			}
+			resp.Tasks = append(resp.Tasks, models.ActionableTask{Task: t})
+			return len(resp.Tasks) < limit
+		})
+		if models.HandleError(w, a.storeError(err), http.StatusInternalServerError) {
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// checkDependency valida que blocker existe e que "id bloqueada por blocker" não fecha um
+// ciclo, ou seja, que id não bloqueia blocker direta ou indiretamente
+func (a *API) checkDependency(ctx context.Context, id, blocker string) error {
+	if blocker == id {
+		return errDependencyCycle
+	}
+	seen := map[string]bool{blocker: true}
+	for queue := []string{blocker}; len(queue) > 0; queue = queue[1:] {
+		t, err := a.store.Get(ctx, queue[0])
+		switch {
+		case errors.Is(err, store.ErrNotFound) && queue[0] == blocker:
+			return models.NewValidationError("blocking task not found: " + blocker)
+		case errors.Is(err, store.ErrNotFound):
+			continue
+		case err != nil:
+			return err
+		}
+		for _, b := range t.BlockedBy {
+			if b == id {
+				return errDependencyCycle
+			}
+			if !seen[b] {
+				seen[b] = true
+				queue = append(queue, b)
+			}
+		}
+	}
+	return nil
+}
+
+// openBlockers conta as tarefas ativas de BlockedBy que ainda estão abertas
+func (a *API) openBlockers(ctx context.Context, t models.Task) (int, error) {
+	open := 0
+	for _, id := range t.BlockedBy {
+		b, err := a.store.Get(ctx, id)
+		if errors.Is(err, store.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		if models.IsOpenTask(b.Status) {
+			open++
+		}
+	}
+	return open, nil
+}
+
+// startsWork indica que o patch move a tarefa para in_progress ou completed, a transição
+// barrada por PreventStartingBlockedTask
+func startsWork(current models.Task, patch map[string]interface{}) bool {
+	status, _ := patch["status"].(string)
+	return (status == models.StatusInProgress || models.IsCompletedTask(status)) && status != current.Status
+}
+
+// dependencyGraph são as tarefas ativas que têm ou são dependências, com as arestas de
+// BlockedBy nos dois sentidos. Dependências com tarefas excluídas ficam de fora
+type dependencyGraph struct {
+	tasks map[string]models.Task
+	// blocks liga cada tarefa às que ela bloqueia
+	blocks map[string][]string
+	// order são os ids em ordem de criação
+	order []string
+}
+
+// loadDependencyGraph carrega, em páginas, as tarefas ativas com dependências
+// (blocked_by!=null) e as que as bloqueiam. As demais não têm arestas e ficam de fora
+func (a *API) loadDependencyGraph(ctx context.Context) (dependencyGraph, error) {
+	g := dependencyGraph{tasks: map[string]models.Task{}, blocks: map[string][]string{}}
+	add := func(t models.Task) bool {
+		g.tasks[t.ID] = t
+		return true
+	}
+	if err := a.eachTask(ctx, []models.TaskFilter{{Field: "blocked_by", Op: models.FilterNotNull}}, add); err != nil {
+		return dependencyGraph{}, err
+	}
+	var missing []string
+	seen := map[string]bool{}
+	for _, t := range g.tasks {
+		for _, b := range t.BlockedBy {
+			if _, ok := g.tasks[b]; !ok && !seen[b] {
+				seen[b] = true
+				missing = append(missing, b)
+			}
+		}
+	}
+	for len(missing) > 0 {
+		n := len(missing)
+		if n > models.MaxPageLimit {
+			n = models.MaxPageLimit
+		}
+		if err := a.eachTask(ctx, []models.TaskFilter{{Field: "id", Op: models.FilterIn, Values: missing[:n]}}, add); err != nil {
+			return dependencyGraph{}, err
+		}
+		missing = missing[n:]
+	}
+
+	g.order = make([]string, 0, len(g.tasks))
+	for id := range g.tasks {
+		g.order = append(g.order, id)
+	}
+	sort.Slice(g.order, func(i, j int) bool {
+		ti, tj := g.tasks[g.order[i]], g.tasks[g.order[j]]
+		if !ti.CreatedAt.Equal(tj.CreatedAt) {
+			return ti.CreatedAt.Before(tj.CreatedAt)
+		}
+		return ti.ID < tj.ID
+	})
+	for _, id := range g.order {
+		for _, b := range g.blockers(id) {
+			g.blocks[b] = append(g.blocks[b], id)
+		}
+	}
+	return g, nil
+}
+
+// eachTask chama fn para as tarefas ativas que casam com filters, em ordem de criação,
+// lendo páginas de MaxPageLimit tarefas; fn devolve false para parar
+func (a *API) eachTask(ctx context.Context, filters []models.TaskFilter, fn func(models.Task) bool) error {
+	q := models.TaskQuery{Filters: filters, Limit: models.MaxPageLimit}
+	for {
+		page, err := a.store.Query(ctx, q)
+		if err != nil {
+			return err
+		}
+		for _, t := range page.Tasks {
+			if !fn(t) {
+				return nil
+			}
+		}
+		if page.NextCursor == "" {
+			return nil
+		}
+		if q.After, err = models.DecodeCursor(page.NextCursor, q); err != nil {
+			return err
+		}
+	}
+}
+
+// blockers retorna as tarefas ativas que bloqueiam id
+func (g dependencyGraph) blockers(id string) []string {
+	var out []string
+	for _, b := range g.tasks[id].BlockedBy {
+		if _, ok := g.tasks[b]; ok {
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+func (g dependencyGraph) openBlockers(t models.Task) int {
+	open := 0
+	for _, b := range t.BlockedBy {
+		if blocker, ok := g.tasks[b]; ok && models.IsOpenTask(blocker.Status) {
+			open++
+		}
+	}
+	return open
+}
+
+// walk percorre o grafo a partir de id seguindo next, em largura; cada tarefa aparece uma
+// vez, com a menor distância até id
+func (g dependencyGraph) walk(id string, next func(string) []string) []models.DependencyNode {
+	nodes := []models.DependencyNode{}
+	seen := map[string]bool{id: true}
+	for level, depth := next(id), 1; len(level) > 0; depth++ {
+		var following []string
+		for _, n := range level {
+			if seen[n] {
+				continue
+			}
+			seen[n] = true
+			t := g.tasks[n]
+			blockedBy := g.blockers(n)
+			if blockedBy == nil {
+				blockedBy = []string{}
+			}
+			nodes = append(nodes, models.DependencyNode{ID: t.ID, Title: t.Title, Status: t.Status, BlockedBy: blockedBy, Depth: depth})
+			following = append(following, next(n)...)
+		}
+		level = following
+	}
+	return nodes
+}
+
+// openDownstreamCounts conta, para cada tarefa do grafo, as tarefas abertas que dependem
+// dela direta ou indiretamente. O conjunto de dependentes de cada tarefa é montado uma
+// única vez, a partir dos conjuntos das tarefas que ela bloqueia
+func (g dependencyGraph) openDownstreamCounts() map[string]int {
+	reach := make(map[string]map[string]bool, len(g.tasks))
+	var visit func(id string) map[string]bool
+	visit = func(id string) map[string]bool {
+		if r, ok := reach[id]; ok {
+			// nil enquanto a tarefa está sendo visitada: protege contra ciclos
+			return r
+		}
+		reach[id] = nil
+		r := map[string]bool{}
+		for _, d := range g.blocks[id] {
+			r[d] = true
+			for x := range visit(d) {
+				r[x] = true
+			}
+		}
+		delete(r, id)
+		reach[id] = r
+		return r
+	}
+	counts := make(map[string]int, len(g.tasks))
+	for _, id := range g.order {
+		for d := range visit(id) {
+			if models.IsOpenTask(g.tasks[d].Status) {
+				counts[id]++
+			}
+		}
+	}
+	return counts
+}
+
+func containsID(ids []string, id string) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// sameIDs compara duas listas de ids na ordem; nil e lista vazia são iguais
+func sameIDs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func writeTask(w http.ResponseWriter, t models.Task) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", etag(t.Version))
+	_ = json.NewEncoder(w).Encode(t)
+}
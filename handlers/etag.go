@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"example.com/tasksapi/models"
+	"example.com/tasksapi/store"
+)
+
+// etag representa a versão da tarefa como entity tag forte
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// ifMatch verifica o header If-Match contra a versão atual; sem o header qualquer versão serve
+func ifMatch(r *http.Request, version int64) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return true
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == etag(version) {
+			return true
+		}
+	}
+	return false
+}
+
+var errPreconditionFailed = models.NewPreconditionFailedError("task version does not match If-Match")
+
+// versionError trata ErrVersionConflict: 412 quando o cliente enviou If-Match,
+// 409 quando outra requisição alterou a tarefa entre a leitura e a escrita
+func (a *API) versionError(r *http.Request, err error) error {
+	if !errors.Is(err, store.ErrVersionConflict) {
+		return a.storeError(err)
+	}
+	if r.Header.Get("If-Match") != "" {
+		return errPreconditionFailed
+	}
+	return models.NewBusinessRuleError("task was modified concurrently, retry the request")
+}
@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 
@@ -12,19 +14,26 @@ import (
 )
 
 type API struct {
-	store   store.Store
-	service *models.TaskService
-	logger  models.Logger
+	store    store.Store
+	projects store.ProjectStore
+	service  *models.TaskService
+	logger   models.Logger
 }
 
+// NewAPI guarda os projetos em memória; NewAPIWithProjects recebe o ProjectStore
 func NewAPI(s store.Store, logger models.Logger) *API {
+	return NewAPIWithProjects(s, store.NewMemoryProjectStore(), logger)
+}
+
+func NewAPIWithProjects(s store.Store, projects store.ProjectStore, logger models.Logger) *API {
 	if logger == nil {
 		logger = models.NewDefaultLogger()
 	}
 	return &API{
-		store:   s,
-		service: models.NewTaskService(logger),
-		logger:  logger,
+		store:    s,
+		projects: projects,
+		service:  models.NewTaskService(logger),
+		logger:   logger,
 	}
 }
 
@@ -46,6 +55,9 @@ func (a *API) CreateTask(w http.ResponseWriter, r *http.Request) {
 		t.Description = r.FormValue("description")
 		t.Status = r.FormValue("status")
 		t.Priority = r.FormValue("priority")
+		t.ParentID = r.FormValue("parent_id")
+		t.ProjectID = r.FormValue("project_id")
+		t.Tags = r.Form["tags"]
 		if v := r.FormValue("due_date"); v != "" {
 			parsed, err := models.ParseDateOnly(v)
 			if models.HandleError(w, err, http.StatusBadRequest) {
@@ -56,127 +68,353 @@ func (a *API) CreateTask(w http.ResponseWriter, r *http.Request) {
 
 	}
 
+	t.Tags = models.NormalizeTags(t.Tags)
 	if models.HandleError(w, a.service.ValidateCreate(t), http.StatusBadRequest) {
 		return
 	}
+	if t.ParentID != "" {
+		if _, err := a.parentChain(r.Context(), t.ParentID); models.HandleError(w, a.storeError(err), http.StatusInternalServerError) {
+			return
+		}
+	}
+	if t.ProjectID != "" {
+		if models.HandleError(w, a.storeError(a.checkProject(r.Context(), t.ProjectID)), http.StatusInternalServerError) {
+			return
+		}
+	}
 
-	created := a.store.Create(t)
+	created, err := a.store.Create(r.Context(), t)
+	if models.HandleError(w, a.storeError(err), http.StatusInternalServerError) {
+		return
+	}
+	if models.HandleError(w, a.storeError(a.confirmCreated(r.Context(), created)), http.StatusInternalServerError) {
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", etag(created.Version))
 	w.WriteHeader(http.StatusCreated)
 	_ = json.NewEncoder(w).Encode(created)
 
 }
 
 func (a *API) ListTasks(w http.ResponseWriter, r *http.Request) {
-	tasks := a.store.List()
-	status := r.URL.Query().Get("status")
-	priority := r.URL.Query().Get("priority")
-	due_date := r.URL.Query().Get("due_date")
-
-	filtered := make([]models.Task, 0)
-	for _, task := range tasks {
-		// Filtros
-		if status != "" && task.Status != status {
-			continue
-		}
-		if priority != "" {
-			if priority == "null" {
-				if task.Priority != "" {
-					continue
-				}
-			} else if task.Priority != priority {
-				continue
-			}
-		}
-		if due_date != "" {
-			if due_date == "null" {
-				if task.DueDate != nil {
-					continue
-				}
-			} else if task.DueDate == nil || task.DueDate.String() != due_date {
-				continue
-			}
+	a.listTasks(w, r, false)
+}
+
+// ListTrash lista as tarefas excluídas, com os mesmos filtros, ordenação e paginação de ListTasks
+func (a *API) ListTrash(w http.ResponseWriter, r *http.Request) {
+	a.listTasks(w, r, true)
+}
+
+// listTasks responde a listagem com os filtros da query string mais os de extra. Com
+// tree=true, filtros e paginação valem para as tarefas de primeiro nível (as raiz, se
+// parent_id não for filtrado) e cada uma traz todas as subtarefas aninhadas. Tarefas
+// ativas de projetos arquivados só aparecem quando project_id é filtrado
+func (a *API) listTasks(w http.ResponseWriter, r *http.Request, deleted bool, extra ...models.TaskFilter) {
+	q, err := models.ParseTaskQuery(r.URL.Query())
+	if models.HandleError(w, err, http.StatusBadRequest) {
+		return
+	}
+	q.Deleted = deleted
+	q.Filters = append(q.Filters, extra...)
+	tree := r.URL.Query().Get("tree") == "true"
+	if tree && deleted {
+		models.WriteError(w, models.NewValidationError("tree is not available in the trash"), http.StatusBadRequest)
+		return
+	}
+	if tree && !hasFilter(q.Filters, "parent_id") {
+		q.Filters = append(q.Filters, models.TaskFilter{Field: "parent_id", Op: models.FilterIsNull})
+	}
+	if !deleted && !hasFilter(q.Filters, "project_id") {
+		archived, err := a.archivedProjectsFilter(r.Context())
+		if models.HandleError(w, a.storeError(err), http.StatusInternalServerError) {
+			return
 		}
+		q.Filters = append(q.Filters, archived...)
+	}
 
-		filtered = append(filtered, task)
+	page, err := a.store.Query(r.Context(), q)
+	if models.HandleError(w, a.storeError(err), http.StatusInternalServerError) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if tree {
+		nodes, err := a.taskTree(r.Context(), page.Tasks, subtaskSort(q))
+		if models.HandleError(w, a.storeError(err), http.StatusInternalServerError) {
+			return
+		}
+		_ = json.NewEncoder(w).Encode(models.TaskTreeResponse{
+			Tasks:      nodes,
+			TotalItems: page.Total,
+			NextCursor: page.NextCursor,
+		})
+		return
 	}
 
 	response := models.TaskListResponse{
-		Tasks:      filtered,
-		TotalItems: len(filtered),
+		Tasks:      page.Tasks,
+		TotalItems: page.Total,
+		NextCursor: page.NextCursor,
 	}
 
-	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(response)
 }
 
 func (a *API) GetTask(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
-	t, err := a.store.Get(id)
-	if models.HandleError(w, err, http.StatusNotFound) {
+	if v := r.URL.Query().Get("as_of"); v != "" {
+		a.getTaskAsOf(w, r, id, v)
+		return
+	}
+	t, err := a.store.Get(r.Context(), id)
+	if models.HandleError(w, a.storeError(err), http.StatusInternalServerError) {
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", etag(t.Version))
 	_ = json.NewEncoder(w).Encode(t)
 }
 
+// UpdateTask substitui a tarefa pela representação enviada: campos editáveis ausentes são limpos
 func (a *API) UpdateTask(w http.ResponseWriter, r *http.Request) {
-	id := mux.Vars(r)["id"]
-	task, err := a.store.Get(id)
-	if models.HandleError(w, err, http.StatusNotFound) {
+	doc, err := documentFromRequest(r)
+	if models.HandleError(w, err, http.StatusBadRequest) {
 		return
 	}
+	patch, err := models.ReplacementPatch(doc)
+	if models.HandleError(w, err, http.StatusBadRequest) {
+		return
+	}
+	a.updateTask(w, r, func(models.Task) (map[string]interface{}, error) {
+		return patch, nil
+	})
+}
 
-	var patch map[string]interface{}
-	ct := r.Header.Get("Content-Type")
-	if strings.Contains(ct, "application/json") {
+// PatchTask aplica uma alteração parcial em JSON Merge Patch (RFC 7396) ou JSON Patch (RFC 6902)
+func (a *API) PatchTask(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Accept-Patch", acceptPatch)
+	switch ct := r.Header.Get("Content-Type"); {
+	case strings.HasPrefix(ct, models.MergePatchContentType):
+		var patch map[string]interface{}
 		if models.HandleError(w, json.NewDecoder(r.Body).Decode(&patch), http.StatusBadRequest) {
 			return
 		}
-	} else {
-		if strings.HasPrefix(ct, "multipart/form-data") {
-			_ = r.ParseMultipartForm(10 << 20)
-		} else {
-			_ = r.ParseForm()
+		a.updateTask(w, r, func(models.Task) (map[string]interface{}, error) {
+			return patch, nil
+		})
+	case strings.HasPrefix(ct, models.JSONPatchContentType):
+		var ops []models.PatchOperation
+		if models.HandleError(w, json.NewDecoder(r.Body).Decode(&ops), http.StatusBadRequest) {
+			return
 		}
-		patch = map[string]interface{}{}
-		if v := r.FormValue("title"); v != "" {
-			patch["title"] = v
+		// As operações, inclusive test, são avaliadas contra o estado armazenado
+		a.updateTask(w, r, func(current models.Task) (map[string]interface{}, error) {
+			return models.ApplyJSONPatch(current, ops)
+		})
+	default:
+		models.WriteError(w, models.NewUnsupportedMediaTypeError("unsupported patch format, use "+acceptPatch), http.StatusUnsupportedMediaType)
+	}
+}
+
+const acceptPatch = models.MergePatchContentType + ", " + models.JSONPatchContentType
+
+// updateTask grava o patch montado por build. If-Match e as regras de negócio são
+// avaliados contra o estado armazenado, atomicamente com a escrita; as regras que leem
+// outras tarefas são conferidas de novo depois dela (confirmUpdate)
+func (a *API) updateTask(w http.ResponseWriter, r *http.Request, build store.UpdateFunc) {
+	id := mux.Vars(r)["id"]
+	check, err := a.checkUpdate(r, id, build)
+	if models.HandleError(w, a.storeError(err), http.StatusInternalServerError) {
+		return
+	}
+	var before models.Task
+	var applied map[string]interface{}
+	t, err := a.store.UpdateWith(r.Context(), id, func(current models.Task) (map[string]interface{}, error) {
+		if !ifMatch(r, current.Version) {
+			return nil, errPreconditionFailed
 		}
-		if v := r.FormValue("description"); v != "" {
-			patch["description"] = v
+		patch, err := build(current)
+		if err != nil {
+			return nil, err
 		}
-		if v := r.FormValue("status"); v != "" {
-			patch["status"] = v
+		if !check.covers(current, patch) {
+			return nil, store.ErrVersionConflict
 		}
-		if v := r.FormValue("priority"); v != "" {
-			patch["priority"] = v
+		// Os validadores podem normalizar o patch; cada tentativa parte de uma cópia
+		p := make(map[string]interface{}, len(patch))
+		for k, v := range patch {
+			p[k] = v
 		}
-		if v := r.FormValue("due_date"); v != "" {
-			parsed, err := models.ParseDateOnly(v)
-			if models.HandleError(w, err, http.StatusBadRequest) {
-				return
-			}
-			patch["due_date"] = parsed
+		if err := a.service.ValidateUpdateWith(current, check.rel, p); err != nil {
+			return nil, err
 		}
+		before, applied = current, p
+		return p, nil
+	})
+	if models.HandleError(w, a.versionError(r, err), http.StatusInternalServerError) {
+		return
+	}
+	if models.HandleError(w, a.storeError(a.confirmUpdate(r.Context(), before, t, applied)), http.StatusInternalServerError) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", etag(t.Version))
+	_ = json.NewEncoder(w).Encode(t)
+}
 
+// documentFromRequest lê a representação da tarefa do corpo JSON ou do formulário;
+// campos de formulário vazios contam como ausentes
+func documentFromRequest(r *http.Request) (map[string]interface{}, error) {
+	var doc map[string]interface{}
+	ct := r.Header.Get("Content-Type")
+	if strings.Contains(ct, "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(&doc); err != nil {
+			return nil, err
+		}
+		return doc, nil
 	}
-	if models.HandleError(w, a.service.ValidateUpdate(task, patch), http.StatusBadRequest) {
+
+	if strings.HasPrefix(ct, "multipart/form-data") {
+		_ = r.ParseMultipartForm(10 << 20)
+	} else {
+		_ = r.ParseForm()
+	}
+	doc = map[string]interface{}{}
+	for _, field := range []string{"title", "description", "status", "priority", "due_date", "parent_id", "project_id"} {
+		if v := r.FormValue(field); v != "" {
+			doc[field] = v
+		}
+	}
+	// tags é repetido no formulário (tags=a&tags=b), como no POST
+	if tags := r.Form["tags"]; len(tags) > 0 {
+		doc["tags"] = tags
+	}
+	return doc, nil
+}
+
+// DeleteTask move a tarefa para a lixeira; com ?purge=true a remove definitivamente.
+// Tarefas com subtarefas ativas só são excluídas com ?cascade=true, que exclui a subárvore
+func (a *API) DeleteTask(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	cascade := r.URL.Query().Get("cascade") == "true"
+	if r.URL.Query().Get("purge") == "true" {
+		if models.HandleError(w, a.storeError(a.purgeSubtree(r.Context(), id, cascade)), http.StatusInternalServerError) {
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if models.HandleError(w, a.versionError(r, a.deleteSubtree(r, id, cascade)), http.StatusInternalServerError) {
 		return
 	}
+	w.WriteHeader(http.StatusNoContent)
+}
 
-	t, err := a.store.Update(id, patch)
-	if models.HandleError(w, err, http.StatusNotFound) {
+// getTaskAsOf responde com a tarefa como ela estava em as_of. Sem ETag, pois não é o estado atual
+func (a *API) getTaskAsOf(w http.ResponseWriter, r *http.Request, id, asOf string) {
+	at, err := models.ParseAsOf(asOf)
+	if models.HandleError(w, err, http.StatusBadRequest) {
+		return
+	}
+	reader, ok := a.store.(store.AsOfReader)
+	if !ok {
+		models.HandleError(w, a.storeError(store.ErrAsOfUnavailable), http.StatusInternalServerError)
+		return
+	}
+	t, err := reader.GetAsOf(r.Context(), id, at)
+	if models.HandleError(w, a.storeError(err), http.StatusInternalServerError) {
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(t)
 }
 
-func (a *API) DeleteTask(w http.ResponseWriter, r *http.Request) {
+// TaskHistory lista as alterações da tarefa em ordem cronológica. O histórico continua
+// disponível depois que a tarefa é excluída ou purgada
+func (a *API) TaskHistory(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
-	if models.HandleError(w, a.store.Delete(id), http.StatusNotFound) {
+	hr, ok := a.store.(store.HistoryReader)
+	if !ok {
+		models.HandleError(w, a.storeError(store.ErrHistoryUnavailable), http.StatusInternalServerError)
 		return
 	}
-	w.WriteHeader(http.StatusNoContent)
+	entries, err := hr.History(r.Context(), id)
+	if models.HandleError(w, a.storeError(err), http.StatusInternalServerError) {
+		return
+	}
+	if len(entries) == 0 {
+		// Sem histórico: 404 se a tarefa não existe, lista vazia se é anterior à auditoria
+		if _, err := a.store.Get(r.Context(), id); models.HandleError(w, a.storeError(err), http.StatusInternalServerError) {
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(models.TaskHistoryResponse{TaskID: id, Entries: entries})
+}
+
+// RestoreTask tira a tarefa da lixeira. Se o pai não estiver mais ativo, ela volta como
+// tarefa raiz; se o projeto tiver sido excluído, volta sem projeto
+func (a *API) RestoreTask(w http.ResponseWriter, r *http.Request) {
+	t, err := a.store.Restore(r.Context(), mux.Vars(r)["id"])
+	if models.HandleError(w, a.storeError(err), http.StatusInternalServerError) {
+		return
+	}
+	// O pai é lido depois da restauração: se ele for excluído ao mesmo tempo, ou aparece
+	// excluído aqui ou vê a subtarefa restaurada em confirmDeleted
+	patch, err := a.restorePatch(r.Context(), t)
+	if models.HandleError(w, a.storeError(err), http.StatusInternalServerError) {
+		return
+	}
+	if len(patch) > 0 {
+		// Pai e projeto removidos saem numa única escrita. fn não lê o Store: o
+		// InMemoryStore chama fn com o lock tomado
+		t, err = a.store.UpdateWith(r.Context(), t.ID, func(models.Task) (map[string]interface{}, error) {
+			return patch, nil
+		})
+		if models.HandleError(w, a.storeError(err), http.StatusInternalServerError) {
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", etag(t.Version))
+	_ = json.NewEncoder(w).Encode(t)
+}
+
+// restorePatch monta o patch que solta a tarefa restaurada do pai e do projeto que não
+// existem mais
+func (a *API) restorePatch(ctx context.Context, t models.Task) (map[string]interface{}, error) {
+	patch := map[string]interface{}{}
+	if err := a.detachOrphan(ctx, t, patch); err != nil {
+		return nil, err
+	}
+	if err := a.detachDeletedProject(ctx, t, patch); err != nil {
+		return nil, err
+	}
+	return patch, nil
+}
+
+// storeError converte erros do Store em APIError: ErrNotFound e ErrProjectNotFound
+// viram 404, deadline estourado vira 504 e qualquer outra falha de persistência vira 500
+func (a *API) storeError(err error) error {
+	var apiErr *models.APIError
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &apiErr):
+		return apiErr
+	case errors.Is(err, store.ErrNotFound), errors.Is(err, store.ErrProjectNotFound):
+		return models.NewNotFoundError(err.Error())
+	case errors.Is(err, store.ErrHistoryUnavailable), errors.Is(err, store.ErrAsOfUnavailable), errors.Is(err, store.ErrAtomicUnavailable):
+		return &models.APIError{Code: http.StatusNotImplemented, Message: err.Error()}
+	case errors.Is(err, store.ErrBatchAborted):
+		return &models.APIError{Code: http.StatusFailedDependency, Message: err.Error()}
+	case errors.Is(err, context.DeadlineExceeded):
+		a.logger.Error("store operation timed out: %v", err)
+		return &models.APIError{Code: http.StatusGatewayTimeout, Message: "storage timeout"}
+	default:
+		a.logger.Error("store operation failed: %v", err)
+		return models.NewInternalError("storage failure")
+	}
 }
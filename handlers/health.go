@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"example.com/tasksapi/models"
+	"example.com/tasksapi/store"
+)
+
+const healthTimeout = 2 * time.Second
+
+// HealthHandler informa qual backend de armazenamento está ativo e se ele responde
+type HealthHandler struct {
+	backend  string
+	fallback bool
+	store    store.Store
+}
+
+// NewHealthHandler recebe o store sem decorators, para que o ping não passe pelo log
+func NewHealthHandler(backend string, fallback bool, s store.Store) *HealthHandler {
+	return &HealthHandler{backend: backend, fallback: fallback, store: s}
+}
+
+// Health responde 200 com o backend ativo, ou 503 se a conexão com ele caiu
+func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
+	resp := models.HealthResponse{Status: "ok", Backend: h.backend, Fallback: h.fallback}
+	code := http.StatusOK
+	if p, ok := h.store.(store.Pinger); ok {
+		ctx, cancel := context.WithTimeout(r.Context(), healthTimeout)
+		defer cancel()
+		if err := p.Ping(ctx); err != nil {
+			resp.Status = "unavailable"
+			resp.Error = err.Error()
+			code = http.StatusServiceUnavailable
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+
+	"example.com/tasksapi/models"
+	"example.com/tasksapi/store"
+)
+
+// IdempotencyKeyHeader permite ao cliente repetir uma requisição de escrita sem repetir o efeito
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// ReplayedHeader marca as respostas devolvidas a partir de uma Idempotency-Key já usada
+const ReplayedHeader = "Idempotent-Replayed"
+
+// maxIdempotencyKeyLength limita o tamanho da chave enviada pelo cliente
+const maxIdempotencyKeyLength = 255
+
+// Idempotency guarda a resposta das requisições POST, PUT, PATCH e DELETE enviadas com
+// Idempotency-Key e a repete quando a mesma requisição volta com a mesma chave
+type Idempotency struct {
+	keys   store.IdempotencyStore
+	ttl    time.Duration
+	logger models.Logger
+}
+
+// NewIdempotency cria o middleware; as chaves valem por ttl
+func NewIdempotency(keys store.IdempotencyStore, ttl time.Duration, logger models.Logger) *Idempotency {
+	if logger == nil {
+		logger = models.NewDefaultLogger()
+	}
+	return &Idempotency{keys: keys, ttl: ttl, logger: logger}
+}
+
+// recordingWriter repassa a resposta ao cliente e guarda uma cópia dela
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rw *recordingWriter) WriteHeader(code int) {
+	rw.status = code
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recordingWriter) Write(b []byte) (int, error) {
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
+
+// Middleware reserva a chave antes de chamar o handler e grava a resposta depois. Uma chave
+// já usada com outra requisição responde 422; uma chave cuja requisição ainda não terminou,
+// 409. Respostas 5xx não são guardadas, para que o cliente possa tentar de novo
+func (i *Idempotency) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" || r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			models.WriteError(w, models.NewValidationError("Idempotency-Key must have at most 255 characters"), http.StatusBadRequest)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if models.HandleError(w, err, http.StatusBadRequest) {
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		now := time.Now().UTC()
+		rec := models.IdempotencyRecord{
+			Key:         key,
+			Fingerprint: requestFingerprint(r, body),
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(i.ttl),
+		}
+		existing, err := i.keys.Reserve(r.Context(), rec)
+		if err != nil {
+			i.logger.Error("[HTTP] Failed to reserve Idempotency-Key %s: %v", key, err)
+			models.WriteError(w, models.NewInternalError("failed to check Idempotency-Key"), http.StatusInternalServerError)
+			return
+		}
+		if existing != nil {
+			i.replay(w, *existing, rec)
+			return
+		}
+
+		rw := &recordingWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rw, r)
+
+		// A resposta já foi enviada; o cancelamento da requisição não pode deixar a chave presa
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if rw.status >= 500 {
+			err = i.keys.Release(ctx, key)
+		} else {
+			rec.Status = rw.status
+			rec.Header = w.Header().Clone()
+			rec.Body = rw.body.Bytes()
+			err = i.keys.Complete(ctx, rec)
+		}
+		if err != nil {
+			i.logger.Error("[HTTP] Failed to save Idempotency-Key %s: %v", key, err)
+		}
+	})
+}
+
+// replay responde a repetição de uma requisição com a resposta guardada
+func (i *Idempotency) replay(w http.ResponseWriter, existing, rec models.IdempotencyRecord) {
+	switch {
+	case existing.Fingerprint != rec.Fingerprint:
+		models.WriteError(w, models.NewUnprocessableEntityError("Idempotency-Key was already used with a different request"), http.StatusUnprocessableEntity)
+	case !existing.Completed():
+		models.WriteError(w, models.NewBusinessRuleError("a request with this Idempotency-Key is still in progress"), http.StatusConflict)
+	default:
+		i.logger.Info("[HTTP] Replaying response for Idempotency-Key %s", rec.Key)
+		for name, values := range existing.Header {
+			w.Header()[name] = values
+		}
+		w.Header().Set(ReplayedHeader, "true")
+		w.WriteHeader(existing.Status)
+		_, _ = w.Write(existing.Body)
+	}
+}
+
+// requestFingerprint identifica a requisição: método, URL, os headers que mudam o efeito
+// dela e o corpo
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	for _, part := range []string{r.Method, r.URL.RequestURI(), r.Header.Get("Content-Type"), r.Header.Get("If-Match"), r.Header.Get(ActorHeader)} {
+		io.WriteString(h, part)
+		h.Write([]byte{0})
+	}
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
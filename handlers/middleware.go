@@ -2,11 +2,54 @@ package handlers
 
 import (
 	"net/http"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"example.com/tasksapi/models"
 )
 
+// ActorHeader identifica quem faz a requisição; o valor é gravado no histórico das tarefas
+const ActorHeader = "X-Actor"
+
+// maxActorLength limita o tamanho do actor gravado no histórico
+const maxActorLength = 100
+
+// ActorMiddleware coloca no contexto o actor da requisição (AnonymousActor sem o header).
+// O header é declarado pelo cliente, não autenticado: em produção ele deve ser definido
+// (ou sobrescrito) por um proxy que autentica a requisição. Valores que não cabem no
+// histórico respondem 400: mais de 100 caracteres, caracteres de controle ou o
+// SystemActor, reservado às alterações feitas pela própria aplicação
+func ActorMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor := r.Header.Get(ActorHeader)
+		if actor == "" {
+			actor = models.AnonymousActor
+		}
+		if err := validateActor(actor); err != nil {
+			models.WriteError(w, err, http.StatusBadRequest)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(models.ContextWithActor(r.Context(), actor)))
+	})
+}
+
+func validateActor(actor string) error {
+	if utf8.RuneCountInString(actor) > maxActorLength || !utf8.ValidString(actor) {
+		return models.NewValidationError("X-Actor must be valid UTF-8 with at most 100 characters")
+	}
+	for _, c := range actor {
+		if unicode.IsControl(c) {
+			return models.NewValidationError("X-Actor must not contain control characters")
+		}
+	}
+	if strings.EqualFold(strings.TrimSpace(actor), models.SystemActor) {
+		return models.NewValidationError("X-Actor " + models.SystemActor + " is reserved for changes made by the application")
+	}
+	return nil
+}
+
 // LoggingMiddleware intercepta todas as requisições HTTP e loga informações
 type LoggingMiddleware struct {
 	logger models.Logger
@@ -71,6 +114,8 @@ func getMethodColor(method string) string {
 		return models.ColorGreen
 	case "PUT":
 		return models.ColorYellow
+	case "PATCH":
+		return models.ColorPurple
 	case "DELETE":
 		return models.ColorRed
 	default:
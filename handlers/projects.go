@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"example.com/tasksapi/models"
+	"example.com/tasksapi/store"
+)
+
+// CreateProject cria um projeto a partir de {"name": ..., "description": ...}
+func (a *API) CreateProject(w http.ResponseWriter, r *http.Request) {
+	var p models.Project
+	if models.HandleError(w, json.NewDecoder(r.Body).Decode(&p), http.StatusBadRequest) {
+		return
+	}
+	p.ArchivedAt = nil
+	if models.HandleError(w, models.ValidateProject(&p), http.StatusBadRequest) {
+		return
+	}
+	created, err := a.projects.Create(r.Context(), p)
+	if models.HandleError(w, a.storeError(err), http.StatusInternalServerError) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(created)
+}
+
+// ListProjects lista os projetos por data de criação; os arquivados só com ?include_archived=true
+func (a *API) ListProjects(w http.ResponseWriter, r *http.Request) {
+	projects, err := a.projects.List(r.Context())
+	if models.HandleError(w, a.storeError(err), http.StatusInternalServerError) {
+		return
+	}
+	if r.URL.Query().Get("include_archived") != "true" {
+		active := projects[:0]
+		for _, p := range projects {
+			if !p.Archived() {
+				active = append(active, p)
+			}
+		}
+		projects = active
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(models.ProjectListResponse{Projects: projects, TotalItems: len(projects)})
+}
+
+func (a *API) GetProject(w http.ResponseWriter, r *http.Request) {
+	p, err := a.projects.Get(r.Context(), mux.Vars(r)["id"])
+	if models.HandleError(w, a.storeError(err), http.StatusInternalServerError) {
+		return
+	}
+	writeProject(w, p)
+}
+
+// PatchProject altera name e/ou description com um merge patch; null limpa a descrição
+func (a *API) PatchProject(w http.ResponseWriter, r *http.Request) {
+	var patch map[string]interface{}
+	if models.HandleError(w, json.NewDecoder(r.Body).Decode(&patch), http.StatusBadRequest) {
+		return
+	}
+	p, err := a.projects.Update(r.Context(), mux.Vars(r)["id"], func(current models.Project) (models.Project, error) {
+		return models.ApplyProjectPatch(current, patch)
+	})
+	if models.HandleError(w, a.storeError(err), http.StatusInternalServerError) {
+		return
+	}
+	writeProject(w, p)
+}
+
+// DeleteProject remove o projeto. Projetos com tarefas ativas não são removidos: as tarefas
+// precisam ser movidas ou excluídas antes (ou o projeto arquivado)
+func (a *API) DeleteProject(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if _, err := a.projects.Get(r.Context(), id); models.HandleError(w, a.storeError(err), http.StatusInternalServerError) {
+		return
+	}
+	page, err := a.store.Query(r.Context(), models.TaskQuery{Filters: []models.TaskFilter{projectFilter(id)}, Limit: 1})
+	if models.HandleError(w, a.storeError(err), http.StatusInternalServerError) {
+		return
+	}
+	if page.Total > 0 {
+		models.WriteError(w, models.NewBusinessRuleError("project has tasks, move or delete them first or archive the project"), http.StatusConflict)
+		return
+	}
+	if models.HandleError(w, a.storeError(a.projects.Delete(r.Context(), id)), http.StatusInternalServerError) {
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ArchiveProject arquiva o projeto: as tarefas dele saem de GET /tasks e ele não recebe
+// tarefas novas. Arquivar um projeto já arquivado não muda nada
+func (a *API) ArchiveProject(w http.ResponseWriter, r *http.Request) {
+	a.setArchived(w, r, true)
+}
+
+// UnarchiveProject devolve o projeto e as tarefas dele às listagens
+func (a *API) UnarchiveProject(w http.ResponseWriter, r *http.Request) {
+	a.setArchived(w, r, false)
+}
+
+func (a *API) setArchived(w http.ResponseWriter, r *http.Request, archived bool) {
+	id := mux.Vars(r)["id"]
+	p, err := a.projects.Get(r.Context(), id)
+	if models.HandleError(w, a.storeError(err), http.StatusInternalServerError) {
+		return
+	}
+	if p.Archived() != archived {
+		p, err = a.projects.Update(r.Context(), id, func(current models.Project) (models.Project, error) {
+			current.ArchivedAt = nil
+			if archived {
+				now := time.Now().UTC()
+				current.ArchivedAt = &now
+			}
+			return current, nil
+		})
+		if models.HandleError(w, a.storeError(err), http.StatusInternalServerError) {
+			return
+		}
+	}
+	writeProject(w, p)
+}
+
+// ListProjectTasks lista as tarefas do projeto, arquivado ou não, com os mesmos filtros,
+// ordenação, paginação e tree=true de ListTasks
+func (a *API) ListProjectTasks(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if _, err := a.projects.Get(r.Context(), id); models.HandleError(w, a.storeError(err), http.StatusInternalServerError) {
+		return
+	}
+	a.listTasks(w, r, false, projectFilter(id))
+}
+
+// ProjectStats resume as tarefas ativas do projeto por status e prioridade
+func (a *API) ProjectStats(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if _, err := a.projects.Get(r.Context(), id); models.HandleError(w, a.storeError(err), http.StatusInternalServerError) {
+		return
+	}
+	page, err := a.store.Query(r.Context(), models.TaskQuery{Filters: []models.TaskFilter{projectFilter(id)}})
+	if models.HandleError(w, a.storeError(err), http.StatusInternalServerError) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(models.NewProjectStats(id, page.Tasks, time.Now()))
+}
+
+func projectFilter(id string) models.TaskFilter {
+	return models.TaskFilter{Field: "project_id", Op: models.FilterEq, Value: id}
+}
+
+// checkProject valida o projeto de destino de uma tarefa: ele precisa existir e não pode
+// estar arquivado
+func (a *API) checkProject(ctx context.Context, id string) error {
+	p, err := a.projects.Get(ctx, id)
+	switch {
+	case errors.Is(err, store.ErrProjectNotFound):
+		return models.NewValidationError("project not found: " + id)
+	case err != nil:
+		return err
+	case p.Archived():
+		return models.NewBusinessRuleError("cannot add tasks to an archived project")
+	}
+	return nil
+}
+
+// archivedProjectsFilter exclui da listagem as tarefas dos projetos arquivados; sem
+// projetos arquivados, não há filtro
+func (a *API) archivedProjectsFilter(ctx context.Context) ([]models.TaskFilter, error) {
+	ids, err := store.ArchivedProjectIDs(ctx, a.projects)
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	return []models.TaskFilter{{Field: "project_id", Op: models.FilterNotIn, Values: ids}}, nil
+}
+
+// detachDeletedProject acrescenta ao patch a limpeza de project_id quando o projeto da
+// tarefa restaurada foi removido
+func (a *API) detachDeletedProject(ctx context.Context, t models.Task, patch map[string]interface{}) error {
+	if t.ProjectID == "" {
+		return nil
+	}
+	_, err := a.projects.Get(ctx, t.ProjectID)
+	if !errors.Is(err, store.ErrProjectNotFound) {
+		return err
+	}
+	patch["project_id"] = nil
+	return nil
+}
+
+func writeProject(w http.ResponseWriter, p models.Project) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(p)
+}
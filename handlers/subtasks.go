@@ -0,0 +1,667 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+
+	"github.com/gorilla/mux"
+
+	"example.com/tasksapi/models"
+	"example.com/tasksapi/store"
+)
+
+var (
+	errParentCycle      = models.NewBusinessRuleError("parent_id would create a cycle")
+	errConcurrentChange = models.NewBusinessRuleError("a related task was modified concurrently, retry the request")
+)
+
+// ListSubtasks lista as subtarefas diretas da tarefa, com os mesmos filtros, ordenação,
+// paginação e tree=true de ListTasks
+func (a *API) ListSubtasks(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if _, err := a.store.Get(r.Context(), id); models.HandleError(w, a.storeError(err), http.StatusInternalServerError) {
+		return
+	}
+	a.listTasks(w, r, false, parentFilter(id))
+}
+
+func parentFilter(id string) models.TaskFilter {
+	return models.TaskFilter{Field: "parent_id", Op: models.FilterEq, Value: id}
+}
+
+// parentChain valida o pai de destino e retorna ele e os ancestrais dele: nenhuma dessas
+// tarefas pode virar subtarefa do pai sem criar um ciclo. O pai precisa estar ativo e não
+// pode estar concluído
+func (a *API) parentChain(ctx context.Context, parentID string) (map[string]bool, error) {
+	chain := map[string]bool{}
+	for id := parentID; id != "" && !chain[id]; {
+		t, err := a.store.Get(ctx, id)
+		switch {
+		case errors.Is(err, store.ErrNotFound) && id == parentID:
+			return nil, models.NewValidationError("parent task not found: " + parentID)
+		case errors.Is(err, store.ErrNotFound):
+			// Ancestral excluído: a cadeia ativa termina aqui
+			return chain, nil
+		case err != nil:
+			return nil, err
+		}
+		if id == parentID && models.IsCompletedTask(t.Status) {
+			return nil, models.NewBusinessRuleError("cannot add subtasks to a completed task")
+		}
+		chain[id] = true
+		id = t.ParentID
+	}
+	return chain, nil
+}
+
+// As escritas que dependem de outras tarefas (criar ou mover subtarefas, concluir ou
+// excluir o pai) não são serializadas na API: cada uma grava e depois confere de novo o
+// que a regra leu. Se duas escritas concorrentes quebram a regra juntas, pelo menos uma
+// enxerga a outra nessa conferência, se desfaz e responde 409 (errConcurrentChange)
+
+// confirmParent confere, depois da escrita, que a tarefa id está sob um pai ativo e não
+// concluído, sem formar ciclo
+func (a *API) confirmParent(ctx context.Context, id, parentID string) error {
+	chain, err := a.parentChain(ctx, parentID)
+	if err != nil {
+		return err
+	}
+	if chain[id] {
+		return errParentCycle
+	}
+	return nil
+}
+
+// confirmCreated confere o pai da tarefa recém-criada; se ele deixou de aceitar
+// subtarefas no meio, a tarefa é removida
+func (a *API) confirmCreated(ctx context.Context, t models.Task) error {
+	if t.ParentID == "" {
+		return nil
+	}
+	cause := a.confirmParent(ctx, t.ID, t.ParentID)
+	if cause == nil {
+		return nil
+	}
+	if err := a.store.Purge(ctx, t.ID); err != nil && !errors.Is(err, store.ErrNotFound) {
+		return err
+	}
+	return concurrentError(cause)
+}
+
+// confirmUpdate confere o novo pai da tarefa e, se ela foi concluída, as subtarefas
+// abertas; se a escrita quebrou uma das regras, patch é desfeito com UpdateIfVersion
+func (a *API) confirmUpdate(ctx context.Context, before, after models.Task, patch map[string]interface{}) error {
+	var cause error
+	if after.ParentID != "" && after.ParentID != before.ParentID {
+		cause = a.confirmParent(ctx, after.ID, after.ParentID)
+	}
+	if cause == nil && models.IsCompletedTask(after.Status) && !models.IsCompletedTask(before.Status) {
+		rels, err := a.countSubtasks(ctx, after.ID)
+		if err != nil {
+			cause = err
+		} else {
+			cause = models.PreventCompletingParentWithOpenSubtasks(before, rels[after.ID], patch)
+		}
+	}
+	if cause == nil {
+		return nil
+	}
+	revert, err := models.RevertPatch(before, patch)
+	if err != nil {
+		return err
+	}
+	if _, err := a.store.UpdateIfVersion(ctx, after.ID, after.Version, revert); err != nil {
+		return err
+	}
+	return concurrentError(cause)
+}
+
+// confirmDeleted confere, depois de excluir as tarefas de ids sem cascade, que nenhuma
+// ganhou subtarefas ativas no meio; as que ganharam são restauradas e saem no mapa
+func (a *API) confirmDeleted(ctx context.Context, ids ...string) (map[string]error, error) {
+	rels, err := a.countSubtasks(ctx, ids...)
+	if err != nil {
+		return nil, err
+	}
+	restored := map[string]error{}
+	for id, rel := range rels {
+		if rel.ActiveSubtasks == 0 {
+			continue
+		}
+		if _, err := a.store.Restore(ctx, id); err != nil && !errors.Is(err, store.ErrNotFound) {
+			restored[id] = err
+			continue
+		}
+		restored[id] = errConcurrentChange
+	}
+	return restored, nil
+}
+
+// concurrentError troca a regra quebrada por uma escrita concorrente por
+// errConcurrentChange: repetir a requisição reavalia a regra com o estado novo
+func concurrentError(err error) error {
+	var apiErr *models.APIError
+	if errors.As(err, &apiErr) {
+		return errConcurrentChange
+	}
+	return err
+}
+
+// subtasksOf retorna as subtarefas diretas ativas das tarefas de ids
+func (a *API) subtasksOf(ctx context.Context, ids []string, sort []models.SortKey) ([]models.Task, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	page, err := a.store.Query(ctx, models.TaskQuery{
+		Filters: []models.TaskFilter{{Field: "parent_id", Op: models.FilterIn, Values: ids}},
+		Sort:    sort,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return page.Tasks, nil
+}
+
+// countSubtasks conta, para cada tarefa de ids, as subtarefas diretas ativas e as abertas
+func (a *API) countSubtasks(ctx context.Context, ids ...string) (map[string]models.TaskRelations, error) {
+	children, err := a.subtasksOf(ctx, ids, nil)
+	if err != nil {
+		return nil, err
+	}
+	rels := make(map[string]models.TaskRelations, len(ids))
+	for _, c := range children {
+		rel := rels[c.ParentID]
+		rel.ActiveSubtasks++
+		if models.IsOpenTask(c.Status) {
+			rel.OpenSubtasks++
+		}
+		rels[c.ParentID] = rel
+	}
+	return rels, nil
+}
+
+// descendants retorna todas as subtarefas ativas abaixo da tarefa, nível a nível; com
+// trash, também as que estão na lixeira (e as subtarefas delas)
+func (a *API) descendants(ctx context.Context, id string, trash bool) ([]string, error) {
+	var out []string
+	seen := map[string]bool{id: true}
+	for level := []string{id}; len(level) > 0; {
+		children, err := a.subtasksOf(ctx, level, nil)
+		if err != nil {
+			return nil, err
+		}
+		if trash {
+			page, err := a.store.Query(ctx, models.TaskQuery{
+				Filters: []models.TaskFilter{{Field: "parent_id", Op: models.FilterIn, Values: level}},
+				Deleted: true,
+			})
+			if err != nil {
+				return nil, err
+			}
+			children = append(children, page.Tasks...)
+		}
+		level = nil
+		for _, c := range children {
+			if !seen[c.ID] {
+				seen[c.ID] = true
+				level = append(level, c.ID)
+			}
+		}
+		out = append(out, level...)
+	}
+	return out, nil
+}
+
+// taskTree aninha sob cada tarefa todas as subtarefas ativas, na ordenação de sort
+func (a *API) taskTree(ctx context.Context, roots []models.Task, sort []models.SortKey) ([]models.TaskNode, error) {
+	children := map[string][]models.Task{}
+	seen := map[string]bool{}
+	var level []string
+	for _, t := range roots {
+		seen[t.ID] = true
+		level = append(level, t.ID)
+	}
+	for len(level) > 0 {
+		tasks, err := a.subtasksOf(ctx, level, sort)
+		if err != nil {
+			return nil, err
+		}
+		level = nil
+		for _, t := range tasks {
+			if seen[t.ID] {
+				continue
+			}
+			seen[t.ID] = true
+			children[t.ParentID] = append(children[t.ParentID], t)
+			level = append(level, t.ID)
+		}
+	}
+
+	var build func(tasks []models.Task) []models.TaskNode
+	build = func(tasks []models.Task) []models.TaskNode {
+		nodes := make([]models.TaskNode, len(tasks))
+		for i, t := range tasks {
+			nodes[i] = models.TaskNode{Task: t, Subtasks: build(children[t.ID])}
+		}
+		return nodes
+	}
+	return build(roots), nil
+}
+
+// updateCheck guarda o que foi validado antes de UpdateWith, já que as regras de
+// hierarquia e de dependências leem outras tarefas e fn não pode chamar o Store. O que
+// outras escritas mudarem no meio é pego por covers ou, depois da escrita, por
+// confirmUpdate
+type updateCheck struct {
+	parent    interface{}
+	moves     bool
+	completes bool
+	starts    bool
+	blockedBy []string
+	rel       models.TaskRelations
+}
+
+// checkUpdate monta o patch contra o estado atual e valida, se for o caso, o novo pai, as
+// subtarefas abertas e os bloqueios abertos. Se o patch não puder ser montado, a validação
+// fica para UpdateWith, que devolve o mesmo erro na ordem normal (If-Match antes das regras)
+func (a *API) checkUpdate(r *http.Request, id string, build store.UpdateFunc) (updateCheck, error) {
+	current, err := a.store.Get(r.Context(), id)
+	if err != nil {
+		return updateCheck{}, err
+	}
+	var c updateCheck
+	if !ifMatch(r, current.Version) {
+		return c, nil
+	}
+	patch, err := build(current)
+	if err != nil {
+		return c, nil
+	}
+	c.parent, c.moves = patch["parent_id"]
+	if parent, ok := c.parent.(string); ok && parent != "" && parent != current.ParentID {
+		chain, err := a.parentChain(r.Context(), parent)
+		if err != nil {
+			return c, err
+		}
+		if chain[id] {
+			return c, errParentCycle
+		}
+	}
+	if project, ok := patch["project_id"].(string); ok && project != "" && project != current.ProjectID {
+		if err := a.checkProject(r.Context(), project); err != nil {
+			return c, err
+		}
+	}
+	if status, _ := patch["status"].(string); models.IsCompletedTask(status) {
+		c.completes = true
+		rels, err := a.countSubtasks(r.Context(), id)
+		if err != nil {
+			return c, err
+		}
+		c.rel.OpenSubtasks = rels[id].OpenSubtasks
+	}
+	if startsWork(current, patch) {
+		c.starts = true
+		c.blockedBy = current.BlockedBy
+		if c.rel.OpenBlockers, err = a.openBlockers(r.Context(), current); err != nil {
+			return c, err
+		}
+	}
+	return c, nil
+}
+
+// covers indica se o patch montado dentro de UpdateWith é o que foi validado; se o estado
+// mudou no meio e o patch também, a escrita é tratada como conflito de versão
+func (c updateCheck) covers(current models.Task, patch map[string]interface{}) bool {
+	parent, moves := patch["parent_id"]
+	if moves != c.moves || !reflect.DeepEqual(parent, c.parent) {
+		return false
+	}
+	if startsWork(current, patch) && (!c.starts || !reflect.DeepEqual(current.BlockedBy, c.blockedBy)) {
+		return false
+	}
+	status, _ := patch["status"].(string)
+	return c.completes || !models.IsCompletedTask(status)
+}
+
+// deleteSubtree exclui a tarefa e, com cascade, todas as subtarefas ativas; sem cascade
+// as regras de negócio rejeitam tarefas com subtarefas. A subárvore vai para a lixeira num
+// lote atômico quando o Store permite; senão as subtarefas saem antes do pai, para que uma
+// falha no meio não deixe subtarefas ativas sob um pai excluído
+func (a *API) deleteSubtree(r *http.Request, id string, cascade bool) error {
+	ctx := r.Context()
+	task := models.Task{ID: id}
+	if r.Header.Get("If-Match") != "" {
+		var err error
+		if task, err = a.store.Get(ctx, id); err != nil {
+			return err
+		}
+		if !ifMatch(r, task.Version) {
+			return errPreconditionFailed
+		}
+	}
+	rels, err := a.countSubtasks(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := a.service.ValidateDelete(task, rels[id], cascade); err != nil {
+		return err
+	}
+	var subtree []string
+	if cascade && rels[id].ActiveSubtasks > 0 {
+		if subtree, err = a.descendants(ctx, id, false); err != nil {
+			return err
+		}
+	}
+
+	if len(subtree) > 0 && r.Header.Get("If-Match") == "" {
+		results, err := store.DeleteManyAtomic(ctx, a.store, append([]string{id}, subtree...))
+		if !errors.Is(err, store.ErrAtomicUnavailable) {
+			if err := atomicDeleteError(id, results, err); err != nil {
+				return err
+			}
+			return a.confirmSubtreeDeleted(ctx, id, cascade)
+		}
+	}
+	if len(subtree) > 0 {
+		results, err := store.DeleteMany(ctx, a.store, subtree)
+		if err != nil {
+			return err
+		}
+		for _, res := range results {
+			// Subtarefas excluídas por outra requisição no meio já estão onde deveriam
+			if res.Err != nil && !errors.Is(res.Err, store.ErrNotFound) {
+				return res.Err
+			}
+		}
+	}
+	if r.Header.Get("If-Match") != "" {
+		err = a.store.DeleteIfVersion(ctx, id, task.Version)
+	} else {
+		err = a.store.Delete(ctx, id)
+	}
+	if err != nil {
+		return err
+	}
+	return a.confirmSubtreeDeleted(ctx, id, cascade)
+}
+
+// confirmSubtreeDeleted confere as subtarefas ativas criadas ou movidas para baixo da
+// tarefa enquanto ela era excluída: sem cascade a tarefa é restaurada; com cascade elas
+// também vão para a lixeira
+func (a *API) confirmSubtreeDeleted(ctx context.Context, id string, cascade bool) error {
+	if !cascade {
+		restored, err := a.confirmDeleted(ctx, id)
+		if err != nil {
+			return err
+		}
+		return restored[id]
+	}
+	rest, err := a.descendants(ctx, id, false)
+	if err != nil || len(rest) == 0 {
+		return err
+	}
+	results, err := store.DeleteMany(ctx, a.store, rest)
+	if err != nil {
+		return err
+	}
+	for _, res := range results {
+		if res.Err != nil && !errors.Is(res.Err, store.ErrNotFound) {
+			return res.Err
+		}
+	}
+	return nil
+}
+
+// atomicDeleteError traduz o resultado do lote atômico de deleteSubtree: o erro da tarefa
+// id, se houver, ou conflito quando uma subtarefa saiu da lixeira ou entrou nela no meio
+func atomicDeleteError(id string, results []store.BulkResult, err error) error {
+	if err != nil {
+		return err
+	}
+	for _, res := range results {
+		if res.Err == nil || errors.Is(res.Err, store.ErrBatchAborted) {
+			continue
+		}
+		if res.Task.ID == id {
+			return res.Err
+		}
+		return store.ErrVersionConflict
+	}
+	return nil
+}
+
+// purgeSubtree remove a tarefa definitivamente e, com cascade, toda a subárvore, inclusive
+// as subtarefas que já estavam na lixeira. As subtarefas ativas vão antes para a lixeira
+// num lote atômico, quando o Store permite; depois a subárvore é removida do nível mais
+// fundo para cima e a tarefa sai por último. Uma falha no meio nunca deixa subtarefas sob
+// um pai que não existe mais
+func (a *API) purgeSubtree(ctx context.Context, id string, cascade bool) error {
+	if err := a.checkPurgeable(ctx, id); err != nil {
+		return err
+	}
+	rels, err := a.countSubtasks(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := a.service.ValidateDelete(models.Task{ID: id}, rels[id], cascade); err != nil {
+		return err
+	}
+	if cascade {
+		if rels[id].ActiveSubtasks > 0 {
+			active, err := a.descendants(ctx, id, false)
+			if err != nil {
+				return err
+			}
+			results, err := store.DeleteManyAtomic(ctx, a.store, active)
+			if !errors.Is(err, store.ErrAtomicUnavailable) {
+				if err := atomicDeleteError(id, results, err); err != nil {
+					return err
+				}
+			}
+		}
+		subtree, err := a.descendants(ctx, id, true)
+		if err != nil {
+			return err
+		}
+		for i := len(subtree) - 1; i >= 0; i-- {
+			if err := a.store.Purge(ctx, subtree[i]); err != nil && !errors.Is(err, store.ErrNotFound) {
+				return err
+			}
+		}
+	}
+	if err := a.store.Purge(ctx, id); err != nil {
+		return err
+	}
+	return a.confirmPurged(ctx, id, cascade)
+}
+
+// checkPurgeable retorna ErrNotFound se a tarefa não está ativa nem na lixeira, antes que
+// a remoção das subtarefas comece
+func (a *API) checkPurgeable(ctx context.Context, id string) error {
+	_, err := a.store.Get(ctx, id)
+	if !errors.Is(err, store.ErrNotFound) {
+		return err
+	}
+	page, err := a.store.Query(ctx, models.TaskQuery{
+		Filters: []models.TaskFilter{{Field: "id", Op: models.FilterEq, Value: id}},
+		Deleted: true,
+	})
+	if err != nil {
+		return err
+	}
+	if page.Total == 0 {
+		return store.ErrNotFound
+	}
+	return nil
+}
+
+// confirmPurged trata as subtarefas ativas criadas ou movidas para baixo da tarefa
+// enquanto ela era removida: com cascade também são removidas; sem cascade, como a tarefa
+// não pode mais ser restaurada, viram tarefas raiz, como na restauração de órfãs
+func (a *API) confirmPurged(ctx context.Context, id string, cascade bool) error {
+	if cascade {
+		rest, err := a.descendants(ctx, id, false)
+		if err != nil {
+			return err
+		}
+		for _, child := range rest {
+			if err := a.store.Purge(ctx, child); err != nil && !errors.Is(err, store.ErrNotFound) {
+				return err
+			}
+		}
+		return nil
+	}
+	children, err := a.subtasksOf(ctx, []string{id}, nil)
+	if err != nil {
+		return err
+	}
+	errMoved := errors.New("subtask moved")
+	for _, c := range children {
+		_, err := a.store.UpdateWith(ctx, c.ID, func(current models.Task) (map[string]interface{}, error) {
+			if current.ParentID != id {
+				return nil, errMoved
+			}
+			return map[string]interface{}{"parent_id": nil}, nil
+		})
+		if err != nil && !errors.Is(err, errMoved) && !errors.Is(err, store.ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// detachOrphan acrescenta ao patch a limpeza de parent_id quando o pai da tarefa
+// restaurada não está mais ativo
+func (a *API) detachOrphan(ctx context.Context, t models.Task, patch map[string]interface{}) error {
+	if t.ParentID == "" {
+		return nil
+	}
+	_, err := a.store.Get(ctx, t.ParentID)
+	if !errors.Is(err, store.ErrNotFound) {
+		return err
+	}
+	patch["parent_id"] = nil
+	return nil
+}
+
+// covers indica se os bloqueios da tarefa são os que foram contados para o lote
+func (h bulkUpdateCheck) covers(current models.Task) bool {
+	counted, ok := h.blockedBy[current.ID]
+	return !ok || sameIDs(current.BlockedBy, counted)
+}
+
+func hasFilter(filters []models.TaskFilter, field string) bool {
+	for _, f := range filters {
+		if f.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// subtaskSort é a ordenação das subtarefas na árvore: a da listagem, sem score, que só
+// existe para as tarefas de primeiro nível
+func subtaskSort(q models.TaskQuery) []models.SortKey {
+	var keys []models.SortKey
+	for _, k := range q.SortKeys() {
+		if k.Field != "score" {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// bulkUpdateCheck guarda as checagens de hierarquia e de dependências de um lote, feitas
+// antes de UpdateMany. blockedBy guarda os bloqueios contados de cada tarefa, para que a
+// escrita possa conferir que eles não mudaram no meio
+type bulkUpdateCheck struct {
+	rejected  map[string]error
+	rels      map[string]models.TaskRelations
+	blockedBy map[string][]string
+}
+
+// checkBulkUpdate valida o novo pai e o novo projeto do patch para cada tarefa do lote e
+// conta as subtarefas abertas quando o patch conclui as tarefas e os bloqueios abertos
+// quando o patch as inicia
+func (a *API) checkBulkUpdate(ctx context.Context, ids []string, patch map[string]interface{}) (bulkUpdateCheck, error) {
+	h := bulkUpdateCheck{rejected: map[string]error{}, rels: map[string]models.TaskRelations{}, blockedBy: map[string][]string{}}
+	if parent, ok := patch["parent_id"].(string); ok && parent != "" {
+		chain, err := a.parentChain(ctx, parent)
+		var apiErr *models.APIError
+		if err != nil && !errors.As(err, &apiErr) {
+			return h, err
+		}
+		for _, id := range ids {
+			switch {
+			case err != nil:
+				h.rejected[id] = err
+			case chain[id]:
+				h.rejected[id] = errParentCycle
+			}
+		}
+	}
+	if project, ok := patch["project_id"].(string); ok && project != "" {
+		if err := a.checkProject(ctx, project); err != nil {
+			var apiErr *models.APIError
+			if !errors.As(err, &apiErr) {
+				return h, err
+			}
+			for _, id := range ids {
+				if h.rejected[id] == nil {
+					h.rejected[id] = err
+				}
+			}
+		}
+	}
+	if status, _ := patch["status"].(string); models.IsCompletedTask(status) && len(ids) > 0 {
+		rels, err := a.countSubtasks(ctx, ids...)
+		if err != nil {
+			return h, err
+		}
+		for id, rel := range rels {
+			h.rels[id] = models.TaskRelations{OpenSubtasks: rel.OpenSubtasks}
+		}
+	}
+	if status, _ := patch["status"].(string); (status == models.StatusInProgress || models.IsCompletedTask(status)) && len(ids) > 0 {
+		g, err := a.loadDependencyGraph(ctx)
+		if err != nil {
+			return h, err
+		}
+		for _, id := range ids {
+			rel := h.rels[id]
+			rel.OpenBlockers = g.openBlockers(g.tasks[id])
+			h.rels[id] = rel
+			h.blockedBy[id] = g.tasks[id].BlockedBy
+		}
+	}
+	return h, nil
+}
+
+// rejectedDeletes aplica as regras de exclusão às tarefas de um lote; as subtarefas que
+// também estão no lote não contam
+func (a *API) rejectedDeletes(ctx context.Context, ids []string) (map[string]error, error) {
+	children, err := a.subtasksOf(ctx, ids, nil)
+	if err != nil {
+		return nil, err
+	}
+	inBatch := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		inBatch[id] = true
+	}
+	remaining := map[string]int{}
+	for _, c := range children {
+		if !inBatch[c.ID] {
+			remaining[c.ParentID]++
+		}
+	}
+	rejected := map[string]error{}
+	for id, n := range remaining {
+		if err := a.service.ValidateDelete(models.Task{ID: id}, models.TaskRelations{ActiveSubtasks: n}, false); err != nil {
+			rejected[id] = err
+		}
+	}
+	return rejected, nil
+}
@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"example.com/tasksapi/models"
+	"example.com/tasksapi/store"
+)
+
+// ListTags lista as tags em uso pelas tarefas ativas, com quantas tarefas usam cada uma;
+// as mais usadas vêm primeiro
+func (a *API) ListTags(w http.ResponseWriter, r *http.Request) {
+	counts, err := store.TagCounts(r.Context(), a.store)
+	if models.HandleError(w, a.storeError(err), http.StatusInternalServerError) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(models.TagListResponse{Tags: counts, TotalItems: len(counts)})
+}
@@ -1,7 +1,14 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"example.com/tasksapi/models"
 	"example.com/tasksapi/router"
@@ -10,10 +17,43 @@ import (
 
 func main() {
 	logger := models.NewDefaultLogger()
-	r := router.New()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		migrate(logger, os.Args[2:])
+		return
+	}
+	// SIGINT/SIGTERM cancelam ctx: o servidor para de aceitar conexões e a purga da lixeira encerra
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	r := router.NewWithContext(ctx, logger)
 	c := cors.AllowAll() // For development, allow all origins
+	srv := &http.Server{Addr: ":8080", Handler: c.Handler(r)}
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			logger.Error("server shutdown failed: %v", err)
+		}
+	}()
+
 	logger.Info("starting server on :8080")
-	if err := http.ListenAndServe(":8080", c.Handler(r)); err != nil {
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		logger.Fatal("server failed: %v", err)
 	}
+	// Espera as requisições em andamento terminarem
+	<-stopped
+	logger.Info("server stopped")
+}
+
+// migrate aplica o esquema do backend configurado e sai: taskapi migrate [-drop-obsolete]
+func migrate(logger models.Logger, args []string) {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dropObsolete := fs.Bool("drop-obsolete", false, "drop MongoDB indexes that are no longer declared")
+	_ = fs.Parse(args)
+	if err := router.Migrate(logger, *dropObsolete); err != nil {
+		logger.Fatal("migration failed: %v", err)
+	}
 }
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// Ações registradas no histórico de uma tarefa
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+	AuditPurge   = "purge"
+)
+
+// SystemActor identifica alterações feitas pela própria aplicação, como a purga da lixeira;
+// AnonymousActor, requisições sem identificação
+const (
+	SystemActor    = "system"
+	AnonymousActor = "anonymous"
+)
+
+// AuditEntry registra uma alteração da tarefa: quem fez, quando e o que mudou.
+// Version é a versão da tarefa depois da alteração (zero em purgas)
+type AuditEntry struct {
+	TaskID  string        `json:"task_id" bson:"task_id"`
+	Action  string        `json:"action" bson:"action"`
+	Actor   string        `json:"actor" bson:"actor"`
+	At      time.Time     `json:"at" bson:"at"`
+	Version int64         `json:"version,omitempty" bson:"version,omitempty"`
+	Changes []FieldChange `json:"changes,omitempty" bson:"changes,omitempty"`
+}
+
+// FieldChange guarda os valores antigo e novo na representação JSON do campo; nil indica vazio
+type FieldChange struct {
+	Field string      `json:"field" bson:"field"`
+	Old   interface{} `json:"old" bson:"old"`
+	New   interface{} `json:"new" bson:"new"`
+}
+
+type TaskHistoryResponse struct {
+	TaskID  string       `json:"task_id"`
+	Entries []AuditEntry `json:"entries"`
+}
+
+// auditIgnoredFields mudam em toda escrita e não descrevem a alteração em si
+var auditIgnoredFields = map[string]bool{"updated_at": true, "version": true, "score": true}
+
+// TaskChanges lista os campos que diferem entre as duas versões da tarefa, em ordem alfabética
+func TaskChanges(old, new Task) []FieldChange {
+	before, err := taskDocument(old)
+	if err != nil {
+		return nil
+	}
+	after, err := taskDocument(new)
+	if err != nil {
+		return nil
+	}
+	fields := map[string]struct{}{}
+	for f := range before {
+		fields[f] = struct{}{}
+	}
+	for f := range after {
+		fields[f] = struct{}{}
+	}
+
+	var changes []FieldChange
+	for f := range fields {
+		if auditIgnoredFields[f] || reflect.DeepEqual(before[f], after[f]) {
+			continue
+		}
+		changes = append(changes, FieldChange{Field: f, Old: before[f], New: after[f]})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+// ParseAsOf interpreta o parâmetro as_of: um timestamp RFC 3339 ou uma data YYYY-MM-DD,
+// que significa o fim daquele dia (UTC), incluindo as alterações feitas nele
+func ParseAsOf(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t.UTC(), nil
+	}
+	d, err := ParseDateOnly(s)
+	if err != nil {
+		return time.Time{}, NewValidationError("invalid as_of, expected YYYY-MM-DD or RFC 3339 timestamp")
+	}
+	return d.Time.Add(24*time.Hour - time.Nanosecond), nil
+}
+
+type actorKey struct{}
+
+// ContextWithActor associa ao contexto quem está fazendo a requisição
+func ContextWithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext retorna o actor da requisição, ou SystemActor fora de uma requisição
+func ActorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return SystemActor
+}
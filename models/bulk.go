@@ -0,0 +1,51 @@
+package models
+
+// MaxBulkItems limita quantas tarefas uma operação em lote pode criar, alterar ou excluir
+const MaxBulkItems = 1000
+
+// BulkCreateRequest é o corpo de POST /tasks/bulk
+type BulkCreateRequest struct {
+	Tasks []Task `json:"tasks"`
+}
+
+// BulkUpdateRequest é o corpo de PATCH /tasks/bulk: Patch (merge patch) é aplicado às
+// tarefas de IDs e/ou às que casam com os filtros da query string
+type BulkUpdateRequest struct {
+	IDs   []string               `json:"ids,omitempty"`
+	Patch map[string]interface{} `json:"patch"`
+}
+
+// BulkDeleteRequest é o corpo, opcional com filtros, de DELETE /tasks/bulk
+type BulkDeleteRequest struct {
+	IDs []string `json:"ids,omitempty"`
+}
+
+// BulkItemResult é o resultado de um item do lote. Index é a posição do item na
+// requisição (em tasks ou ids) ou, com filtros, na lista de tarefas selecionadas
+type BulkItemResult struct {
+	Index  int       `json:"index"`
+	ID     string    `json:"id,omitempty"`
+	Status int       `json:"status"`
+	Task   *Task     `json:"task,omitempty"`
+	Error  *APIError `json:"error,omitempty"`
+}
+
+// BulkResponse reúne os resultados por item de uma operação em lote. Num lote atômico
+// que falhou nada foi gravado e Error resume as falhas
+type BulkResponse struct {
+	Results   []BulkItemResult `json:"results"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Atomic    bool             `json:"atomic,omitempty"`
+	Error     *APIError        `json:"error,omitempty"`
+}
+
+// Add registra o resultado de um item e atualiza os contadores
+func (r *BulkResponse) Add(item BulkItemResult) {
+	if item.Error != nil {
+		r.Failed++
+	} else {
+		r.Succeeded++
+	}
+	r.Results = append(r.Results, item)
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// cursorPayload é o conteúdo do cursor opaco: a ordenação usada e os valores de
+// ordenação da última tarefa entregue. Como o cursor aponta para uma posição na
+// ordem (e não para um índice), criações e remoções concorrentes não deslocam a página
+type cursorPayload struct {
+	Sort   string        `json:"s"`
+	Values []interface{} `json:"v"`
+}
+
+var timeSortFields = map[string]struct{}{"due_date": {}, "created_at": {}, "updated_at": {}, "deleted_at": {}}
+
+// EncodeCursor gera o cursor que continua a listagem logo após a tarefa
+func EncodeCursor(t Task, q TaskQuery) string {
+	keys := q.SortKeys()
+	p := cursorPayload{Sort: sortSignature(q), Values: make([]interface{}, len(keys))}
+	for i, k := range keys {
+		v := SortValue(t, k.Field)
+		if tm, ok := v.(time.Time); ok {
+			v = tm.UTC().Format(time.RFC3339Nano)
+		}
+		p.Values[i] = v
+	}
+	data, _ := json.Marshal(p)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor valida o cursor contra a ordenação atual e devolve os valores de ordenação
+func DecodeCursor(cursor string, q TaskQuery) ([]interface{}, error) {
+	keys := q.SortKeys()
+	invalid := NewValidationError("invalid cursor")
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, invalid
+	}
+	var p cursorPayload
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, invalid
+	}
+	if p.Sort != sortSignature(q) || len(p.Values) != len(keys) {
+		return nil, NewValidationError("cursor does not match the requested sort")
+	}
+	values := make([]interface{}, len(keys))
+	for i, k := range keys {
+		if p.Values[i] == nil {
+			continue
+		}
+		if k.Field == "priority" || k.Field == "score" {
+			n, ok := p.Values[i].(float64)
+			if !ok {
+				return nil, invalid
+			}
+			values[i] = n
+			if k.Field == "priority" {
+				values[i] = int(n)
+			}
+			continue
+		}
+		s, ok := p.Values[i].(string)
+		if !ok {
+			return nil, invalid
+		}
+		values[i] = s
+		if _, isTime := timeSortFields[k.Field]; isTime {
+			tm, err := time.Parse(time.RFC3339Nano, s)
+			if err != nil {
+				return nil, invalid
+			}
+			values[i] = tm
+		}
+	}
+	return values, nil
+}
+
+// sortSignature identifica a ordenação para que um cursor não seja reutilizado com outra
+func sortSignature(q TaskQuery) string {
+	keys := q.SortKeys()
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k.Field
+		if k.Desc {
+			parts[i] = "-" + k.Field
+		}
+	}
+	signature := strings.Join(parts, ",")
+	if q.NullsFirst {
+		signature += ";nulls=first"
+	}
+	return signature
+}
@@ -0,0 +1,38 @@
+package models
+
+// DependencyRequest é o corpo de POST /tasks/{id}/dependencies: TaskID passa a bloquear a tarefa
+type DependencyRequest struct {
+	TaskID string `json:"task_id"`
+}
+
+// DependencyNode é uma tarefa do grafo de dependências. Depth é a distância até a tarefa
+// consultada; BlockedBy traz só as arestas para tarefas ativas
+type DependencyNode struct {
+	ID        string   `json:"id"`
+	Title     string   `json:"title"`
+	Status    string   `json:"status"`
+	BlockedBy []string `json:"blocked_by"`
+	Depth     int      `json:"depth"`
+}
+
+// DependencyGraphResponse é a resposta de GET /tasks/{id}/dependencies: Upstream são as
+// tarefas que bloqueiam a tarefa, direta ou indiretamente, e Downstream as que ela bloqueia
+type DependencyGraphResponse struct {
+	TaskID     string           `json:"task_id"`
+	Blocked    bool             `json:"blocked"`
+	Upstream   []DependencyNode `json:"upstream"`
+	Downstream []DependencyNode `json:"downstream"`
+}
+
+// ActionableTask é uma tarefa aberta sem bloqueios abertos. Unblocks conta as tarefas
+// abertas que dependem dela, direta ou indiretamente
+type ActionableTask struct {
+	Task
+	Unblocks int `json:"unblocks"`
+}
+
+// ActionableResponse é a resposta de GET /tasks/actionable
+type ActionableResponse struct {
+	Tasks      []ActionableTask `json:"tasks"`
+	TotalItems int              `json:"total_items"`
+}
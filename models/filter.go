@@ -0,0 +1,425 @@
+package models
+
+import (
+	"net/url"
+	"strings"
+	"time"
+)
+
+// FilterOp identifica o tipo de comparação de um TaskFilter
+type FilterOp string
+
+const (
+	FilterEq      FilterOp = "eq"
+	FilterIn      FilterOp = "in"
+	FilterNotIn   FilterOp = "nin"
+	FilterIsNull  FilterOp = "null"
+	FilterNotNull FilterOp = "notnull"
+	FilterLt      FilterOp = "lt"
+	FilterLte     FilterOp = "lte"
+	FilterGt      FilterOp = "gt"
+	FilterGte     FilterOp = "gte"
+	// FilterAll exige todos os valores de Values; só vale para campos de lista (tags)
+	FilterAll FilterOp = "all"
+)
+
+// NullLiteral é o valor de query string que seleciona tarefas sem o campo preenchido
+const NullLiteral = "null"
+
+// TaskFilter restringe um campo da tarefa. Value (ou Values, para FilterIn/FilterNotIn/FilterAll)
+// é sempre a representação textual, convertida por FilterValue, para que todos os
+// backends interpretem o filtro da mesma forma. Values pode conter NullLiteral
+type TaskFilter struct {
+	Field  string
+	Op     FilterOp
+	Value  string
+	Values []string
+}
+
+type fieldKind int
+
+const (
+	kindString fieldKind = iota
+	kindDate
+	kindTime
+	// kindList são campos com vários valores: os filtros casam com qualquer elemento
+	kindList
+)
+
+var filterableFields = map[string]fieldKind{
+	"status":     kindString,
+	"priority":   kindString,
+	"due_date":   kindDate,
+	"parent_id":  kindString,
+	"created_at": kindTime,
+	"updated_at": kindTime,
+	"tags":       kindList,
+	"project_id": kindString,
+	// blocked_by só aceita null e !null (tarefas sem ou com bloqueios)
+	"blocked_by": kindList,
+	// id não é exposto em GET /tasks; serve para buscar várias tarefas numa consulta
+	"id": kindString,
+}
+
+// rangeParams mapeia os query params de intervalo para o filtro correspondente
+var rangeParams = []struct {
+	param string
+	field string
+	op    FilterOp
+}{
+	{"due_before", "due_date", FilterLt},
+	{"due_after", "due_date", FilterGt},
+	{"created_before", "created_at", FilterLt},
+	{"created_after", "created_at", FilterGt},
+	{"updated_since", "updated_at", FilterGte},
+}
+
+// ParseFilters interpreta os filtros de GET /tasks:
+//   - status=pending,in_progress  (um ou mais valores; "null" seleciona o campo vazio)
+//   - status!=completed           (negação, também com vários valores)
+//   - parent_id=<id>              (subtarefas da tarefa; parent_id=null seleciona as raiz)
+//   - project_id=<id>             (tarefas do projeto; project_id=null seleciona as sem projeto)
+//   - due_before/due_after, created_before/created_after, updated_since (intervalos)
+//   - tag=backend&tag=urgent      (alguma das tags; com tag_match=all, todas elas)
+//   - tag!=wontfix                (nenhuma das tags; tag=null seleciona as tarefas sem tags)
+//   - blocked_by!=null            (tarefas com dependências; blocked_by=null, as sem)
+func ParseFilters(values url.Values) ([]TaskFilter, error) {
+	var filters []TaskFilter
+	for _, field := range []string{"status", "priority", "due_date", "parent_id", "project_id", "blocked_by"} {
+		for _, negate := range []bool{false, true} {
+			param := field
+			if negate {
+				// "status!=completed" chega como a chave "status!"
+				param += "!"
+			}
+			list := splitValues(values[param])
+			if len(list) == 0 {
+				continue
+			}
+			f := listFilter(field, list, negate)
+			if err := f.Validate(); err != nil {
+				return nil, err
+			}
+			filters = append(filters, f)
+		}
+	}
+
+	tagFilters, err := parseTagFilters(values)
+	if err != nil {
+		return nil, err
+	}
+	filters = append(filters, tagFilters...)
+
+	for _, rp := range rangeParams {
+		v := values.Get(rp.param)
+		if v == "" {
+			continue
+		}
+		f := TaskFilter{Field: rp.field, Op: rp.op, Value: v}
+		if err := f.Validate(); err != nil {
+			return nil, NewValidationError("invalid " + rp.param + " filter, expected YYYY-MM-DD" + timeHint(rp.field))
+		}
+		filters = append(filters, f)
+	}
+	return filters, nil
+}
+
+// parseTagFilters interpreta tag, tag! e tag_match. Os valores são normalizados como as
+// tags gravadas, então o filtro não diferencia maiúsculas
+func parseTagFilters(values url.Values) ([]TaskFilter, error) {
+	all := false
+	switch values.Get("tag_match") {
+	case "", "any":
+	case "all":
+		all = true
+	default:
+		return nil, NewValidationError("invalid tag_match option, allowed: any, all")
+	}
+	var filters []TaskFilter
+	for _, negate := range []bool{false, true} {
+		param := "tag"
+		if negate {
+			param += "!"
+		}
+		list := NormalizeTags(splitValues(values[param]))
+		if len(list) == 0 {
+			continue
+		}
+		f := listFilter("tags", list, negate)
+		if all && !negate && len(list) > 1 {
+			f = TaskFilter{Field: "tags", Op: FilterAll, Values: list}
+		}
+		if err := f.Validate(); err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	return filters, nil
+}
+
+func timeHint(field string) string {
+	if filterableFields[field] == kindTime {
+		return " or RFC 3339 timestamp"
+	}
+	return ""
+}
+
+// splitValues aceita tanto parâmetros repetidos quanto listas separadas por vírgula
+func splitValues(raw []string) []string {
+	var out []string
+	for _, r := range raw {
+		for _, v := range strings.Split(r, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				out = append(out, v)
+			}
+		}
+	}
+	return out
+}
+
+// listFilter escolhe o operador mais simples que representa a lista de valores
+func listFilter(field string, list []string, negate bool) TaskFilter {
+	if len(list) == 1 {
+		switch {
+		case list[0] == NullLiteral && negate:
+			return TaskFilter{Field: field, Op: FilterNotNull}
+		case list[0] == NullLiteral:
+			return TaskFilter{Field: field, Op: FilterIsNull}
+		case !negate:
+			return TaskFilter{Field: field, Op: FilterEq, Value: list[0]}
+		}
+	}
+	if negate {
+		return TaskFilter{Field: field, Op: FilterNotIn, Values: list}
+	}
+	return TaskFilter{Field: field, Op: FilterIn, Values: list}
+}
+
+// Validate verifica o campo e converte os valores, rejeitando datas mal formadas
+func (f TaskFilter) Validate() error {
+	kind, ok := filterableFields[f.Field]
+	if !ok {
+		return NewValidationError("cannot filter by field: " + f.Field)
+	}
+	if f.Field == "blocked_by" && f.Op != FilterIsNull && f.Op != FilterNotNull {
+		return NewValidationError("blocked_by only supports null filters: blocked_by=null or blocked_by!=null")
+	}
+	if kind == kindList {
+		if _, isRange := rangeOps[f.Op]; isRange {
+			return NewValidationError("range filters are not supported on " + f.Field)
+		}
+	} else if f.Op == FilterAll {
+		return NewValidationError("filter on " + f.Field + " does not support matching all values")
+	}
+	switch f.Op {
+	case FilterIsNull, FilterNotNull:
+		return nil
+	case FilterAll:
+		if len(f.Values) == 0 {
+			return NewValidationError("filter on " + f.Field + " requires at least one value")
+		}
+		for _, v := range f.Values {
+			if v == NullLiteral {
+				return NewValidationError("null cannot be combined with tag_match=all")
+			}
+			if _, err := FilterValue(f.Field, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FilterIn, FilterNotIn:
+		if len(f.Values) == 0 {
+			return NewValidationError("filter on " + f.Field + " requires at least one value")
+		}
+		for _, v := range f.Values {
+			if v == NullLiteral {
+				continue
+			}
+			if _, err := FilterValue(f.Field, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FilterEq, FilterLt, FilterLte, FilterGt, FilterGte:
+		_, err := FilterValue(f.Field, f.Value)
+		return err
+	}
+	return NewValidationError("unsupported filter operator: " + string(f.Op))
+}
+
+// rangeOps são os operadores de intervalo, que não se aplicam a campos de lista
+var rangeOps = map[FilterOp]struct{}{FilterLt: {}, FilterLte: {}, FilterGt: {}, FilterGte: {}}
+
+// FilterValue converte o valor textual do filtro para o tipo do campo: string para
+// status/priority, a tag normalizada para tags e time.Time (UTC) para datas. due_date
+// aceita YYYY-MM-DD; created_at e updated_at aceitam também timestamps RFC 3339
+func FilterValue(field, raw string) (interface{}, error) {
+	switch filterableFields[field] {
+	case kindList:
+		tag := NormalizeTag(raw)
+		if err := ValidateTag(tag); err != nil {
+			return nil, err
+		}
+		return tag, nil
+	case kindDate:
+		d, err := ParseDateOnly(raw)
+		if err != nil {
+			return nil, NewValidationError("invalid " + field + " filter, expected YYYY-MM-DD")
+		}
+		return d.Time, nil
+	case kindTime:
+		if tm, err := time.Parse(time.RFC3339Nano, raw); err == nil {
+			return tm.UTC(), nil
+		}
+		d, err := ParseDateOnly(raw)
+		if err != nil {
+			return nil, NewValidationError("invalid " + field + " filter, expected YYYY-MM-DD or RFC 3339 timestamp")
+		}
+		return d.Time, nil
+	}
+	return raw, nil
+}
+
+// Match avalia o filtro contra a tarefa. Campos vazios nunca satisfazem comparações
+// de valor ou de intervalo
+func (f TaskFilter) Match(t Task) bool {
+	if f.Field == "blocked_by" {
+		return f.matchList(t.BlockedBy)
+	}
+	if filterableFields[f.Field] == kindList {
+		return f.matchList(t.Tags)
+	}
+	value := filterFieldValue(t, f.Field)
+	switch f.Op {
+	case FilterIsNull:
+		return value == nil
+	case FilterNotNull:
+		return value != nil
+	case FilterIn:
+		return f.contains(value)
+	case FilterNotIn:
+		return !f.contains(value)
+	}
+
+	if value == nil {
+		return false
+	}
+	target, err := FilterValue(f.Field, f.Value)
+	if err != nil {
+		return false
+	}
+	c := compareValues(value, target)
+	switch f.Op {
+	case FilterEq:
+		return c == 0
+	case FilterLt:
+		return c < 0
+	case FilterLte:
+		return c <= 0
+	case FilterGt:
+		return c > 0
+	case FilterGte:
+		return c >= 0
+	}
+	return false
+}
+
+func (f TaskFilter) contains(value interface{}) bool {
+	for _, raw := range f.Values {
+		if raw == NullLiteral {
+			if value == nil {
+				return true
+			}
+			continue
+		}
+		if value == nil {
+			continue
+		}
+		if target, err := FilterValue(f.Field, raw); err == nil && compareValues(value, target) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// matchList avalia o filtro contra um campo de lista: eq e in casam com qualquer elemento,
+// nin com nenhum e all exige todos; a lista vazia conta como nula
+func (f TaskFilter) matchList(list []string) bool {
+	has := make(map[string]bool, len(list))
+	for _, v := range list {
+		has[v] = true
+	}
+	matches := func(raw string) bool {
+		if raw == NullLiteral {
+			return len(list) == 0
+		}
+		v, err := FilterValue(f.Field, raw)
+		return err == nil && has[v.(string)]
+	}
+	switch f.Op {
+	case FilterIsNull:
+		return len(list) == 0
+	case FilterNotNull:
+		return len(list) > 0
+	case FilterEq:
+		return matches(f.Value)
+	case FilterAll:
+		for _, raw := range f.Values {
+			if !matches(raw) {
+				return false
+			}
+		}
+		return true
+	case FilterIn, FilterNotIn:
+		found := false
+		for _, raw := range f.Values {
+			if matches(raw) {
+				found = true
+				break
+			}
+		}
+		return found == (f.Op == FilterIn)
+	}
+	return false
+}
+
+// filterFieldValue retorna o valor do campo no mesmo tipo de FilterValue, ou nil quando vazio
+func filterFieldValue(t Task, field string) interface{} {
+	switch field {
+	case "status":
+		if t.Status == "" {
+			return nil
+		}
+		return t.Status
+	case "priority":
+		if t.Priority == "" {
+			return nil
+		}
+		return t.Priority
+	case "due_date":
+		if t.DueDate == nil || t.DueDate.IsZero() {
+			return nil
+		}
+		return t.DueDate.UTC()
+	case "parent_id":
+		if t.ParentID == "" {
+			return nil
+		}
+		return t.ParentID
+	case "project_id":
+		if t.ProjectID == "" {
+			return nil
+		}
+		return t.ProjectID
+	case "created_at":
+		return t.CreatedAt.UTC()
+	case "updated_at":
+		if t.UpdatedAt == nil {
+			return nil
+		}
+		return t.UpdatedAt.UTC()
+	case "id":
+		return t.ID
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"net/http"
+	"time"
+)
+
+// IdempotencyRecord guarda a resposta da requisição que usou uma Idempotency-Key, para que
+// as repetições recebam a mesma resposta. Status zero indica que ela ainda está em andamento
+type IdempotencyRecord struct {
+	Key         string      `bson:"_id"`
+	Fingerprint string      `bson:"fingerprint"`
+	Status      int         `bson:"status"`
+	Header      http.Header `bson:"header,omitempty"`
+	Body        []byte      `bson:"body,omitempty"`
+	CreatedAt   time.Time   `bson:"created_at"`
+	ExpiresAt   time.Time   `bson:"expires_at"`
+}
+
+// Completed indica se a resposta original já foi gravada
+func (r IdempotencyRecord) Completed() bool {
+	return r.Status != 0
+}
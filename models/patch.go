@@ -0,0 +1,150 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// Formatos aceitos por PATCH /tasks/{id}
+const (
+	MergePatchContentType = "application/merge-patch+json"
+	JSONPatchContentType  = "application/json-patch+json"
+)
+
+// PatchOperation é uma operação de JSON Patch (RFC 6902). Value guarda o JSON cru
+// para distinguir null de ausente
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// ReplacementPatch converte o corpo de um PUT (representação completa da tarefa) em patch:
+// todo campo editável ausente ou null vira nil, ou seja, é limpo; nos campos opcionais, ""
+// também (o GET serializa "priority": ""). Campos somente leitura enviados de volta pelo
+// cliente são ignorados
+func ReplacementPatch(doc map[string]interface{}) (map[string]interface{}, error) {
+	for field := range doc {
+		if _, ok := allowedUpdateFields[field]; !ok && !readOnlyFields[field] {
+			return nil, NewValidationError("unknown field: " + field)
+		}
+	}
+	patch := make(map[string]interface{}, len(allowedUpdateFields))
+	for field := range allowedUpdateFields {
+		if v := doc[field]; v != "" || !nullableFields[field] {
+			patch[field] = v
+		} else {
+			patch[field] = nil
+		}
+	}
+	return patch, nil
+}
+
+// ApplyJSONPatch aplica as operações add, remove, replace e test sobre a representação
+// JSON da tarefa e devolve o patch com os campos que mudaram (nil para os removidos).
+// Uma operação test que falha gera erro 409, pois depende do estado atual
+func ApplyJSONPatch(t Task, ops []PatchOperation) (map[string]interface{}, error) {
+	before, err := taskDocument(t)
+	if err != nil {
+		return nil, err
+	}
+	doc := make(map[string]interface{}, len(before))
+	for k, v := range before {
+		doc[k] = v
+	}
+
+	for _, op := range ops {
+		field, err := patchPath(op.Path)
+		if err != nil {
+			return nil, err
+		}
+		var value interface{}
+		switch op.Op {
+		case "add", "replace", "test":
+			if len(op.Value) == 0 {
+				return nil, NewValidationError(op.Op + " operation on " + op.Path + " requires a value")
+			}
+			if err := json.Unmarshal(op.Value, &value); err != nil {
+				return nil, NewValidationError("invalid value for " + op.Path)
+			}
+		case "remove":
+		default:
+			return nil, NewValidationError("unsupported patch operation: " + op.Op + ", allowed: add, remove, replace, test")
+		}
+
+		_, exists := doc[field]
+		if !exists && op.Op != "add" {
+			return nil, NewValidationError("path not found: " + op.Path)
+		}
+		switch op.Op {
+		case "add", "replace":
+			doc[field] = value
+		case "remove":
+			delete(doc, field)
+		case "test":
+			if !reflect.DeepEqual(doc[field], value) {
+				return nil, NewBusinessRuleError("test operation failed for path " + op.Path)
+			}
+		}
+	}
+
+	patch := map[string]interface{}{}
+	for field, value := range doc {
+		if old, ok := before[field]; !ok || !reflect.DeepEqual(old, value) {
+			patch[field] = value
+		}
+	}
+	for field := range before {
+		if _, ok := doc[field]; !ok {
+			patch[field] = nil
+		}
+	}
+	for field := range patch {
+		if readOnlyFields[field] {
+			return nil, NewValidationError("field is read-only: " + field)
+		}
+	}
+	return patch, nil
+}
+
+// RevertPatch monta o patch que devolve aos valores de before os campos alterados por
+// patch; campos opcionais que estavam vazios voltam como nil
+func RevertPatch(before Task, patch map[string]interface{}) (map[string]interface{}, error) {
+	doc, err := taskDocument(before)
+	if err != nil {
+		return nil, err
+	}
+	revert := make(map[string]interface{}, len(patch))
+	for field := range patch {
+		revert[field] = doc[field]
+	}
+	return revert, nil
+}
+
+// taskDocument é a representação JSON da tarefa como mapa; campos opcionais vazios ficam
+// de fora, então replace, remove e test neles respondem "path not found"
+func taskDocument(t Task) (map[string]interface{}, error) {
+	raw, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	for field := range nullableFields {
+		if v, ok := doc[field]; ok && (v == nil || v == "") {
+			delete(doc, field)
+		}
+	}
+	return doc, nil
+}
+
+// patchPath converte um JSON Pointer de um nível ("/due_date") no nome do campo
+func patchPath(path string) (string, error) {
+	if !strings.HasPrefix(path, "/") || strings.Count(path, "/") != 1 || len(path) == 1 {
+		return "", NewValidationError("invalid patch path: " + path)
+	}
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(path[1:]), nil
+}
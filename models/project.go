@@ -0,0 +1,122 @@
+package models
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxProjectNameLength é o tamanho máximo do nome do projeto
+const MaxProjectNameLength = 100
+
+// Project agrupa tarefas (Task.ProjectID). Um projeto arquivado continua existindo, mas as
+// tarefas dele saem de GET /tasks e não recebem tarefas novas
+type Project struct {
+	ID          string `json:"id" bson:"_id"`
+	Name        string `json:"name" bson:"name"`
+	Description string `json:"description,omitempty" bson:"description,omitempty"`
+	// ArchivedAt marca o projeto como arquivado; vazio nos projetos ativos
+	ArchivedAt *time.Time `json:"archived_at,omitempty" bson:"archived_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at" bson:"created_at"`
+	UpdatedAt  *time.Time `json:"updated_at" bson:"updated_at,omitempty"`
+	// Version começa em 1 e é incrementada a cada alteração, como na tarefa
+	Version int64 `json:"version" bson:"version"`
+}
+
+// Archived indica se o projeto está arquivado
+func (p Project) Archived() bool {
+	return p.ArchivedAt != nil
+}
+
+type ProjectListResponse struct {
+	Projects   []Project `json:"projects"`
+	TotalItems int       `json:"total_items"`
+}
+
+// ProjectStats resume as tarefas ativas do projeto (GET /projects/{id}/stats). Tarefas
+// sem prioridade são contadas em ByPriority["none"]
+type ProjectStats struct {
+	ProjectID  string         `json:"project_id"`
+	TotalTasks int            `json:"total_tasks"`
+	ByStatus   map[string]int `json:"by_status"`
+	ByPriority map[string]int `json:"by_priority"`
+	// Overdue conta as tarefas abertas com due_date anterior a hoje
+	Overdue int `json:"overdue"`
+	// CompletionRate é a fração das tarefas concluídas, de 0 a 1; zero sem tarefas
+	CompletionRate float64 `json:"completion_rate"`
+}
+
+// NewProjectStats calcula as estatísticas das tarefas do projeto; now define o que está atrasado
+func NewProjectStats(projectID string, tasks []Task, now time.Time) ProjectStats {
+	stats := ProjectStats{
+		ProjectID:  projectID,
+		TotalTasks: len(tasks),
+		ByStatus:   map[string]int{},
+		ByPriority: map[string]int{"none": 0},
+	}
+	for status := range ValidStatuses {
+		stats.ByStatus[status] = 0
+	}
+	for priority := range ValidPriorities {
+		stats.ByPriority[priority] = 0
+	}
+	now = now.UTC()
+	today := NewDate(now.Year(), now.Month(), now.Day())
+	for _, t := range tasks {
+		stats.ByStatus[t.Status]++
+		if t.Priority == "" {
+			stats.ByPriority["none"]++
+		} else {
+			stats.ByPriority[t.Priority]++
+		}
+		if IsOpenTask(t.Status) && t.DueDate != nil && t.DueDate.Before(today.Time) {
+			stats.Overdue++
+		}
+	}
+	if len(tasks) > 0 {
+		stats.CompletionRate = float64(stats.ByStatus[StatusCompleted]) / float64(len(tasks))
+	}
+	return stats
+}
+
+// ValidateProject normaliza e valida os campos editáveis de um projeto novo
+func ValidateProject(p *Project) error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return NewValidationError("name is required")
+	}
+	if utf8.RuneCountInString(p.Name) > MaxProjectNameLength {
+		return NewValidationError("invalid name length, it should be at most 100 characters")
+	}
+	return nil
+}
+
+// ApplyProjectPatch aplica um merge patch com name e/ou description (null limpa a
+// descrição) e valida o resultado
+func ApplyProjectPatch(p Project, patch map[string]interface{}) (Project, error) {
+	if len(patch) == 0 {
+		return p, NewValidationError("no fields to update")
+	}
+	for field, v := range patch {
+		switch field {
+		case "name":
+			s, ok := v.(string)
+			if !ok {
+				return p, NewValidationError("name must be a string")
+			}
+			p.Name = s
+		case "description":
+			switch vv := v.(type) {
+			case nil:
+				p.Description = ""
+			case string:
+				p.Description = vv
+			default:
+				return p, NewValidationError("description must be a string")
+			}
+		default:
+			return p, NewValidationError("unknown field: " + field)
+		}
+	}
+	return p, ValidateProject(&p)
+}
@@ -0,0 +1,320 @@
+package models
+
+import (
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SortKey ordena por um campo da tarefa; Desc inverte a direção
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// TaskQuery descreve uma listagem completa: filtros, ordenação e paginação.
+// Limit zero significa sem limite. After, quando presente, contém os valores de
+// ordenação (alinhados com SortKeys) da última tarefa da página anterior.
+// Campos vazios ficam no fim da ordenação, ou no início com NullsFirst
+type TaskQuery struct {
+	Filters []TaskFilter
+	// Search restringe o resultado às tarefas com alguma das palavras em title ou
+	// description (sem diferenciar maiúsculas ou acentos) e habilita a ordenação por score
+	Search     string
+	Sort       []SortKey
+	NullsFirst bool
+	Limit      int
+	Offset     int
+	After      []interface{}
+	// Deleted lista a lixeira (tarefas com DeletedAt) em vez das tarefas ativas
+	Deleted bool
+}
+
+// TaskPage é uma página do resultado de um TaskQuery. Total conta todas as tarefas
+// que satisfazem os filtros, independente de Limit/Offset/After
+type TaskPage struct {
+	Tasks      []Task
+	Total      int
+	NextCursor string
+}
+
+const (
+	DefaultPageLimit = 100
+	MaxPageLimit     = 1000
+)
+
+// DefaultSort garante ordem determinística entre backends
+var DefaultSort = []SortKey{{Field: "created_at"}, {Field: "id"}}
+
+// SearchSort é a ordenação padrão de buscas textuais: mais relevantes primeiro
+var SearchSort = []SortKey{{Field: "score", Desc: true}, {Field: "created_at"}, {Field: "id"}}
+
+var sortableFields = map[string]struct{}{
+	"id": {}, "title": {}, "status": {}, "priority": {}, "due_date": {}, "created_at": {}, "updated_at": {}, "deleted_at": {}, "score": {},
+}
+
+// ParseTaskQuery monta um TaskQuery a partir dos query params de GET /tasks
+func ParseTaskQuery(values url.Values) (TaskQuery, error) {
+	var q TaskQuery
+	filters, err := ParseFilters(values)
+	if err != nil {
+		return TaskQuery{}, err
+	}
+	q.Filters = filters
+
+	if v := values.Get("q"); v != "" {
+		if len(SearchTerms(v)) == 0 {
+			return TaskQuery{}, NewValidationError("q must contain at least one word")
+		}
+		q.Search = v
+	}
+
+	if v := values.Get("sort"); v != "" {
+		sortKeys, err := ParseSort(v)
+		if err != nil {
+			return TaskQuery{}, err
+		}
+		q.Sort = sortKeys
+	}
+	switch values.Get("nulls") {
+	case "", "last":
+	case "first":
+		q.NullsFirst = true
+	default:
+		return TaskQuery{}, NewValidationError("invalid nulls option, allowed: first, last")
+	}
+
+	q.Limit = DefaultPageLimit
+	if v := values.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > MaxPageLimit {
+			return TaskQuery{}, NewValidationError("limit must be between 1 and " + strconv.Itoa(MaxPageLimit))
+		}
+		q.Limit = limit
+	}
+	if v := values.Get("cursor"); v != "" {
+		after, err := DecodeCursor(v, q)
+		if err != nil {
+			return TaskQuery{}, err
+		}
+		q.After = after
+	}
+	return q, q.Validate()
+}
+
+// ParseSort interpreta uma lista como "due_date,-priority,created_at"; o prefixo "-" indica ordem descendente
+func ParseSort(s string) ([]SortKey, error) {
+	var keys []SortKey
+	seen := map[string]struct{}{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		k := SortKey{Field: strings.TrimPrefix(part, "-"), Desc: strings.HasPrefix(part, "-")}
+		if _, ok := sortableFields[k.Field]; !ok {
+			return nil, NewValidationError("cannot sort by field: " + k.Field)
+		}
+		if _, dup := seen[k.Field]; dup {
+			return nil, NewValidationError("duplicated sort field: " + k.Field)
+		}
+		seen[k.Field] = struct{}{}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// Validate rejeita filtros inválidos, campos desconhecidos e paginação negativa
+func (q TaskQuery) Validate() error {
+	for _, f := range q.Filters {
+		if err := f.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, k := range q.Sort {
+		if _, ok := sortableFields[k.Field]; !ok {
+			return NewValidationError("cannot sort by field: " + k.Field)
+		}
+		if k.Field == "score" && q.Search == "" {
+			return NewValidationError("sorting by score requires a search (q)")
+		}
+	}
+	if q.Limit < 0 || q.Offset < 0 {
+		return NewValidationError("limit and offset must not be negative")
+	}
+	if q.After != nil && len(q.After) != len(q.SortKeys()) {
+		return NewValidationError("cursor does not match the requested sort")
+	}
+	return nil
+}
+
+// SortKeys retorna a ordenação efetiva, sempre terminando em id para desempate
+func (q TaskQuery) SortKeys() []SortKey {
+	keys := q.Sort
+	if len(keys) == 0 && q.Search != "" {
+		keys = SearchSort
+	} else if len(keys) == 0 {
+		keys = DefaultSort
+	}
+	for _, k := range keys {
+		if k.Field == "id" {
+			return keys
+		}
+	}
+	return append(append([]SortKey{}, keys...), SortKey{Field: "id"})
+}
+
+// Match avalia todos os filtros do query contra a tarefa; tarefas excluídas só casam com Deleted
+func (q TaskQuery) Match(t Task) bool {
+	if (t.DeletedAt != nil) != q.Deleted {
+		return false
+	}
+	for _, f := range q.Filters {
+		if !f.Match(t) {
+			return false
+		}
+	}
+	return true
+}
+
+// SortValue retorna o valor de ordenação do campo: string, int (rank da prioridade),
+// float64 (score), time.Time ou nil quando vazio
+func SortValue(t Task, field string) interface{} {
+	switch field {
+	case "id":
+		return t.ID
+	case "title":
+		return t.Title
+	case "status":
+		return t.Status
+	case "priority":
+		if rank, ok := PriorityRanks[t.Priority]; ok {
+			return rank
+		}
+		return nil
+	case "due_date":
+		if t.DueDate == nil || t.DueDate.IsZero() {
+			return nil
+		}
+		return t.DueDate.Time
+	case "created_at":
+		return t.CreatedAt
+	case "updated_at":
+		if t.UpdatedAt == nil {
+			return nil
+		}
+		return *t.UpdatedAt
+	case "deleted_at":
+		if t.DeletedAt == nil {
+			return nil
+		}
+		return *t.DeletedAt
+	case "score":
+		return t.Score
+	}
+	return nil
+}
+
+// compareValues compara dois valores não nulos do mesmo campo
+func compareValues(a, b interface{}) int {
+	switch av := a.(type) {
+	case string:
+		return strings.Compare(av, b.(string))
+	case int:
+		return av - b.(int)
+	case float64:
+		bv := b.(float64)
+		if av < bv {
+			return -1
+		}
+		if av > bv {
+			return 1
+		}
+	case time.Time:
+		bv := b.(time.Time)
+		if av.Before(bv) {
+			return -1
+		}
+		if av.After(bv) {
+			return 1
+		}
+	}
+	return 0
+}
+
+// compareKey aplica a direção da chave; nulos ficam no início ou no fim independente dela
+func (q TaskQuery) compareKey(a, b interface{}, k SortKey) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil || b == nil:
+		if (a == nil) == q.NullsFirst {
+			return -1
+		}
+		return 1
+	}
+	c := compareValues(a, b)
+	if k.Desc {
+		c = -c
+	}
+	return c
+}
+
+// Compare compara duas tarefas segundo a ordenação do query
+func (q TaskQuery) Compare(a, b Task) int {
+	for _, k := range q.SortKeys() {
+		if c := q.compareKey(SortValue(a, k.Field), SortValue(b, k.Field), k); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// CompareToCursor compara a tarefa com a posição After: positivo quando ela vem depois
+func (q TaskQuery) CompareToCursor(t Task) int {
+	for i, k := range q.SortKeys() {
+		if c := q.compareKey(SortValue(t, k.Field), q.After[i], k); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// SortTasks ordena as tarefas in-place segundo o query
+func SortTasks(tasks []Task, q TaskQuery) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return q.Compare(tasks[i], tasks[j]) < 0
+	})
+}
+
+// Paginate aplica After, Offset e Limit a uma lista já filtrada e ordenada
+func Paginate(sorted []Task, q TaskQuery) TaskPage {
+	total := len(sorted)
+	if q.After != nil {
+		start := sort.Search(len(sorted), func(i int) bool {
+			return q.CompareToCursor(sorted[i]) > 0
+		})
+		sorted = sorted[start:]
+	}
+	if q.Offset >= len(sorted) {
+		return NewTaskPage(nil, total, q)
+	}
+	sorted = sorted[q.Offset:]
+	if q.Limit > 0 && q.Limit < len(sorted) {
+		sorted = sorted[:q.Limit+1]
+	}
+	return NewTaskPage(sorted, total, q)
+}
+
+// NewTaskPage monta a página a partir de até Limit+1 tarefas: a tarefa excedente
+// só indica que existe próxima página e gera o NextCursor
+func NewTaskPage(tasks []Task, total int, q TaskQuery) TaskPage {
+	page := TaskPage{Tasks: tasks, Total: total}
+	if page.Tasks == nil {
+		page.Tasks = []Task{}
+	}
+	if q.Limit > 0 && len(page.Tasks) > q.Limit {
+		page.Tasks = page.Tasks[:q.Limit]
+		page.NextCursor = EncodeCursor(page.Tasks[q.Limit-1], q)
+	}
+	return page
+}
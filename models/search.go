@@ -0,0 +1,66 @@
+package models
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// SearchWeights pondera a relevância de cada campo pesquisável. Os mesmos pesos
+// configuram o índice de texto do MongoDB
+var SearchWeights = map[string]int{"title": 10, "description": 1}
+
+// NormalizeText remove acentos e converte para minúsculas ("Revisão" vira "revisao")
+func NormalizeText(s string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(s) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+func words(s string) []string {
+	return strings.FieldsFunc(NormalizeText(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// SearchTerms quebra o texto em palavras normalizadas e sem repetição
+func SearchTerms(s string) []string {
+	seen := map[string]struct{}{}
+	var terms []string
+	for _, w := range words(s) {
+		if _, ok := seen[w]; ok {
+			continue
+		}
+		seen[w] = struct{}{}
+		terms = append(terms, w)
+	}
+	return terms
+}
+
+// SearchTermWeights retorna, para cada palavra da tarefa, a soma dos pesos dos campos
+// em que ela aparece multiplicados pelo número de ocorrências
+func SearchTermWeights(t Task) map[string]float64 {
+	weights := map[string]float64{}
+	for field, text := range map[string]string{"title": t.Title, "description": t.Description} {
+		for _, w := range words(text) {
+			weights[w] += float64(SearchWeights[field])
+		}
+	}
+	return weights
+}
+
+// SearchScore calcula a relevância da tarefa para os termos; zero quando nenhum termo aparece
+func SearchScore(t Task, terms []string) float64 {
+	weights := SearchTermWeights(t)
+	score := 0.0
+	for _, term := range terms {
+		score += weights[term]
+	}
+	return score
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"net/http"
 	"reflect"
+	"strconv"
 	"strings"
 )
 
@@ -80,6 +81,15 @@ var updateBusinessRules = []BusinessRule{
 	PreventCompletedTaskEdits,
 }
 
+// RelationRule é a regra de alteração que depende de outras tarefas, recebidas já
+// contadas em rel
+type RelationRule func(task Task, rel TaskRelations, patch map[string]interface{}) error
+
+var relationBusinessRules = []RelationRule{
+	PreventCompletingParentWithOpenSubtasks,
+	PreventStartingBlockedTask,
+}
+
 func PreventCompletedTaskEdits(task Task, patch map[string]interface{}) error {
 	if IsCompletedTask(task.Status) {
 		return NewBusinessRuleError("completed tasks cannot be edited")
@@ -87,10 +97,66 @@ func PreventCompletedTaskEdits(task Task, patch map[string]interface{}) error {
 	return nil
 }
 
+// PreventCompletingParentWithOpenSubtasks impede concluir uma tarefa enquanto alguma
+// subtarefa direta estiver aberta (rel.OpenSubtasks)
+func PreventCompletingParentWithOpenSubtasks(task Task, rel TaskRelations, patch map[string]interface{}) error {
+	if status, _ := patch["status"].(string); IsCompletedTask(status) && rel.OpenSubtasks > 0 {
+		return NewBusinessRuleError("task has open subtasks and cannot be completed")
+	}
+	return nil
+}
+
+// PreventStartingBlockedTask impede mover a tarefa para in_progress ou completed enquanto
+// alguma tarefa de BlockedBy estiver aberta (rel.OpenBlockers)
+func PreventStartingBlockedTask(task Task, rel TaskRelations, patch map[string]interface{}) error {
+	status, _ := patch["status"].(string)
+	if status != StatusInProgress && status != StatusCompleted || status == task.Status {
+		return nil
+	}
+	if rel.OpenBlockers > 0 {
+		return NewBusinessRuleError(fmt.Sprintf("task is blocked by %d open task(s)", rel.OpenBlockers))
+	}
+	return nil
+}
+
 func AddUpdateRule(rule BusinessRule) {
 	updateBusinessRules = append(updateBusinessRules, rule)
 }
 
+func AddRelationRule(rule RelationRule) {
+	relationBusinessRules = append(relationBusinessRules, rule)
+}
+
+// DeleteRule decide se a tarefa pode ser excluída; cascade indica que as subtarefas
+// serão excluídas junto (DELETE /tasks/{id}?cascade=true)
+type DeleteRule func(task Task, rel TaskRelations, cascade bool) error
+
+var deleteBusinessRules = []DeleteRule{
+	PreventDeletingParentWithSubtasks,
+}
+
+// PreventDeletingParentWithSubtasks rejeita excluir uma tarefa com subtarefas ativas
+// (rel.ActiveSubtasks), a menos que a exclusão seja em cascata
+func PreventDeletingParentWithSubtasks(task Task, rel TaskRelations, cascade bool) error {
+	if rel.ActiveSubtasks > 0 && !cascade {
+		return NewBusinessRuleError("task has subtasks, delete them first or use cascade=true")
+	}
+	return nil
+}
+
+func AddDeleteRule(rule DeleteRule) {
+	deleteBusinessRules = append(deleteBusinessRules, rule)
+}
+
+func (s *TaskService) ValidateDelete(task Task, rel TaskRelations, cascade bool) error {
+	for _, rule := range deleteBusinessRules {
+		if err := rule(task, rel, cascade); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *TaskService) ValidateCreate(t Task) error {
 	// Required fields
 	if t.Title == "" {
@@ -99,6 +165,9 @@ func (s *TaskService) ValidateCreate(t Task) error {
 	if t.Status == "" {
 		return NewValidationError("status is required")
 	}
+	if len(t.BlockedBy) > 0 {
+		return NewValidationError("blocked_by cannot be set on create, use POST /tasks/{id}/dependencies")
+	}
 
 	// fieldValidators na validação
 	patch := make(map[string]interface{})
@@ -119,6 +188,21 @@ func (s *TaskService) ValidateCreate(t Task) error {
 			return err
 		}
 	}
+	if t.ParentID != "" {
+		if err := fieldValidators["parent_id"](t.ParentID, patch, "parent_id"); err != nil {
+			return err
+		}
+	}
+	if t.Tags != nil {
+		if err := fieldValidators["tags"](t.Tags, patch, "tags"); err != nil {
+			return err
+		}
+	}
+	if t.ProjectID != "" {
+		if err := fieldValidators["project_id"](t.ProjectID, patch, "project_id"); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
@@ -133,9 +217,18 @@ var fieldValidators = map[string]FieldValidator{
 	"due_date":    ValidateDueDateField,
 	"title":       ValidateTitleField,
 	"description": ValidateStringField,
+	"parent_id":   ValidateParentField,
+	"tags":        ValidateTagsField,
+	"project_id":  ValidateProjectField,
 }
 
+// nullableFields aceitam null no patch, que limpa o campo; os demais campos editáveis são obrigatórios
+var nullableFields = map[string]bool{"description": true, "priority": true, "due_date": true, "parent_id": true, "tags": true, "project_id": true}
+
 func ValidateStatusField(value interface{}, patch map[string]interface{}, fieldName string) error {
+	if value == nil {
+		return NewValidationError(fieldName + " is required")
+	}
 	s, ok := value.(string)
 	if !ok || !IsValidStatus(s) {
 		return NewValidationError("invalid status, allowed: pending, in_progress, completed, cancelled")
@@ -145,6 +238,9 @@ func ValidateStatusField(value interface{}, patch map[string]interface{}, fieldN
 }
 
 func ValidatePriorityField(value interface{}, patch map[string]interface{}, fieldName string) error {
+	if value == nil {
+		return nil
+	}
 	s, ok := value.(string)
 	if !ok || !IsValidPriority(s) {
 		return NewValidationError("invalid priority, allowed: low, medium, high")
@@ -155,6 +251,8 @@ func ValidatePriorityField(value interface{}, patch map[string]interface{}, fiel
 
 func ValidateDueDateField(value interface{}, patch map[string]interface{}, fieldName string) error {
 	switch vv := value.(type) {
+	case nil:
+		return nil
 	case string:
 		parsed, err := ParseDateOnly(vv)
 		if err != nil {
@@ -176,12 +274,18 @@ func ValidateDueDateField(value interface{}, patch map[string]interface{}, field
 }
 
 func ValidateStringField(value interface{}, patch map[string]interface{}, fieldName string) error {
+	if value == nil && nullableFields[fieldName] {
+		return nil
+	}
 	if _, ok := value.(string); !ok {
 		return NewValidationError(fieldName + " must be a string")
 	}
 	return nil
 }
 func ValidateTitleField(value interface{}, patch map[string]interface{}, fieldName string) error {
+	if value == nil {
+		return NewValidationError(fieldName + " is required")
+	}
 	s, ok := value.(string)
 	if !ok {
 		return NewValidationError(fieldName + " must be a string")
@@ -193,13 +297,88 @@ func ValidateTitleField(value interface{}, patch map[string]interface{}, fieldNa
 	return nil
 }
 
+// ValidateParentField só verifica o formato; a existência do pai e a ausência de ciclos
+// dependem do Store e são checadas pelo handler
+func ValidateParentField(value interface{}, patch map[string]interface{}, fieldName string) error {
+	if value == nil {
+		return nil
+	}
+	s, ok := value.(string)
+	if !ok || strings.TrimSpace(s) == "" {
+		return NewValidationError(fieldName + " must be a task id or null")
+	}
+	return nil
+}
+
+// ValidateProjectField só verifica o formato; o handler checa se o projeto existe e
+// não está arquivado
+func ValidateProjectField(value interface{}, patch map[string]interface{}, fieldName string) error {
+	if value == nil {
+		return nil
+	}
+	s, ok := value.(string)
+	if !ok || strings.TrimSpace(s) == "" {
+		return NewValidationError(fieldName + " must be a project id or null")
+	}
+	return nil
+}
+
+// ValidateTagsField aceita uma lista de strings ou null (limpa as tags). Cada tag é
+// normalizada e validada por ValidateTag; o patch recebe a lista normalizada
+func ValidateTagsField(value interface{}, patch map[string]interface{}, fieldName string) error {
+	var tags []string
+	switch vv := value.(type) {
+	case nil:
+		return nil
+	case []string:
+		tags = vv
+	case []interface{}:
+		tags = make([]string, len(vv))
+		for i, item := range vv {
+			s, ok := item.(string)
+			if !ok {
+				return NewValidationError(fieldName + " must be a list of strings")
+			}
+			tags[i] = s
+		}
+	default:
+		return NewValidationError(fieldName + " must be a list of strings")
+	}
+	tags = NormalizeTags(tags)
+	if len(tags) > MaxTagsPerTask {
+		return NewValidationError(fieldName + " must have at most " + strconv.Itoa(MaxTagsPerTask) + " items")
+	}
+	for _, tag := range tags {
+		if err := ValidateTag(tag); err != nil {
+			return err
+		}
+	}
+	if tags == nil {
+		patch[fieldName] = nil
+	} else {
+		patch[fieldName] = tags
+	}
+	return nil
+}
+
 func (s *TaskService) ValidateUpdate(task Task, patch map[string]interface{}) error {
+	return s.ValidateUpdateWith(task, TaskRelations{}, patch)
+}
+
+// ValidateUpdateWith é ValidateUpdate com as contagens de rel para as regras que dependem
+// de outras tarefas (RelationRule)
+func (s *TaskService) ValidateUpdateWith(task Task, rel TaskRelations, patch map[string]interface{}) error {
 	// Apply business rules
 	for _, rule := range updateBusinessRules {
 		if err := rule(task, patch); err != nil {
 			return err
 		}
 	}
+	for _, rule := range relationBusinessRules {
+		if err := rule(task, rel, patch); err != nil {
+			return err
+		}
+	}
 
 	if len(patch) == 0 {
 		return NewValidationError("no fields to update")
@@ -210,6 +389,13 @@ func (s *TaskService) ValidateUpdate(task Task, patch map[string]interface{}) er
 			return NewValidationError("unknown field: " + fieldName)
 		}
 
+		// Reenviar o prazo atual (caso do PUT com o corpo do GET) não passa pela checagem de
+		// data futura: uma tarefa atrasada continua editável
+		if fieldName == "due_date" && sameDueDate(task, value) {
+			patch[fieldName] = *task.DueDate
+			continue
+		}
+
 		// Use field validator if available
 		if validator, ok := fieldValidators[fieldName]; ok {
 			if err := validator(value, patch, fieldName); err != nil {
@@ -220,6 +406,25 @@ func (s *TaskService) ValidateUpdate(task Task, patch map[string]interface{}) er
 	return nil
 }
 
+// sameDueDate indica que value é o prazo já armazenado na tarefa
+func sameDueDate(task Task, value interface{}) bool {
+	if task.DueDate == nil {
+		return false
+	}
+	switch vv := value.(type) {
+	case string:
+		parsed, err := ParseDateOnly(vv)
+		return err == nil && parsed.String() == task.DueDate.String()
+	case Date:
+		return vv.String() == task.DueDate.String()
+	}
+	return false
+}
+
+// readOnlyFields são mantidos pelo Store (ou, como blocked_by, por rotas próprias) e não
+// podem ser alterados pelo cliente no PUT e no PATCH
+var readOnlyFields = map[string]bool{"id": true, "created_at": true, "updated_at": true, "deleted_at": true, "version": true, "score": true, "blocked_by": true}
+
 func getUpdateableFields() map[string]struct{} {
 	fields := make(map[string]struct{})
 	t := reflect.TypeOf(Task{})
@@ -231,8 +436,8 @@ func getUpdateableFields() map[string]struct{} {
 		}
 		// Extract field name from json tag (before comma)
 		fieldName := strings.Split(jsonTag, ",")[0]
-		// Skip system/read-only fields
-		if fieldName != "id" && fieldName != "created_at" && fieldName != "updated_at" {
+		// Skip system/read-only fields and the ones never exposed
+		if fieldName != "-" && !readOnlyFields[fieldName] {
 			fields[fieldName] = struct{}{}
 		}
 	}
@@ -249,8 +454,13 @@ type APIError struct {
 
 func (e *APIError) Error() string { return e.Message }
 
-func NewValidationError(msg string) error   { return &APIError{Code: 400, Message: msg} }
-func NewBusinessRuleError(msg string) error { return &APIError{Code: 409, Message: msg} }
+func NewValidationError(msg string) error           { return &APIError{Code: 400, Message: msg} }
+func NewBusinessRuleError(msg string) error         { return &APIError{Code: 409, Message: msg} }
+func NewNotFoundError(msg string) error             { return &APIError{Code: 404, Message: msg} }
+func NewInternalError(msg string) error             { return &APIError{Code: 500, Message: msg} }
+func NewPreconditionFailedError(msg string) error   { return &APIError{Code: 412, Message: msg} }
+func NewUnsupportedMediaTypeError(msg string) error { return &APIError{Code: 415, Message: msg} }
+func NewUnprocessableEntityError(msg string) error  { return &APIError{Code: 422, Message: msg} }
 
 func WriteError(w http.ResponseWriter, err error, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
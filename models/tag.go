@@ -0,0 +1,72 @@
+package models
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	MaxTagsPerTask = 20
+	MaxTagLength   = 50
+)
+
+// TagCount é uma tag com o número de tarefas ativas que a usam
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// TagListResponse é a resposta de GET /tags
+type TagListResponse struct {
+	Tags       []TagCount `json:"tags"`
+	TotalItems int        `json:"total_items"`
+}
+
+// NormalizeTag deixa a tag em minúsculas e sem espaços nas pontas
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// NormalizeTags normaliza cada tag e remove as repetidas, mantendo a ordem da primeira
+// ocorrência. Uma lista que fica vazia vira nil
+func NormalizeTags(tags []string) []string {
+	var out []string
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = NormalizeTag(tag)
+		if !seen[tag] {
+			seen[tag] = true
+			out = append(out, tag)
+		}
+	}
+	return out
+}
+
+// ValidateTag verifica uma tag já normalizada: letras, dígitos e os separadores - _ . :
+// Vírgulas e espaços ficam de fora porque a tag também é usada em filtros da query string
+func ValidateTag(tag string) error {
+	if tag == "" {
+		return NewValidationError("tags must not be empty")
+	}
+	if len(tag) > MaxTagLength {
+		return NewValidationError("tag " + strconv.Quote(tag) + " is longer than " + strconv.Itoa(MaxTagLength) + " characters")
+	}
+	for _, r := range tag {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("-_.:", r) {
+			return NewValidationError("invalid tag " + strconv.Quote(tag) + ", allowed: letters, digits, - _ . :")
+		}
+	}
+	return nil
+}
+
+// SortTagCounts ordena as tags mais usadas primeiro; empates em ordem alfabética
+func SortTagCounts(counts []TagCount) {
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Tag < counts[j].Tag
+	})
+}
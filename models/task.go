@@ -5,17 +5,64 @@ import (
 )
 
 type Task struct {
-	ID          string     `json:"id" bson:"id,omitempty"`
-	Title       string     `json:"title" bson:"title"`
-	Description string     `json:"description,omitempty" bson:"description,omitempty"`
-	Status      string     `json:"status" bson:"status"`
-	Priority    string     `json:"priority" bson:"priority,omitempty"`
-	DueDate     *Date      `json:"due_date" bson:"due_date,omitempty"`
-	CreatedAt   time.Time  `json:"created_at" bson:"created_at"`
-	UpdatedAt   *time.Time `json:"updated_at" bson:"updated_at,omitempty"`
+	ID          string `json:"id" bson:"id,omitempty"`
+	Title       string `json:"title" bson:"title"`
+	Description string `json:"description,omitempty" bson:"description,omitempty"`
+	Status      string `json:"status" bson:"status"`
+	Priority    string `json:"priority" bson:"priority,omitempty"`
+	DueDate     *Date  `json:"due_date" bson:"due_date,omitempty"`
+	// ParentID liga a subtarefa à tarefa pai; vazio nas tarefas raiz
+	ParentID string `json:"parent_id,omitempty" bson:"parent_id,omitempty"`
+	// BlockedBy lista as tarefas que precisam ser encerradas antes desta começar; é
+	// alterado só pelas rotas de /tasks/{id}/dependencies
+	BlockedBy []string `json:"blocked_by,omitempty" bson:"blocked_by,omitempty"`
+	// Tags são normalizadas pelo validador (minúsculas, sem espaços nas pontas, sem repetição)
+	Tags []string `json:"tags,omitempty" bson:"tags,omitempty"`
+	// ProjectID liga a tarefa a um projeto (Project); vazio nas tarefas sem projeto
+	ProjectID string     `json:"project_id,omitempty" bson:"project_id,omitempty"`
+	CreatedAt time.Time  `json:"created_at" bson:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at" bson:"updated_at,omitempty"`
+	// DeletedAt marca a tarefa como excluída: ela sai de GET /tasks e fica na lixeira até ser restaurada ou purgada
+	DeletedAt *time.Time `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+	// Version começa em 1 e é incrementada a cada alteração; é exposta como ETag
+	Version int64 `json:"version" bson:"version"`
+	// Score é a relevância calculada em buscas textuais; nunca é persistido
+	Score float64 `json:"score,omitempty" bson:"score,omitempty"`
+}
+
+// TaskRelations são as contagens de outras tarefas que as regras de hierarquia e de
+// dependências precisam. Não fazem parte da tarefa: o handler as calcula antes de validar
+type TaskRelations struct {
+	// ActiveSubtasks e OpenSubtasks contam as subtarefas diretas ativas e as ainda abertas
+	ActiveSubtasks int
+	OpenSubtasks   int
+	// OpenBlockers conta as tarefas de BlockedBy ainda abertas
+	OpenBlockers int
 }
 
 type TaskListResponse struct {
 	Tasks      []Task `json:"tasks"`
 	TotalItems int    `json:"total_items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// TaskNode é a tarefa com as subtarefas aninhadas, usada em GET /tasks?tree=true
+type TaskNode struct {
+	Task
+	Subtasks []TaskNode `json:"subtasks"`
+}
+
+type TaskTreeResponse struct {
+	Tasks      []TaskNode `json:"tasks"`
+	TotalItems int        `json:"total_items"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+}
+
+// HealthResponse é a resposta de GET /health. Fallback indica que o backend configurado
+// não estava disponível e a API está rodando em memória
+type HealthResponse struct {
+	Status   string `json:"status"`
+	Backend  string `json:"backend"`
+	Fallback bool   `json:"fallback,omitempty"`
+	Error    string `json:"error,omitempty"`
 }
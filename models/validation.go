@@ -4,13 +4,20 @@ import (
 	"time"
 )
 
-const StatusCompleted = "completed"
+const (
+	StatusInProgress = "in_progress"
+	StatusCompleted  = "completed"
+	StatusCancelled  = "cancelled"
+)
 
 var (
 	ValidStatuses   = map[string]struct{}{"pending": {}, "in_progress": {}, "completed": {}, "cancelled": {}}
 	ValidPriorities = map[string]struct{}{"low": {}, "medium": {}, "high": {}}
 )
 
+// PriorityRanks define a ordem semântica das prioridades (high > medium > low)
+var PriorityRanks = map[string]int{"low": 1, "medium": 2, "high": 3}
+
 func IsValidStatus(status string) bool {
 	_, ok := ValidStatuses[status]
 	return ok
@@ -35,6 +42,11 @@ func IsCompletedTask(status string) bool {
 	return status == StatusCompleted
 }
 
+// IsOpenTask indica que a tarefa ainda não foi concluída nem cancelada
+func IsOpenTask(status string) bool {
+	return status != StatusCompleted && status != StatusCancelled
+}
+
 func IsValidTitle(title string) bool {
 	return len(title) >= 3 && len(title) <= 100
 }
@@ -2,6 +2,7 @@ package router
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"time"
 
@@ -12,47 +13,107 @@ import (
 	"example.com/tasksapi/store"
 )
 
+// Backends aceitos em STORE_BACKEND
+const (
+	BackendMemory = "memory"
+	BackendMongo  = "mongo"
+	BackendFile   = "file"
+	BackendSQL    = "sql"
+	BackendEvents = "events"
+)
+
+// openStore abre um backend e o log de auditoria que o acompanha
+type openStore func(logger models.Logger) (store.Store, store.AuditLog, error)
+
+var backends = map[string]openStore{
+	BackendMemory: func(models.Logger) (store.Store, store.AuditLog, error) {
+		return store.New(), store.NewMemoryAuditLog(), nil
+	},
+	BackendMongo:  openMongo,
+	BackendFile:   withHistory(openFileStore),
+	BackendSQL:    withHistory(openSQL),
+	BackendEvents: withHistory(openEventStore),
+}
+
 func New() *mux.Router {
 	logger := models.NewDefaultLogger()
 	return NewWithLogger(logger)
 }
 
+// NewWithLogger monta o router e encerra o processo (logger.Fatal) se o backend
+// configurado não puder ser aberto
 func NewWithLogger(logger models.Logger) *mux.Router {
-	var s store.Store
+	return NewWithContext(context.Background(), logger)
+}
 
-	mongoURI := os.Getenv("MONGO_URI")
-	if mongoURI == "" {
-		mongoURI = "mongodb://localhost:27017"
+// NewWithContext é NewWithLogger com as tarefas de fundo (a purga da lixeira) presas a
+// ctx: cancelá-lo no desligamento do servidor as encerra
+func NewWithContext(ctx context.Context, logger models.Logger) *mux.Router {
+	r, err := OpenContext(ctx, logger)
+	if err != nil {
+		logger.Fatal("%v", err)
 	}
+	return r
+}
 
-	dbName := os.Getenv("MONGO_DB")
-	if dbName == "" {
-		dbName = "taskdb"
+// Open monta o router com o backend de STORE_BACKEND. Se ele não estiver disponível,
+// retorna erro; só cai para o armazenamento em memória com STORE_FALLBACK=memory
+func Open(logger models.Logger) (*mux.Router, error) {
+	return OpenContext(context.Background(), logger)
+}
+
+// OpenContext é Open com as tarefas de fundo presas a ctx
+func OpenContext(ctx context.Context, logger models.Logger) (*mux.Router, error) {
+	backend, open, err := selectBackend()
+	if err != nil {
+		return nil, err
 	}
 
-	collectionName := os.Getenv("MONGO_COLLECTION")
-	if collectionName == "" {
-		collectionName = "tasks"
+	fallback := false
+	s, history, err := open(logger)
+	if err != nil {
+		if os.Getenv("STORE_FALLBACK") != BackendMemory {
+			return nil, fmt.Errorf("failed to open %s store: %w", backend, err)
+		}
+		logger.Warn("failed to open %s store: %v", backend, err)
+		logger.Warn("falling back to in-memory store (STORE_FALLBACK=memory): tasks will be lost on restart")
+		s, history, _ = backends[BackendMemory](logger)
+		backend, fallback = BackendMemory, true
+	} else if backend == BackendMemory {
+		logger.Warn("using in-memory store: tasks will be lost on restart")
 	}
+	health := handlers.NewHealthHandler(backend, fallback, s)
 
-	// Tenta conexao para o MongoDB
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	m, err := store.NewMongo(ctx, mongoURI, dbName, collectionName)
-	cancel()
+	// Respostas de requisições com Idempotency-Key ficam guardadas por IDEMPOTENCY_TTL
+	// (padrão 24h; "0" desliga o suporte ao header)
+	idempotencyTTL := envDuration(logger, "IDEMPOTENCY_TTL", 24*time.Hour)
+	var keys store.IdempotencyStore
+	if idempotencyTTL > 0 {
+		if keys, err = openIdempotency(s); err != nil {
+			return nil, err
+		}
+	}
 
+	// Projetos (/projects) ficam fora do Store de tarefas, que só guarda o project_id
+	projects, err := openProjects(s)
 	if err != nil {
-		logger.Warn("failed to connect to MongoDB (%s): %v", mongoURI, err)
-		logger.Info("falling back to in-memory store")
-		s = store.New()
-	} else {
-		s = m
-		logger.Info("successfully connected to MongoDB: %s", mongoURI)
+		return nil, err
 	}
 
+	// Registra cada escrita no histórico da tarefa (GET /tasks/{id}/history)
+	s = store.NewAuditStore(s, history, logger)
+
 	// Encapsula o store com logging para interceptar todas as operações
 	s = store.NewLoggingStore(s, logger)
 
-	api := handlers.NewAPI(s, logger)
+	// Tarefas excluídas ficam na lixeira por TRASH_RETENTION (padrão 30 dias; "0" desliga a purga)
+	retention := envDuration(logger, "TRASH_RETENTION", 30*24*time.Hour)
+	if retention > 0 {
+		interval := envPositiveDuration(logger, "TRASH_PURGE_INTERVAL", time.Hour)
+		store.StartPurger(ctx, s, retention, interval, logger)
+	}
+
+	api := handlers.NewAPIWithProjects(s, projects, logger)
 
 	// Cria o middleware de logging HTTP
 	loggingMiddleware := handlers.NewLoggingMiddleware(logger)
@@ -61,11 +122,300 @@ func NewWithLogger(logger models.Logger) *mux.Router {
 
 	// Aplica o middleware globalmente para interceptar todas as requisições
 	r.Use(loggingMiddleware.Middleware)
+	r.Use(handlers.ActorMiddleware)
+	if keys != nil {
+		r.Use(handlers.NewIdempotency(keys, idempotencyTTL, logger).Middleware)
+	}
 
+	r.HandleFunc("/health", health.Health).Methods("GET")
 	r.HandleFunc("/tasks", api.CreateTask).Methods("POST")
 	r.HandleFunc("/tasks", api.ListTasks).Methods("GET")
+	r.HandleFunc("/tasks/trash", api.ListTrash).Methods("GET")
+	r.HandleFunc("/tasks/bulk", api.BulkCreateTasks).Methods("POST")
+	r.HandleFunc("/tasks/bulk", api.BulkUpdateTasks).Methods("PATCH")
+	r.HandleFunc("/tasks/bulk", api.BulkDeleteTasks).Methods("DELETE")
+	r.HandleFunc("/tasks/actionable", api.ListActionable).Methods("GET")
 	r.HandleFunc("/tasks/{id}", api.GetTask).Methods("GET")
 	r.HandleFunc("/tasks/{id}", api.UpdateTask).Methods("PUT")
+	r.HandleFunc("/tasks/{id}", api.PatchTask).Methods("PATCH")
 	r.HandleFunc("/tasks/{id}", api.DeleteTask).Methods("DELETE")
-	return r
+	r.HandleFunc("/tasks/{id}/restore", api.RestoreTask).Methods("POST")
+	r.HandleFunc("/tasks/{id}/history", api.TaskHistory).Methods("GET")
+	r.HandleFunc("/tasks/{id}/subtasks", api.ListSubtasks).Methods("GET")
+	r.HandleFunc("/tasks/{id}/dependencies", api.TaskDependencies).Methods("GET")
+	r.HandleFunc("/tasks/{id}/dependencies", api.AddDependency).Methods("POST")
+	r.HandleFunc("/tasks/{id}/dependencies/{blocker_id}", api.RemoveDependency).Methods("DELETE")
+	r.HandleFunc("/tags", api.ListTags).Methods("GET")
+	r.HandleFunc("/projects", api.CreateProject).Methods("POST")
+	r.HandleFunc("/projects", api.ListProjects).Methods("GET")
+	r.HandleFunc("/projects/{id}", api.GetProject).Methods("GET")
+	r.HandleFunc("/projects/{id}", api.PatchProject).Methods("PATCH")
+	r.HandleFunc("/projects/{id}", api.DeleteProject).Methods("DELETE")
+	r.HandleFunc("/projects/{id}/archive", api.ArchiveProject).Methods("POST")
+	r.HandleFunc("/projects/{id}/unarchive", api.UnarchiveProject).Methods("POST")
+	r.HandleFunc("/projects/{id}/tasks", api.ListProjectTasks).Methods("GET")
+	r.HandleFunc("/projects/{id}/stats", api.ProjectStats).Methods("GET")
+	return r, nil
+}
+
+// Migrate abre o backend de STORE_BACKEND e aplica o esquema dele (tabelas SQL, índices do
+// MongoDB). Com dropObsolete, remove também os índices do MongoDB que saíram da lista
+// declarada. Não há fallback: o comando falha se o backend não abrir
+func Migrate(logger models.Logger, dropObsolete bool) error {
+	backend, open, err := selectBackend()
+	if err != nil {
+		return err
+	}
+	s, _, err := open(logger)
+	if err != nil {
+		return fmt.Errorf("failed to open %s store: %w", backend, err)
+	}
+	defer closeStore(s)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if m, ok := s.(store.Migrator); ok {
+		if err := m.Migrate(ctx); err != nil {
+			return fmt.Errorf("failed to migrate %s store: %w", backend, err)
+		}
+		logger.Info("%s store schema is up to date", backend)
+	} else {
+		logger.Info("%s store has no schema to migrate", backend)
+	}
+
+	if !dropObsolete {
+		return nil
+	}
+	m, ok := s.(*store.MongoStore)
+	if !ok {
+		logger.Warn("-drop-obsolete only applies to the mongo backend, ignoring")
+		return nil
+	}
+	dropped, err := m.DropObsoleteIndexes(ctx)
+	for _, name := range dropped {
+		logger.Info("dropped obsolete index %s", name)
+	}
+	if err != nil {
+		return err
+	}
+	if len(dropped) == 0 {
+		logger.Info("no obsolete indexes found")
+	}
+	return nil
+}
+
+// selectBackend resolve STORE_BACKEND no opener correspondente
+func selectBackend() (string, openStore, error) {
+	backend, err := storeBackend()
+	if err != nil {
+		return "", nil, err
+	}
+	open, ok := backends[backend]
+	if !ok {
+		return "", nil, fmt.Errorf("invalid STORE_BACKEND=%q, allowed: memory, mongo, file, sql, events", backend)
+	}
+	return backend, open, nil
+}
+
+// closeStore libera a conexão ou os arquivos do Store, quando ele tiver algum
+func closeStore(s store.Store) {
+	switch c := s.(type) {
+	case interface{ Close() error }:
+		_ = c.Close()
+	case interface{ Close(context.Context) error }:
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = c.Close(ctx)
+	}
+}
+
+// storeBackend lê STORE_BACKEND. Sem ele, usa o MongoDB quando MONGO_URI está definida;
+// sem nenhum dos dois é erro, para que um deploy mal configurado não suba em memória e
+// perca as tarefas no reinício: a memória só é usada com STORE_BACKEND=memory
+func storeBackend() (string, error) {
+	if backend := os.Getenv("STORE_BACKEND"); backend != "" {
+		return backend, nil
+	}
+	if os.Getenv("MONGO_URI") != "" {
+		return BackendMongo, nil
+	}
+	return "", fmt.Errorf("no store configured: set STORE_BACKEND (memory, mongo, file, sql, events) or MONGO_URI; use STORE_BACKEND=memory to keep tasks only in memory")
+}
+
+// withHistory exige que o backend durável grave o histórico na mesma escrita da tarefa
+// (store.HistoryWriter: mesma transação no SQL, mesmo registro no arquivo e no log de
+// eventos). Ele não precisa de um AuditLog separado: o AuditStore lê do próprio backend
+func withHistory(open func(models.Logger) (store.Store, error)) openStore {
+	return func(logger models.Logger) (store.Store, store.AuditLog, error) {
+		s, err := open(logger)
+		if err != nil {
+			return nil, nil, err
+		}
+		if w, ok := s.(store.HistoryWriter); !ok || !w.WritesHistory() {
+			closeStore(s)
+			return nil, nil, fmt.Errorf("%T does not persist task history", s)
+		}
+		return s, nil, nil
+	}
+}
+
+// openMongo conecta no MongoDB em MONGO_URI; o histórico fica numa coleção ao lado da de tarefas
+func openMongo(logger models.Logger) (store.Store, store.AuditLog, error) {
+	mongoURI := os.Getenv("MONGO_URI")
+	if mongoURI == "" {
+		mongoURI = "mongodb://localhost:27017"
+	}
+
+	dbName := os.Getenv("MONGO_DB")
+	if dbName == "" {
+		dbName = "taskdb"
+	}
+
+	collectionName := mongoCollection()
+
+	// Tenta conexao para o MongoDB
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	m, err := store.NewMongo(ctx, mongoURI, dbName, collectionName)
+	cancel()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to MongoDB (%s): %w", mongoURI, err)
+	}
+	logger.Info("successfully connected to MongoDB: %s", mongoURI)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	history, err := m.(*store.MongoStore).AuditLog(ctx, collectionName+"_history")
+	cancel()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to prepare task history collection: %w", err)
+	}
+	return m, history, nil
+}
+
+// mongoCollection é a coleção de tarefas em MONGO_COLLECTION (padrão tasks); as coleções
+// auxiliares usam o nome dela como prefixo
+func mongoCollection() string {
+	if name := os.Getenv("MONGO_COLLECTION"); name != "" {
+		return name
+	}
+	return "tasks"
+}
+
+// openIdempotency guarda as chaves de Idempotency-Key no MongoDB quando ele é o backend,
+// para que valham entre instâncias da API; nos demais backends, em memória
+func openIdempotency(s store.Store) (store.IdempotencyStore, error) {
+	m, ok := s.(*store.MongoStore)
+	if !ok {
+		return store.NewMemoryIdempotencyStore(), nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	keys, err := m.IdempotencyStore(ctx, mongoCollection()+"_idempotency")
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare idempotency key collection: %w", err)
+	}
+	return keys, nil
+}
+
+// openProjects guarda os projetos no mesmo backend das tarefas: numa coleção do MongoDB,
+// na tabela projects do SQL ou num log ao lado das tarefas no arquivo e no log de eventos.
+// Só o backend em memória usa projetos em memória
+func openProjects(s store.Store) (store.ProjectStore, error) {
+	switch ps := s.(type) {
+	case *store.MongoStore:
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		projects, err := ps.ProjectStore(ctx, mongoCollection()+"_projects")
+		if err != nil {
+			return nil, fmt.Errorf("failed to prepare project collection: %w", err)
+		}
+		return projects, nil
+	case interface {
+		ProjectStore() (store.ProjectStore, error)
+	}:
+		projects, err := ps.ProjectStore()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open projects: %w", err)
+		}
+		return projects, nil
+	case *store.InMemoryStore:
+		return store.NewMemoryProjectStore(), nil
+	}
+	return nil, fmt.Errorf("%T does not persist projects", s)
+}
+
+// openSQL conecta com SQL_DRIVER (postgres, padrão, ou sqlite) em SQL_DSN e aplica as migrações
+func openSQL(logger models.Logger) (store.Store, error) {
+	driver := os.Getenv("SQL_DRIVER")
+	if driver == "" {
+		driver = "postgres"
+	}
+	dsn := os.Getenv("SQL_DSN")
+	if dsn == "" && driver == "sqlite" {
+		dsn = "data/tasks.db"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	s, err := store.NewSQL(ctx, driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s database: %w", driver, err)
+	}
+	logger.Info("using %s store", driver)
+	return s, nil
+}
+
+// openFileStore abre o log em FILE_STORE_PATH (padrão data/tasks.jsonl) com o fsync de
+// FILE_STORE_SYNC: always (padrão), interval (a cada FILE_STORE_SYNC_INTERVAL) ou never
+func openFileStore(logger models.Logger) (store.Store, error) {
+	path := os.Getenv("FILE_STORE_PATH")
+	if path == "" {
+		path = "data/tasks.jsonl"
+	}
+	fs, err := store.NewFileStore(store.FileStoreOptions{
+		Path:         path,
+		Sync:         store.FileSyncMode(os.Getenv("FILE_STORE_SYNC")),
+		SyncInterval: envPositiveDuration(logger, "FILE_STORE_SYNC_INTERVAL", time.Second),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file store (%s): %w", path, err)
+	}
+	logger.Info("using file store: %s", path)
+	return fs, nil
+}
+
+// openEventStore abre o log de eventos em EVENT_STORE_DIR (padrão data/events)
+func openEventStore(logger models.Logger) (store.Store, error) {
+	dir := os.Getenv("EVENT_STORE_DIR")
+	if dir == "" {
+		dir = "data/events"
+	}
+	es, err := store.NewEventStore(store.EventStoreOptions{Dir: dir, SnapshotEvery: 1000, Sync: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event store (%s): %w", dir, err)
+	}
+	logger.Info("using event store: %s", dir)
+	return es, nil
+}
+
+// envDuration lê uma duração como "720h"; valores inválidos usam o padrão
+func envDuration(logger models.Logger, name string, fallback time.Duration) time.Duration {
+	v := os.Getenv(name)
+	if v == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		logger.Warn("invalid %s=%q, using %v", name, v, fallback)
+		return fallback
+	}
+	return d
+}
+
+// envPositiveDuration é envDuration para intervalos, em que "0" não faz sentido
+func envPositiveDuration(logger models.Logger, name string, fallback time.Duration) time.Duration {
+	d := envDuration(logger, name, fallback)
+	if d <= 0 {
+		logger.Warn("invalid %s=%q, using %v", name, os.Getenv(name), fallback)
+		return fallback
+	}
+	return d
 }
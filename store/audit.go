@@ -0,0 +1,360 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"example.com/tasksapi/models"
+)
+
+// ErrHistoryUnavailable indica que o Store não registra histórico (sem AuditStore)
+var ErrHistoryUnavailable = errors.New("task history is not enabled")
+
+// HistoryReader é implementado pelos Stores que expõem o histórico de alterações.
+// Decorators como LoggingStore repassam a chamada para o Store encapsulado
+type HistoryReader interface {
+	History(ctx context.Context, id string) ([]models.AuditEntry, error)
+}
+
+// HistoryWriter é implementado pelos backends que gravam a entrada do histórico na mesma
+// escrita da tarefa (mesma transação SQL ou mesmo registro de log): uma não existe sem a
+// outra. O AuditStore não grava de novo para eles e lê o histórico do próprio backend
+type HistoryWriter interface {
+	HistoryReader
+	WritesHistory() bool
+}
+
+// AuditLog guarda as entradas do histórico, separadas das tarefas
+type AuditLog interface {
+	Append(ctx context.Context, entry models.AuditEntry) error
+	// History retorna as entradas da tarefa em ordem cronológica
+	History(ctx context.Context, taskID string) ([]models.AuditEntry, error)
+}
+
+// MemoryAuditLog é o AuditLog em memória
+type MemoryAuditLog struct {
+	mu      sync.RWMutex
+	entries map[string][]models.AuditEntry
+}
+
+func NewMemoryAuditLog() *MemoryAuditLog {
+	return &MemoryAuditLog{entries: make(map[string][]models.AuditEntry)}
+}
+
+func (l *MemoryAuditLog) Append(ctx context.Context, entry models.AuditEntry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	l.add(entry)
+	return nil
+}
+
+// add guarda entradas já persistidas pelo backend, sem checar o contexto: a escrita
+// da tarefa já aconteceu
+func (l *MemoryAuditLog) add(entries ...models.AuditEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, e := range entries {
+		l.entries[e.TaskID] = append(l.entries[e.TaskID], e)
+	}
+}
+
+func (l *MemoryAuditLog) History(ctx context.Context, taskID string) ([]models.AuditEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return append([]models.AuditEntry{}, l.entries[taskID]...), nil
+}
+
+// newAuditEntry monta a entrada com o actor do contexto (models.ContextWithActor)
+func newAuditEntry(ctx context.Context, id, action string, at time.Time, version int64, changes []models.FieldChange) models.AuditEntry {
+	return models.AuditEntry{
+		TaskID:  id,
+		Action:  action,
+		Actor:   models.ActorFromContext(ctx),
+		At:      at,
+		Version: version,
+		Changes: changes,
+	}
+}
+
+// createChanges são os campos preenchidos na criação, sem valor antigo
+func createChanges(created models.Task) []models.FieldChange {
+	changes := models.TaskChanges(models.Task{}, created)
+	for i := range changes {
+		changes[i].Old = nil
+	}
+	return changes
+}
+
+// writeEntry deduz a entrada do histórico de uma escrita pelos estados antes e depois
+// dela: sem before é uma criação, sem after uma purga, e deleted_at distingue exclusão e
+// restauração de uma alteração. Usado pelos backends que são HistoryWriter
+func writeEntry(ctx context.Context, before, after *models.Task, at time.Time) models.AuditEntry {
+	switch {
+	case before == nil:
+		return newAuditEntry(ctx, after.ID, models.AuditCreate, at, after.Version, createChanges(*after))
+	case after == nil:
+		return newAuditEntry(ctx, before.ID, models.AuditPurge, at, 0, nil)
+	case before.DeletedAt == nil && after.DeletedAt != nil:
+		return newAuditEntry(ctx, after.ID, models.AuditDelete, at, after.Version, nil)
+	case before.DeletedAt != nil && after.DeletedAt == nil:
+		return newAuditEntry(ctx, after.ID, models.AuditRestore, at, after.Version, nil)
+	}
+	return newAuditEntry(ctx, after.ID, models.AuditUpdate, at, after.Version, models.TaskChanges(*before, *after))
+}
+
+// AuditStore é um decorator que registra no AuditLog cada escrita bem-sucedida do Store,
+// com o actor do contexto (models.ContextWithActor) e os campos alterados. Com um backend
+// HistoryWriter (SQL, arquivo, eventos) ele não grava nada: o backend registra a entrada
+// na mesma escrita da tarefa. Nos demais (memória, MongoDB) a entrada é gravada depois da
+// escrita, e uma falha ao gravá-la é logada, mas não desfaz nem falha a escrita
+type AuditStore struct {
+	store  Store
+	log    AuditLog
+	logger models.Logger
+	// inStore é o próprio backend quando ele grava o histórico
+	inStore HistoryWriter
+}
+
+func NewAuditStore(s Store, log AuditLog, logger models.Logger) Store {
+	if logger == nil {
+		logger = models.NewDefaultLogger()
+	}
+	a := &AuditStore{store: s, log: log, logger: logger}
+	if w, ok := s.(HistoryWriter); ok && w.WritesHistory() {
+		a.inStore = w
+	}
+	return a
+}
+
+func (a *AuditStore) Get(ctx context.Context, id string) (models.Task, error) {
+	return a.store.Get(ctx, id)
+}
+
+func (a *AuditStore) List(ctx context.Context) ([]models.Task, error) {
+	return a.store.List(ctx)
+}
+
+func (a *AuditStore) Query(ctx context.Context, q models.TaskQuery) (models.TaskPage, error) {
+	return a.store.Query(ctx, q)
+}
+
+func (a *AuditStore) GetAsOf(ctx context.Context, id string, at time.Time) (models.Task, error) {
+	r, ok := a.store.(AsOfReader)
+	if !ok {
+		return models.Task{}, ErrAsOfUnavailable
+	}
+	return r.GetAsOf(ctx, id, at)
+}
+
+// TagCounts só lê, então repassa para o Store encapsulado
+func (a *AuditStore) TagCounts(ctx context.Context) ([]models.TagCount, error) {
+	return TagCounts(ctx, a.store)
+}
+
+func (a *AuditStore) History(ctx context.Context, id string) ([]models.AuditEntry, error) {
+	if a.inStore != nil {
+		return a.inStore.History(ctx, id)
+	}
+	return a.log.History(ctx, id)
+}
+
+func (a *AuditStore) record(ctx context.Context, id, action string, version int64, changes []models.FieldChange) {
+	if a.inStore != nil {
+		return
+	}
+	entry := newAuditEntry(ctx, id, action, time.Now().UTC(), version, changes)
+	if err := a.log.Append(ctx, entry); err != nil {
+		a.logger.Error("failed to record %s of task %s in history: %v", action, id, err)
+	}
+}
+
+func (a *AuditStore) Create(ctx context.Context, t models.Task) (models.Task, error) {
+	created, err := a.store.Create(ctx, t)
+	if err != nil {
+		return created, err
+	}
+	a.record(ctx, created.ID, models.AuditCreate, created.Version, createChanges(created))
+	return created, nil
+}
+
+// Update e UpdateIfVersion passam pelo UpdateWith do Store encapsulado para obter o
+// estado anterior na mesma operação da escrita
+func (a *AuditStore) Update(ctx context.Context, id string, patch map[string]interface{}) (models.Task, error) {
+	return a.UpdateWith(ctx, id, func(models.Task) (map[string]interface{}, error) {
+		return patch, nil
+	})
+}
+
+func (a *AuditStore) UpdateIfVersion(ctx context.Context, id string, version int64, patch map[string]interface{}) (models.Task, error) {
+	return a.UpdateWith(ctx, id, func(current models.Task) (map[string]interface{}, error) {
+		if current.Version != version {
+			return nil, ErrVersionConflict
+		}
+		return patch, nil
+	})
+}
+
+func (a *AuditStore) UpdateWith(ctx context.Context, id string, fn UpdateFunc) (models.Task, error) {
+	var before models.Task
+	updated, err := a.store.UpdateWith(ctx, id, func(current models.Task) (map[string]interface{}, error) {
+		before = current
+		return fn(current)
+	})
+	if err != nil {
+		return updated, err
+	}
+	a.record(ctx, id, models.AuditUpdate, updated.Version, models.TaskChanges(before, updated))
+	return updated, nil
+}
+
+// Delete exclui pela versão lida (deleteTask) para registrar a versão gravada pela exclusão
+func (a *AuditStore) Delete(ctx context.Context, id string) error {
+	deleted, err := deleteTask(ctx, a.store, id)
+	if err != nil {
+		return err
+	}
+	a.record(ctx, id, models.AuditDelete, deleted.Version, nil)
+	return nil
+}
+
+func (a *AuditStore) DeleteIfVersion(ctx context.Context, id string, version int64) error {
+	if err := a.store.DeleteIfVersion(ctx, id, version); err != nil {
+		return err
+	}
+	// A exclusão incrementa a versão
+	a.record(ctx, id, models.AuditDelete, version+1, nil)
+	return nil
+}
+
+func (a *AuditStore) Restore(ctx context.Context, id string) (models.Task, error) {
+	restored, err := a.store.Restore(ctx, id)
+	if err != nil {
+		return restored, err
+	}
+	a.record(ctx, id, models.AuditRestore, restored.Version, nil)
+	return restored, nil
+}
+
+func (a *AuditStore) Purge(ctx context.Context, id string) error {
+	if err := a.store.Purge(ctx, id); err != nil {
+		return err
+	}
+	a.record(ctx, id, models.AuditPurge, 0, nil)
+	return nil
+}
+
+// CreateMany, UpdateMany, DeleteMany e as versões atômicas registram no histórico cada
+// item gravado do lote
+func (a *AuditStore) CreateMany(ctx context.Context, tasks []models.Task) ([]BulkResult, error) {
+	return a.createMany(ctx, tasks, CreateMany)
+}
+
+func (a *AuditStore) CreateManyAtomic(ctx context.Context, tasks []models.Task) ([]BulkResult, error) {
+	return a.createMany(ctx, tasks, CreateManyAtomic)
+}
+
+func (a *AuditStore) UpdateMany(ctx context.Context, ids []string, fn UpdateFunc) ([]BulkResult, error) {
+	return a.updateMany(ctx, ids, fn, UpdateMany)
+}
+
+func (a *AuditStore) UpdateManyAtomic(ctx context.Context, ids []string, fn UpdateFunc) ([]BulkResult, error) {
+	return a.updateMany(ctx, ids, fn, UpdateManyAtomic)
+}
+
+func (a *AuditStore) DeleteMany(ctx context.Context, ids []string) ([]BulkResult, error) {
+	return a.deleteMany(ctx, ids, DeleteMany)
+}
+
+func (a *AuditStore) DeleteManyAtomic(ctx context.Context, ids []string) ([]BulkResult, error) {
+	return a.deleteMany(ctx, ids, DeleteManyAtomic)
+}
+
+func (a *AuditStore) createMany(ctx context.Context, tasks []models.Task,
+	create func(context.Context, Store, []models.Task) ([]BulkResult, error)) ([]BulkResult, error) {
+	results, err := create(ctx, a.store, tasks)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range results {
+		if r.Err != nil {
+			continue
+		}
+		a.record(ctx, r.Task.ID, models.AuditCreate, r.Task.Version, createChanges(r.Task))
+	}
+	return results, nil
+}
+
+func (a *AuditStore) updateMany(ctx context.Context, ids []string, fn UpdateFunc,
+	update func(context.Context, Store, []string, UpdateFunc) ([]BulkResult, error)) ([]BulkResult, error) {
+	// O mesmo id pode aparecer mais de uma vez no lote; cada resultado guarda o estado
+	// anterior da versão que ele alterou
+	var mu sync.Mutex
+	before := map[string]map[int64]models.Task{}
+	results, err := update(ctx, a.store, ids, func(current models.Task) (map[string]interface{}, error) {
+		mu.Lock()
+		if before[current.ID] == nil {
+			before[current.ID] = map[int64]models.Task{}
+		}
+		before[current.ID][current.Version] = current
+		mu.Unlock()
+		return fn(current)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range results {
+		if r.Err != nil {
+			continue
+		}
+		a.record(ctx, r.Task.ID, models.AuditUpdate, r.Task.Version, models.TaskChanges(before[r.Task.ID][r.Task.Version-1], r.Task))
+	}
+	return results, nil
+}
+
+func (a *AuditStore) deleteMany(ctx context.Context, ids []string,
+	del func(context.Context, Store, []string) ([]BulkResult, error)) ([]BulkResult, error) {
+	results, err := del(ctx, a.store, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range results {
+		if r.Err == nil {
+			a.record(ctx, r.Task.ID, models.AuditDelete, r.Task.Version, nil)
+		}
+	}
+	return results, nil
+}
+
+// PurgeDeleted purga tarefa por tarefa para que cada remoção fique no histórico; um
+// HistoryWriter já registra cada tarefa purgada na mesma escrita
+func (a *AuditStore) PurgeDeleted(ctx context.Context, before time.Time) (int, error) {
+	if a.inStore != nil {
+		return a.store.PurgeDeleted(ctx, before)
+	}
+	page, err := a.store.Query(ctx, models.TaskQuery{Deleted: true})
+	if err != nil {
+		return 0, err
+	}
+	purged := 0
+	for _, t := range page.Tasks {
+		if t.DeletedAt == nil || !t.DeletedAt.Before(before) {
+			continue
+		}
+		err := a.Purge(ctx, t.ID)
+		if errors.Is(err, ErrNotFound) {
+			// Já purgada por outra chamada
+			continue
+		}
+		if err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
@@ -0,0 +1,313 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"example.com/tasksapi/models"
+	"github.com/google/uuid"
+)
+
+// BulkResult é o resultado de um item de uma operação em lote. Err preenchido indica que
+// só aquele item falhou. Nas exclusões bem-sucedidas Task traz a tarefa na lixeira, com a
+// versão gravada pela exclusão
+type BulkResult struct {
+	Task models.Task
+	Err  error
+}
+
+// BulkWriter é implementado pelos Stores que gravam um lote numa única operação
+// (InsertMany/BulkWrite no MongoDB, uma passada sob o lock no InMemoryStore). Os
+// resultados vêm na ordem dos itens; o erro retornado indica que o lote inteiro falhou
+type BulkWriter interface {
+	CreateMany(ctx context.Context, tasks []models.Task) ([]BulkResult, error)
+	// UpdateMany chama fn para cada tarefa, como UpdateWith
+	UpdateMany(ctx context.Context, ids []string, fn UpdateFunc) ([]BulkResult, error)
+	DeleteMany(ctx context.Context, ids []string) ([]BulkResult, error)
+}
+
+// ErrAtomicUnavailable indica que o Store não grava lotes atômicos
+var ErrAtomicUnavailable = errors.New("atomic batches are not supported by this store")
+
+// ErrBatchAborted marca, num lote atômico que falhou, os itens válidos que não foram gravados
+var ErrBatchAborted = errors.New("not applied: another item of the atomic batch failed")
+
+// AtomicBulkWriter grava o lote inteiro ou nada. Se algum item falhar, nenhum é aplicado:
+// os itens com problema trazem o próprio erro e os demais ErrBatchAborted
+type AtomicBulkWriter interface {
+	CreateManyAtomic(ctx context.Context, tasks []models.Task) ([]BulkResult, error)
+	UpdateManyAtomic(ctx context.Context, ids []string, fn UpdateFunc) ([]BulkResult, error)
+	DeleteManyAtomic(ctx context.Context, ids []string) ([]BulkResult, error)
+}
+
+// CreateMany usa o BulkWriter do Store ou, sem ele, cria uma tarefa por vez
+func CreateMany(ctx context.Context, s Store, tasks []models.Task) ([]BulkResult, error) {
+	if bw, ok := s.(BulkWriter); ok {
+		return bw.CreateMany(ctx, tasks)
+	}
+	return eachItem(ctx, len(tasks), func(i int) (models.Task, error) {
+		return s.Create(ctx, tasks[i])
+	})
+}
+
+// UpdateMany usa o BulkWriter do Store ou, sem ele, chama UpdateWith para cada tarefa
+func UpdateMany(ctx context.Context, s Store, ids []string, fn UpdateFunc) ([]BulkResult, error) {
+	if bw, ok := s.(BulkWriter); ok {
+		return bw.UpdateMany(ctx, ids, fn)
+	}
+	return eachItem(ctx, len(ids), func(i int) (models.Task, error) {
+		return s.UpdateWith(ctx, ids[i], fn)
+	})
+}
+
+// DeleteMany usa o BulkWriter do Store ou, sem ele, exclui uma tarefa por vez
+func DeleteMany(ctx context.Context, s Store, ids []string) ([]BulkResult, error) {
+	if bw, ok := s.(BulkWriter); ok {
+		return bw.DeleteMany(ctx, ids)
+	}
+	return eachItem(ctx, len(ids), func(i int) (models.Task, error) {
+		return deleteTask(ctx, s, ids[i])
+	})
+}
+
+// deleteTask move a tarefa para a lixeira com DeleteIfVersion, para saber a versão gravada
+// pela exclusão; se outra escrita acontecer no meio, repete até maxUpdateRetries vezes
+func deleteTask(ctx context.Context, s Store, id string) (models.Task, error) {
+	for attempt := 0; attempt < maxUpdateRetries; attempt++ {
+		current, err := s.Get(ctx, id)
+		if err != nil {
+			return models.Task{ID: id}, err
+		}
+		err = s.DeleteIfVersion(ctx, id, current.Version)
+		if errors.Is(err, ErrVersionConflict) {
+			continue
+		}
+		if err != nil {
+			return models.Task{ID: id}, err
+		}
+		now := time.Now().UTC()
+		current.DeletedAt = &now
+		current.Version++
+		return current, nil
+	}
+	return models.Task{ID: id}, ErrVersionConflict
+}
+
+// CreateManyAtomic, UpdateManyAtomic e DeleteManyAtomic retornam ErrAtomicUnavailable
+// quando o Store não implementa AtomicBulkWriter
+func CreateManyAtomic(ctx context.Context, s Store, tasks []models.Task) ([]BulkResult, error) {
+	if aw, ok := s.(AtomicBulkWriter); ok {
+		return aw.CreateManyAtomic(ctx, tasks)
+	}
+	return nil, ErrAtomicUnavailable
+}
+
+func UpdateManyAtomic(ctx context.Context, s Store, ids []string, fn UpdateFunc) ([]BulkResult, error) {
+	if aw, ok := s.(AtomicBulkWriter); ok {
+		return aw.UpdateManyAtomic(ctx, ids, fn)
+	}
+	return nil, ErrAtomicUnavailable
+}
+
+func DeleteManyAtomic(ctx context.Context, s Store, ids []string) ([]BulkResult, error) {
+	if aw, ok := s.(AtomicBulkWriter); ok {
+		return aw.DeleteManyAtomic(ctx, ids)
+	}
+	return nil, ErrAtomicUnavailable
+}
+
+// abortBatch verifica se algum item falhou e, nesse caso, marca os demais com
+// ErrBatchAborted; o lote atômico então não deve ser gravado
+func abortBatch(results []BulkResult) bool {
+	failed := false
+	for _, r := range results {
+		if r.Err != nil {
+			failed = true
+			break
+		}
+	}
+	if !failed {
+		return false
+	}
+	for i := range results {
+		if results[i].Err == nil {
+			results[i].Err = ErrBatchAborted
+		}
+	}
+	return true
+}
+
+// eachItem executa o lote item a item; só o cancelamento do contexto interrompe o lote
+func eachItem(ctx context.Context, n int, do func(i int) (models.Task, error)) ([]BulkResult, error) {
+	results := make([]BulkResult, n)
+	for i := range results {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		results[i].Task, results[i].Err = do(i)
+	}
+	return results, nil
+}
+
+// CreateMany cria todas as tarefas sob um único lock e numa única chamada a persist
+func (s *InMemoryStore) CreateMany(ctx context.Context, tasks []models.Task) ([]BulkResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now().UTC()
+	results := make([]BulkResult, len(tasks))
+	put := make([]models.Task, len(tasks))
+	for i, t := range tasks {
+		t.ID = uuid.New().String()
+		if t.DueDate != nil && t.DueDate.IsZero() {
+			t.DueDate = nil
+		}
+		// Um nanossegundo de diferença mantém a ordem do lote na ordenação por created_at
+		t.CreatedAt = now.Add(time.Duration(i))
+		t.UpdatedAt = nil
+		t.DeletedAt = nil
+		t.Score = 0
+		t.Version = 1
+		put[i] = t
+		results[i].Task = t
+	}
+	if err := s.save(ctx, put, nil); err != nil {
+		return nil, err
+	}
+	for _, t := range put {
+		s.items[t.ID] = t
+		s.indexTask(t)
+	}
+	return results, nil
+}
+
+// CreateManyAtomic é o próprio CreateMany: no InMemoryStore a criação de um item só falha
+// junto com o lote inteiro
+func (s *InMemoryStore) CreateManyAtomic(ctx context.Context, tasks []models.Task) ([]BulkResult, error) {
+	return s.CreateMany(ctx, tasks)
+}
+
+// UpdateMany avalia fn e aplica os patches numa única seção crítica; itens inexistentes
+// ou rejeitados por fn falham sozinhos. fn não pode chamar o Store
+func (s *InMemoryStore) UpdateMany(ctx context.Context, ids []string, fn UpdateFunc) ([]BulkResult, error) {
+	return s.updateMany(ctx, ids, fn, false)
+}
+
+// UpdateManyAtomic aplica os patches sobre cópias das tarefas (copy-on-write) e só as
+// publica se todos os itens passarem
+func (s *InMemoryStore) UpdateManyAtomic(ctx context.Context, ids []string, fn UpdateFunc) ([]BulkResult, error) {
+	return s.updateMany(ctx, ids, fn, true)
+}
+
+func (s *InMemoryStore) updateMany(ctx context.Context, ids []string, fn UpdateFunc, atomic bool) ([]BulkResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now().UTC()
+	results := make([]BulkResult, len(ids))
+	// pending guarda o estado já alterado no lote, para ids repetidos verem a alteração anterior
+	pending := map[string]models.Task{}
+	var put []models.Task
+	for i, id := range ids {
+		current, ok := pending[id]
+		if !ok {
+			current, ok = s.items[id]
+		}
+		if !ok || current.DeletedAt != nil {
+			results[i] = BulkResult{Task: models.Task{ID: id}, Err: ErrNotFound}
+			continue
+		}
+		patch, err := fn(current)
+		if err != nil {
+			results[i] = BulkResult{Task: current, Err: err}
+			continue
+		}
+		p, err := parsePatch(patch)
+		if err != nil {
+			results[i] = BulkResult{Task: current, Err: err}
+			continue
+		}
+		t := p.apply(current)
+		updatedAt := now
+		t.UpdatedAt = &updatedAt
+		t.Version++
+		pending[id] = t
+		put = append(put, t)
+		results[i].Task = t
+	}
+	if atomic && abortBatch(results) {
+		return results, nil
+	}
+	if err := s.commitPending(ctx, put, pending); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// DeleteMany move as tarefas para a lixeira numa única seção crítica
+func (s *InMemoryStore) DeleteMany(ctx context.Context, ids []string) ([]BulkResult, error) {
+	return s.deleteMany(ctx, ids, false)
+}
+
+// DeleteManyAtomic só exclui se todas as tarefas do lote existirem
+func (s *InMemoryStore) DeleteManyAtomic(ctx context.Context, ids []string) ([]BulkResult, error) {
+	return s.deleteMany(ctx, ids, true)
+}
+
+func (s *InMemoryStore) deleteMany(ctx context.Context, ids []string, atomic bool) ([]BulkResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now().UTC()
+	results := make([]BulkResult, len(ids))
+	pending := map[string]models.Task{}
+	var put []models.Task
+	for i, id := range ids {
+		results[i].Task = models.Task{ID: id}
+		t, ok := pending[id]
+		if !ok {
+			t, ok = s.items[id]
+		}
+		if !ok || t.DeletedAt != nil {
+			results[i].Err = ErrNotFound
+			continue
+		}
+		deletedAt := now
+		t.DeletedAt = &deletedAt
+		t.Version++
+		results[i].Task = t
+		pending[id] = t
+		put = append(put, t)
+	}
+	if atomic && abortBatch(results) {
+		return results, nil
+	}
+	if err := s.commitPending(ctx, put, pending); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// commitPending persiste e aplica o estado final de cada tarefa alterada no lote; deve
+// ser chamado com o lock de escrita
+func (s *InMemoryStore) commitPending(ctx context.Context, put []models.Task, pending map[string]models.Task) error {
+	if len(put) == 0 {
+		return nil
+	}
+	if err := s.save(ctx, put, nil); err != nil {
+		return err
+	}
+	for id, t := range pending {
+		s.unindexTask(s.items[id])
+		s.items[id] = t
+		s.indexTask(t)
+	}
+	return nil
+}
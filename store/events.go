@@ -0,0 +1,654 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"example.com/tasksapi/models"
+	"github.com/google/uuid"
+)
+
+// ErrAsOfUnavailable indica que o Store não guarda estados anteriores das tarefas
+var ErrAsOfUnavailable = errors.New("as_of is not supported by the configured store")
+
+// AsOfReader é implementado pelos Stores que reconstroem a tarefa num instante passado.
+// Decorators como LoggingStore repassam a chamada para o Store encapsulado
+type AsOfReader interface {
+	GetAsOf(ctx context.Context, id string, at time.Time) (models.Task, error)
+}
+
+// EventType identifica o tipo de um Event
+type EventType string
+
+const (
+	TaskCreated   EventType = "TaskCreated"
+	FieldChanged  EventType = "FieldChanged"
+	StatusChanged EventType = "StatusChanged"
+	TaskDeleted   EventType = "TaskDeleted"
+	TaskRestored  EventType = "TaskRestored"
+	TaskPurged    EventType = "TaskPurged"
+)
+
+// Event é um registro do log do EventStore. Version é a versão da tarefa depois do evento.
+// Task só existe em TaskCreated; Field e Value em FieldChanged e StatusChanged, com Value
+// na representação JSON do campo (null limpa). Audit é a entrada do histórico da escrita,
+// no último evento de cada tarefa gravado por ela
+type Event struct {
+	Seq     int64              `json:"seq"`
+	Type    EventType          `json:"type"`
+	TaskID  string             `json:"task_id"`
+	At      time.Time          `json:"at"`
+	Actor   string             `json:"actor,omitempty"`
+	Version int64              `json:"version"`
+	Task    *models.Task       `json:"task,omitempty"`
+	Field   string             `json:"field,omitempty"`
+	Value   interface{}        `json:"value,omitempty"`
+	Audit   *models.AuditEntry `json:"audit,omitempty"`
+}
+
+// apply é a única função que transforma eventos em estado, usada tanto nas escritas
+// quanto no replay. ok é falso quando a tarefa deixa de existir
+func (e Event) apply(t models.Task) (models.Task, bool, error) {
+	at := e.At
+	switch e.Type {
+	case TaskCreated:
+		if e.Task == nil {
+			return models.Task{}, false, fmt.Errorf("event %d: TaskCreated without task", e.Seq)
+		}
+		return *e.Task, true, nil
+	case FieldChanged, StatusChanged:
+		p, err := parsePatch(map[string]interface{}{e.Field: e.Value})
+		if err != nil {
+			return models.Task{}, false, fmt.Errorf("event %d: %w", e.Seq, err)
+		}
+		t = p.apply(t)
+		t.UpdatedAt = &at
+	case TaskDeleted:
+		t.DeletedAt = &at
+	case TaskRestored:
+		t.DeletedAt = nil
+	case TaskPurged:
+		return models.Task{}, false, nil
+	default:
+		return models.Task{}, false, fmt.Errorf("event %d: unknown type %q", e.Seq, e.Type)
+	}
+	t.Version = e.Version
+	return t, true, nil
+}
+
+// eventValue é o valor do campo na representação gravada no evento
+func eventValue(t models.Task, field string) interface{} {
+	switch field {
+	case "title":
+		return t.Title
+	case "status":
+		return t.Status
+	case "description":
+		if t.Description == "" {
+			return nil
+		}
+		return t.Description
+	case "priority":
+		if t.Priority == "" {
+			return nil
+		}
+		return t.Priority
+	case "due_date":
+		if t.DueDate == nil {
+			return nil
+		}
+		return t.DueDate.String()
+	case "parent_id":
+		if t.ParentID == "" {
+			return nil
+		}
+		return t.ParentID
+	case "project_id":
+		if t.ProjectID == "" {
+			return nil
+		}
+		return t.ProjectID
+	case "blocked_by":
+		if len(t.BlockedBy) == 0 {
+			return nil
+		}
+		return t.BlockedBy
+	case "tags":
+		if len(t.Tags) == 0 {
+			return nil
+		}
+		return t.Tags
+	}
+	return nil
+}
+
+// EventStoreOptions configura o EventStore
+type EventStoreOptions struct {
+	// Dir guarda events.jsonl e snapshot.json e, quando aberto, projects.jsonl
+	Dir string
+	// SnapshotEvery grava um snapshot a cada N eventos; zero desliga
+	SnapshotEvery int
+	// Sync faz fsync do log a cada escrita
+	Sync bool
+}
+
+// eventSnapshot é o estado de todas as tarefas (inclusive na lixeira) depois do evento Seq.
+// At é a maior data entre os eventos até Seq, que não precisam estar em ordem de data
+// (o relógio pode voltar)
+type eventSnapshot struct {
+	Seq   int64         `json:"seq"`
+	At    time.Time     `json:"at"`
+	Tasks []models.Task `json:"tasks"`
+}
+
+// EventStore deriva as tarefas de um log append-only de eventos em arquivo. O estado atual
+// fica num InMemoryStore, que atende as leituras; o log nunca é reescrito, então qualquer
+// instante passado pode ser reconstruído (GetAsOf). Snapshots só aceleram a abertura e
+// as consultas recentes
+type EventStore struct {
+	// mu serializa as escritas: evento no log e estado em memória mudam juntos
+	mu   sync.Mutex
+	mem  *InMemoryStore
+	log  *jsonlLog
+	opts EventStoreOptions
+	seq  int64
+	// maxAt é a maior data dos eventos gravados, guardada no snapshot
+	maxAt         time.Time
+	sinceSnapshot int
+	// history é carregado de todo o log, inclusive dos eventos anteriores ao snapshot
+	history *MemoryAuditLog
+	// projects é aberto sob demanda (ProjectStore)
+	projects *FileProjectStore
+}
+
+func (s *EventStore) logPath() string      { return filepath.Join(s.opts.Dir, "events.jsonl") }
+func (s *EventStore) snapshotPath() string { return filepath.Join(s.opts.Dir, "snapshot.json") }
+
+// NewEventStore abre o log em opts.Dir, carregando o snapshot e refazendo os eventos seguintes
+func NewEventStore(opts EventStoreOptions) (*EventStore, error) {
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create event store dir: %w", err)
+	}
+	s := &EventStore{mem: New().(*InMemoryStore), opts: opts, history: NewMemoryAuditLog()}
+
+	snap, err := s.readSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	tasks := map[string]models.Task{}
+	for _, t := range snap.Tasks {
+		tasks[t.ID] = t
+	}
+	s.seq, s.maxAt = snap.Seq, snap.At
+
+	s.log, err = openJSONL(s.logPath(), opts.Sync, func(line []byte) error {
+		var e Event
+		if err := json.Unmarshal(line, &e); err != nil {
+			return err
+		}
+		if e.Audit != nil {
+			s.history.add(*e.Audit)
+		}
+		if e.Seq <= snap.Seq {
+			return nil
+		}
+		s.seq = e.Seq
+		if e.At.After(s.maxAt) {
+			s.maxAt = e.At
+		}
+		s.sinceSnapshot++
+		return replayEvent(tasks, e)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tasks {
+		s.mem.items[t.ID] = t
+		s.mem.indexTask(t)
+	}
+	return s, nil
+}
+
+func (s *EventStore) readSnapshot() (eventSnapshot, error) {
+	var snap eventSnapshot
+	data, err := os.ReadFile(s.snapshotPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return snap, nil
+	}
+	if err != nil {
+		return snap, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return snap, fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+	return snap, nil
+}
+
+// replayEvent aplica o evento sobre o mapa de tarefas
+func replayEvent(tasks map[string]models.Task, e Event) error {
+	t, ok, err := e.apply(tasks[e.TaskID])
+	if err != nil {
+		return err
+	}
+	if ok {
+		tasks[e.TaskID] = t
+	} else {
+		delete(tasks, e.TaskID)
+	}
+	return nil
+}
+
+// commit calcula o estado resultante dos eventos, grava-os no log com a entrada do
+// histórico de cada tarefa e só então os aplica ao estado em memória; deve ser chamado
+// com s.mu
+func (s *EventStore) commit(ctx context.Context, events ...Event) (models.Task, error) {
+	actor := models.ActorFromContext(ctx)
+	for i := range events {
+		events[i].Seq = s.seq + int64(i) + 1
+		events[i].Actor = actor
+	}
+
+	// states[i] é a tarefa depois do evento i; before e after, o estado de cada tarefa
+	// antes e depois de todos os eventos (nil quando ela não existe)
+	states := make([]*models.Task, len(events))
+	before := map[string]*models.Task{}
+	after := map[string]*models.Task{}
+	last := map[string]int{}
+	s.mem.mu.RLock()
+	for i, e := range events {
+		current, seen := after[e.TaskID]
+		if !seen {
+			if t, ok := s.mem.items[e.TaskID]; ok {
+				current = &t
+			}
+			before[e.TaskID] = current
+		}
+		var t models.Task
+		if current != nil {
+			t = *current
+		}
+		next, ok, err := e.apply(t)
+		if err != nil {
+			s.mem.mu.RUnlock()
+			return models.Task{}, err
+		}
+		if ok {
+			states[i] = &next
+		}
+		after[e.TaskID] = states[i]
+		last[e.TaskID] = i
+	}
+	s.mem.mu.RUnlock()
+
+	var entries []models.AuditEntry
+	for i := range events {
+		id := events[i].TaskID
+		if last[id] != i {
+			continue
+		}
+		entry := writeEntry(ctx, before[id], after[id], events[i].At)
+		events[i].Audit = &entry
+		entries = append(entries, entry)
+	}
+
+	records := make([]interface{}, len(events))
+	for i, e := range events {
+		records[i] = e
+	}
+	if err := s.log.append(records...); err != nil {
+		return models.Task{}, err
+	}
+	s.seq += int64(len(events))
+	for _, e := range events {
+		if e.At.After(s.maxAt) {
+			s.maxAt = e.At
+		}
+	}
+	s.history.add(entries...)
+
+	s.mem.mu.Lock()
+	for i, e := range events {
+		if current, ok := s.mem.items[e.TaskID]; ok {
+			s.mem.unindexTask(current)
+		}
+		if states[i] != nil {
+			s.mem.items[e.TaskID] = *states[i]
+			s.mem.indexTask(*states[i])
+		} else {
+			delete(s.mem.items, e.TaskID)
+		}
+	}
+	s.mem.mu.Unlock()
+
+	s.sinceSnapshot += len(events)
+	if s.opts.SnapshotEvery > 0 && s.sinceSnapshot >= s.opts.SnapshotEvery {
+		// O snapshot é só uma otimização: se falhar, o log continua completo
+		if err := s.snapshot(); err == nil {
+			s.sinceSnapshot = 0
+		}
+	}
+	var t models.Task
+	if last := states[len(states)-1]; last != nil {
+		t = *last
+	}
+	return t, nil
+}
+
+// Snapshot grava o estado atual de forma atômica
+func (s *EventStore) Snapshot() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.snapshot(); err != nil {
+		return err
+	}
+	s.sinceSnapshot = 0
+	return nil
+}
+
+// snapshot deve ser chamado com s.mu
+func (s *EventStore) snapshot() error {
+	s.mem.mu.RLock()
+	snap := eventSnapshot{Seq: s.seq, At: s.maxAt, Tasks: make([]models.Task, 0, len(s.mem.items))}
+	for _, t := range s.mem.items {
+		snap.Tasks = append(snap.Tasks, t)
+	}
+	s.mem.mu.RUnlock()
+
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	if err := writeFileAtomic(s.snapshotPath(), data); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return nil
+}
+
+// live retorna a tarefa ativa; deve ser chamado com s.mu
+func (s *EventStore) live(id string) (models.Task, error) {
+	s.mem.mu.RLock()
+	defer s.mem.mu.RUnlock()
+	t, ok := s.mem.items[id]
+	if !ok || t.DeletedAt != nil {
+		return models.Task{}, ErrNotFound
+	}
+	return t, nil
+}
+
+func (s *EventStore) Get(ctx context.Context, id string) (models.Task, error) {
+	return s.mem.Get(ctx, id)
+}
+
+func (s *EventStore) List(ctx context.Context) ([]models.Task, error) {
+	return s.mem.List(ctx)
+}
+
+func (s *EventStore) Query(ctx context.Context, q models.TaskQuery) (models.TaskPage, error) {
+	return s.mem.Query(ctx, q)
+}
+
+func (s *EventStore) Create(ctx context.Context, t models.Task) (models.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Task{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t.ID = uuid.New().String()
+	if t.DueDate != nil && t.DueDate.IsZero() {
+		t.DueDate = nil
+	}
+	t.CreatedAt = time.Now().UTC()
+	t.UpdatedAt = nil
+	t.DeletedAt = nil
+	t.Score = 0
+	t.Version = 1
+	return s.commit(ctx, Event{Type: TaskCreated, TaskID: t.ID, At: t.CreatedAt, Version: 1, Task: &t})
+}
+
+func (s *EventStore) Update(ctx context.Context, id string, patch map[string]interface{}) (models.Task, error) {
+	return s.update(ctx, id, anyVersion, patch)
+}
+
+func (s *EventStore) UpdateIfVersion(ctx context.Context, id string, version int64, patch map[string]interface{}) (models.Task, error) {
+	return s.update(ctx, id, version, patch)
+}
+
+func (s *EventStore) update(ctx context.Context, id string, version int64, patch map[string]interface{}) (models.Task, error) {
+	return s.UpdateWith(ctx, id, func(current models.Task) (map[string]interface{}, error) {
+		if version != anyVersion && current.Version != version {
+			return nil, ErrVersionConflict
+		}
+		return patch, nil
+	})
+}
+
+// UpdateWith gera um evento por campo do patch (StatusChanged para status), todos com a
+// mesma versão e data; um patch vazio não gera evento nem nova versão. fn roda com o
+// lock de escrita e não pode chamar o Store
+func (s *EventStore) UpdateWith(ctx context.Context, id string, fn UpdateFunc) (models.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Task{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, err := s.live(id)
+	if err != nil {
+		return models.Task{}, err
+	}
+	patch, err := fn(current)
+	if err != nil {
+		return models.Task{}, err
+	}
+	p, err := parsePatch(patch)
+	if err != nil {
+		return models.Task{}, err
+	}
+	next := p.apply(current)
+
+	if len(patch) == 0 {
+		// Nada mudou, então não há evento a gravar: a tarefa volta como está, sem
+		// nova versão
+		return current, nil
+	}
+	fields := make([]string, 0, len(patch))
+	for field := range patch {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	now := time.Now().UTC()
+	events := make([]Event, len(fields))
+	for i, field := range fields {
+		typ := FieldChanged
+		if field == "status" {
+			typ = StatusChanged
+		}
+		events[i] = Event{Type: typ, TaskID: id, At: now, Version: current.Version + 1, Field: field, Value: eventValue(next, field)}
+	}
+	return s.commit(ctx, events...)
+}
+
+func (s *EventStore) Delete(ctx context.Context, id string) error {
+	return s.delete(ctx, id, anyVersion)
+}
+
+func (s *EventStore) DeleteIfVersion(ctx context.Context, id string, version int64) error {
+	return s.delete(ctx, id, version)
+}
+
+func (s *EventStore) delete(ctx context.Context, id string, version int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, err := s.live(id)
+	if err != nil {
+		return err
+	}
+	if version != anyVersion && current.Version != version {
+		return ErrVersionConflict
+	}
+	_, err = s.commit(ctx, Event{Type: TaskDeleted, TaskID: id, At: time.Now().UTC(), Version: current.Version + 1})
+	return err
+}
+
+func (s *EventStore) Restore(ctx context.Context, id string) (models.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Task{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.mem.mu.RLock()
+	current, ok := s.mem.items[id]
+	s.mem.mu.RUnlock()
+	if !ok || current.DeletedAt == nil {
+		return models.Task{}, ErrNotFound
+	}
+	return s.commit(ctx, Event{Type: TaskRestored, TaskID: id, At: time.Now().UTC(), Version: current.Version + 1})
+}
+
+func (s *EventStore) Purge(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.mem.mu.RLock()
+	_, ok := s.mem.items[id]
+	s.mem.mu.RUnlock()
+	if !ok {
+		return ErrNotFound
+	}
+	_, err := s.commit(ctx, Event{Type: TaskPurged, TaskID: id, At: time.Now().UTC()})
+	return err
+}
+
+func (s *EventStore) PurgeDeleted(ctx context.Context, before time.Time) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	var events []Event
+	s.mem.mu.RLock()
+	for id, t := range s.mem.items {
+		if t.DeletedAt != nil && t.DeletedAt.Before(before) {
+			events = append(events, Event{Type: TaskPurged, TaskID: id, At: now})
+		}
+	}
+	s.mem.mu.RUnlock()
+	if len(events) == 0 {
+		return 0, nil
+	}
+	if _, err := s.commit(ctx, events...); err != nil {
+		return 0, err
+	}
+	return len(events), nil
+}
+
+// GetAsOf reconstrói a tarefa como ela estava no instante at, refazendo em ordem de Seq
+// os eventos dela com data até at, a partir do snapshot (quando todos os eventos dele são
+// anteriores a at) ou do início. O log inteiro é lido: as datas seguem o relógio de quem
+// gravou e podem sair de ordem. Tarefas que ainda não existiam ou estavam na lixeira
+// retornam ErrNotFound
+func (s *EventStore) GetAsOf(ctx context.Context, id string, at time.Time) (models.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Task{}, err
+	}
+	var (
+		t      models.Task
+		exists bool
+		from   int64
+	)
+	if snap, err := s.readSnapshot(); err != nil {
+		return models.Task{}, err
+	} else if snap.Seq > 0 && !snap.At.After(at) {
+		from = snap.Seq
+		for _, st := range snap.Tasks {
+			if st.ID == id {
+				t, exists = st, true
+			}
+		}
+	}
+
+	f, err := os.Open(s.logPath())
+	if err != nil {
+		return models.Task{}, fmt.Errorf("failed to open log: %w", err)
+	}
+	defer f.Close()
+	var events []Event
+	_, err = readJSONL(f, func(line []byte) error {
+		var e Event
+		if err := json.Unmarshal(line, &e); err != nil {
+			return err
+		}
+		if e.Seq > from && e.TaskID == id && !e.At.After(at) {
+			events = append(events, e)
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Task{}, err
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Seq < events[j].Seq })
+	for _, e := range events {
+		if t, exists, err = e.apply(t); err != nil {
+			return models.Task{}, err
+		}
+	}
+	if !exists || t.DeletedAt != nil {
+		return models.Task{}, ErrNotFound
+	}
+	return t, nil
+}
+
+// WritesHistory indica que cada escrita grava sua entrada do histórico no próprio evento
+func (s *EventStore) WritesHistory() bool {
+	return true
+}
+
+// History retorna as entradas da tarefa, carregadas dos eventos na abertura
+func (s *EventStore) History(ctx context.Context, id string) ([]models.AuditEntry, error) {
+	return s.history.History(ctx, id)
+}
+
+// ProjectStore abre (na primeira chamada) os projetos em Dir/projects.jsonl, com o fsync
+// de opts.Sync
+func (s *EventStore) ProjectStore() (ProjectStore, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.projects == nil {
+		projects, err := NewFileProjectStore(filepath.Join(s.opts.Dir, "projects.jsonl"), s.opts.Sync)
+		if err != nil {
+			return nil, err
+		}
+		s.projects = projects
+	}
+	return s.projects, nil
+}
+
+// Close fecha os arquivos do log e dos projetos
+func (s *EventStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := closeProjects(s.projects); err != nil {
+		s.log.close()
+		return err
+	}
+	return s.log.close()
+}
@@ -0,0 +1,287 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"example.com/tasksapi/models"
+)
+
+// FileSyncMode define quando o FileStore faz fsync do log
+type FileSyncMode string
+
+const (
+	// FileSyncAlways faz fsync antes de cada escrita retornar: nada confirmado se perde
+	FileSyncAlways FileSyncMode = "always"
+	// FileSyncInterval faz fsync a cada SyncInterval: um crash do SO perde no máximo esse intervalo
+	FileSyncInterval FileSyncMode = "interval"
+	// FileSyncNever deixa o fsync para o SO; sobrevive a um crash do processo, não do SO
+	FileSyncNever FileSyncMode = "never"
+)
+
+const (
+	defaultSyncInterval     = time.Second
+	defaultCompactThreshold = 1000
+)
+
+// FileStoreOptions configura o FileStore
+type FileStoreOptions struct {
+	// Path é o arquivo do log
+	Path string
+	// Sync padrão é FileSyncAlways
+	Sync         FileSyncMode
+	SyncInterval time.Duration
+	// CompactThreshold é o número mínimo de registros para compactar, o que só acontece
+	// quando o log tem mais que o dobro de registros do que tarefas. Padrão 1000
+	CompactThreshold int
+}
+
+// fileRecord é uma linha do log: o estado completo da tarefa depois da escrita ou, com
+// Purged, a remoção definitiva dela. Audit é a entrada do histórico da escrita, gravada
+// na mesma linha; a compactação regrava o histórico em linhas só com Audit
+type fileRecord struct {
+	Task   *models.Task       `json:"task,omitempty"`
+	Purged string             `json:"purged,omitempty"`
+	Audit  *models.AuditEntry `json:"audit,omitempty"`
+}
+
+// FileStore persiste as tarefas num log append-only em arquivo. O estado fica num
+// InMemoryStore, que atende leituras e escritas; cada escrita é gravada no log antes de
+// valer em memória. Quando o log acumula versões antigas demais ele é reescrito só com
+// o estado atual e o histórico (compactação)
+type FileStore struct {
+	*InMemoryStore
+	opts FileStoreOptions
+	// mu protege log, que é trocado na compactação e usado pelo fsync periódico, e o log
+	// de projetos, aberto sob demanda
+	mu       sync.Mutex
+	log      *jsonlLog
+	projects *FileProjectStore
+	records  int
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewFileStore abre (ou cria) o log em opts.Path e carrega as tarefas. Uma escrita
+// interrompida por crash deixa no máximo uma linha incompleta no fim, que é descartada
+func NewFileStore(opts FileStoreOptions) (*FileStore, error) {
+	if opts.Sync == "" {
+		opts.Sync = FileSyncAlways
+	}
+	switch opts.Sync {
+	case FileSyncAlways, FileSyncInterval, FileSyncNever:
+	default:
+		return nil, fmt.Errorf("invalid sync mode %q, allowed: always, interval, never", opts.Sync)
+	}
+	if opts.SyncInterval <= 0 {
+		opts.SyncInterval = defaultSyncInterval
+	}
+	if opts.CompactThreshold <= 0 {
+		opts.CompactThreshold = defaultCompactThreshold
+	}
+	if err := os.MkdirAll(filepath.Dir(opts.Path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create file store dir: %w", err)
+	}
+	// Restos de uma compactação interrompida; o log original continua válido
+	_ = os.Remove(opts.Path + ".tmp")
+
+	s := &FileStore{InMemoryStore: New().(*InMemoryStore), opts: opts}
+	s.history = NewMemoryAuditLog()
+	var err error
+	s.log, err = openJSONL(opts.Path, opts.Sync == FileSyncAlways, func(line []byte) error {
+		var rec fileRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return err
+		}
+		if rec.Audit != nil {
+			s.history.add(*rec.Audit)
+		}
+		switch {
+		case rec.Task != nil:
+			s.items[rec.Task.ID] = *rec.Task
+		case rec.Purged != "":
+			delete(s.items, rec.Purged)
+		default:
+			// Linha só de histórico, gravada pela compactação
+			return nil
+		}
+		s.records++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range s.items {
+		s.indexTask(t)
+	}
+	if s.shouldCompact() {
+		if err := s.compact(); err != nil {
+			s.log.close()
+			return nil, err
+		}
+	}
+
+	s.persist = s.append
+	if opts.Sync == FileSyncInterval {
+		s.stop, s.done = make(chan struct{}), make(chan struct{})
+		go s.syncLoop()
+	}
+	return s, nil
+}
+
+// append grava as alterações, cada uma na mesma linha da sua entrada do histórico; é o
+// persist do InMemoryStore e roda com o lock de escrita dele
+func (s *FileStore) append(put []models.Task, purged []string, entries []models.AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.shouldCompact() {
+		// A compactação grava o estado anterior a esta escrita, que vem logo depois.
+		// Se falhar, o log antigo continua válido e a escrita segue nele
+		_ = s.compact()
+	}
+	records := make([]interface{}, 0, len(put)+len(purged))
+	for i := range put {
+		records = append(records, fileRecord{Task: &put[i], Audit: &entries[i]})
+	}
+	for i, id := range purged {
+		records = append(records, fileRecord{Purged: id, Audit: &entries[len(put)+i]})
+	}
+	if err := s.log.append(records...); err != nil {
+		return err
+	}
+	s.records += len(records)
+	return nil
+}
+
+func (s *FileStore) shouldCompact() bool {
+	return s.records >= s.opts.CompactThreshold && s.records > 2*len(s.items)
+}
+
+// Compact reescreve o log só com o estado atual
+func (s *FileStore) Compact() error {
+	s.InMemoryStore.mu.RLock()
+	defer s.InMemoryStore.mu.RUnlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.compact()
+}
+
+// compact grava o novo log num temporário e o renomeia por cima do atual, então um crash
+// no meio deixa o log antigo ou o novo, nunca um misto. O histórico não é compactado:
+// cada entrada vira uma linha só com Audit. Deve ser chamado com s.mu e algum lock do
+// InMemoryStore
+func (s *FileStore) compact() error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	s.history.mu.RLock()
+	for _, entries := range s.history.entries {
+		for i := range entries {
+			if err := enc.Encode(fileRecord{Audit: &entries[i]}); err != nil {
+				s.history.mu.RUnlock()
+				return fmt.Errorf("failed to encode log record: %w", err)
+			}
+		}
+	}
+	s.history.mu.RUnlock()
+	for _, t := range s.items {
+		t := t
+		if err := enc.Encode(fileRecord{Task: &t}); err != nil {
+			return fmt.Errorf("failed to encode log record: %w", err)
+		}
+	}
+	if err := writeFileAtomic(s.opts.Path, buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to compact log: %w", err)
+	}
+	log, err := openJSONL(s.opts.Path, s.opts.Sync == FileSyncAlways, func([]byte) error { return nil })
+	if err != nil {
+		return err
+	}
+	s.log.close()
+	s.log = log
+	s.records = len(s.items)
+	return nil
+}
+
+// companionPath é o log auxiliar ao lado do de tarefas: data/tasks.jsonl tem os projetos
+// em data/tasks.projects.jsonl
+func (s *FileStore) companionPath(name string) string {
+	return strings.TrimSuffix(s.opts.Path, filepath.Ext(s.opts.Path)) + "." + name + ".jsonl"
+}
+
+// WritesHistory indica que cada escrita grava sua entrada do histórico na mesma linha do log
+func (s *FileStore) WritesHistory() bool {
+	return true
+}
+
+// History retorna as entradas da tarefa, carregadas do log junto com as tarefas
+func (s *FileStore) History(ctx context.Context, id string) ([]models.AuditEntry, error) {
+	return s.history.History(ctx, id)
+}
+
+// ProjectStore abre (na primeira chamada) os projetos num log ao lado do de tarefas, com
+// o mesmo modo de fsync
+func (s *FileStore) ProjectStore() (ProjectStore, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.projects == nil {
+		projects, err := NewFileProjectStore(s.companionPath("projects"), s.opts.Sync == FileSyncAlways)
+		if err != nil {
+			return nil, err
+		}
+		s.projects = projects
+	}
+	return s.projects, nil
+}
+
+func (s *FileStore) syncLoop() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.opts.SyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			_ = s.log.flush()
+			if s.projects != nil {
+				_ = s.projects.flush()
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// Close para o fsync periódico, faz um último fsync e fecha os logs
+func (s *FileStore) Close() error {
+	if s.stop != nil {
+		close(s.stop)
+		<-s.done
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := closeProjects(s.projects); err != nil {
+		s.log.close()
+		return err
+	}
+	if err := s.log.flush(); err != nil {
+		s.log.close()
+		return fmt.Errorf("failed to sync log: %w", err)
+	}
+	return s.log.close()
+}
+
+// closeProjects fecha os projetos abertos ao lado de um Store em arquivo
+func closeProjects(projects *FileProjectStore) error {
+	if projects == nil {
+		return nil
+	}
+	return projects.Close()
+}
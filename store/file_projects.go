@@ -0,0 +1,72 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"example.com/tasksapi/models"
+)
+
+// projectRecord é uma linha do log de projetos: o estado completo do projeto depois da
+// escrita ou, com Deleted, a remoção dele
+type projectRecord struct {
+	Project *models.Project `json:"project,omitempty"`
+	Deleted string          `json:"deleted,omitempty"`
+}
+
+// FileProjectStore guarda os projetos num log JSONL append-only, como o FileStore faz com
+// as tarefas. O estado fica num MemoryProjectStore; cada escrita é gravada no log antes
+// de valer em memória. Projetos são poucos e o log não é compactado
+type FileProjectStore struct {
+	*MemoryProjectStore
+	log *jsonlLog
+}
+
+// NewFileProjectStore abre (ou cria) o log em path e carrega os projetos. Com sync, cada
+// escrita faz fsync antes de retornar
+func NewFileProjectStore(path string, sync bool) (*FileProjectStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create project dir: %w", err)
+	}
+	s := &FileProjectStore{MemoryProjectStore: NewMemoryProjectStore()}
+	var err error
+	s.log, err = openJSONL(path, sync, func(line []byte) error {
+		var rec projectRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return err
+		}
+		if rec.Project != nil {
+			s.projects[rec.Project.ID] = *rec.Project
+		} else {
+			delete(s.projects, rec.Deleted)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open projects: %w", err)
+	}
+	s.persist = func(put *models.Project, deleted string) error {
+		return s.log.append(projectRecord{Project: put, Deleted: deleted})
+	}
+	return s, nil
+}
+
+// flush faz fsync das escritas gravadas sem sync
+func (s *FileProjectStore) flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.log.flush()
+}
+
+// Close faz fsync e fecha o log
+func (s *FileProjectStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.log.flush(); err != nil {
+		s.log.close()
+		return fmt.Errorf("failed to sync projects: %w", err)
+	}
+	return s.log.close()
+}
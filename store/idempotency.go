@@ -0,0 +1,83 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"example.com/tasksapi/models"
+)
+
+// IdempotencyStore guarda as chaves de Idempotency-Key até ExpiresAt. Registros expirados
+// são tratados como inexistentes, mesmo que o backend ainda não os tenha removido
+type IdempotencyStore interface {
+	// Reserve grava rec como em andamento se a chave estiver livre e retorna nil. Se ela
+	// já existir, nada é gravado e o registro existente é retornado
+	Reserve(ctx context.Context, rec models.IdempotencyRecord) (*models.IdempotencyRecord, error)
+	// Complete grava a resposta da requisição que reservou a chave
+	Complete(ctx context.Context, rec models.IdempotencyRecord) error
+	// Release libera a chave para que a requisição possa ser repetida
+	Release(ctx context.Context, key string) error
+}
+
+// idempotencySweepInterval é o intervalo mínimo entre as limpezas das chaves expiradas em memória
+const idempotencySweepInterval = time.Minute
+
+// MemoryIdempotencyStore é o IdempotencyStore em memória
+type MemoryIdempotencyStore struct {
+	mu        sync.Mutex
+	records   map[string]models.IdempotencyRecord
+	nextSweep time.Time
+}
+
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{records: make(map[string]models.IdempotencyRecord)}
+}
+
+func (s *MemoryIdempotencyStore) Reserve(ctx context.Context, rec models.IdempotencyRecord) (*models.IdempotencyRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now().UTC()
+	s.sweep(now)
+	if existing, ok := s.records[rec.Key]; ok && now.Before(existing.ExpiresAt) {
+		return &existing, nil
+	}
+	s.records[rec.Key] = rec
+	return nil, nil
+}
+
+func (s *MemoryIdempotencyStore) Complete(ctx context.Context, rec models.IdempotencyRecord) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[rec.Key] = rec
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) Release(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+// sweep remove as chaves expiradas; deve ser chamado com o lock
+func (s *MemoryIdempotencyStore) sweep(now time.Time) {
+	if now.Before(s.nextSweep) {
+		return
+	}
+	for key, rec := range s.records {
+		if !now.Before(rec.ExpiresAt) {
+			delete(s.records, key)
+		}
+	}
+	s.nextSweep = now.Add(idempotencySweepInterval)
+}
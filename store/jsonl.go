@@ -0,0 +1,136 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// jsonlLog é um arquivo append-only com um registro JSON por linha
+type jsonlLog struct {
+	f    *os.File
+	size int64
+	// sync faz fsync a cada append; sem ele os dados podem ficar no cache do SO
+	sync bool
+}
+
+// openJSONL abre (ou cria) o log chamando each para cada registro. Uma última linha sem
+// "\n" é resto de uma escrita interrompida por crash e é descartada; uma linha completa
+// inválida indica corrupção e gera erro
+func openJSONL(path string, sync bool, each func(line []byte) error) (*jsonlLog, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log: %w", err)
+	}
+	size, err := readJSONL(f, each)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Truncate(size); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to recover log: %w", err)
+	}
+	if _, err := f.Seek(size, io.SeekStart); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to seek log: %w", err)
+	}
+	return &jsonlLog{f: f, size: size, sync: sync}, nil
+}
+
+// readJSONL lê os registros completos e retorna o tamanho da parte íntegra do arquivo
+func readJSONL(r io.Reader, each func(line []byte) error) (int64, error) {
+	br := bufio.NewReader(r)
+	var size int64
+	for n := 1; ; n++ {
+		line, err := br.ReadBytes('\n')
+		if err == io.EOF {
+			// Sem "\n" final: registro incompleto, ignorado
+			return size, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read log: %w", err)
+		}
+		if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+			if err := each(trimmed); err != nil {
+				return 0, fmt.Errorf("corrupted log at line %d: %w", n, err)
+			}
+		}
+		size += int64(len(line))
+	}
+}
+
+// append grava os registros numa única escrita. Se ela falhar no meio, o arquivo volta ao
+// tamanho anterior para não deixar registro parcial
+func (l *jsonlLog) append(records ...interface{}) error {
+	var buf bytes.Buffer
+	for _, rec := range records {
+		data, err := json.Marshal(rec)
+		if err != nil {
+			return fmt.Errorf("failed to encode log record: %w", err)
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	if _, err := l.f.Write(buf.Bytes()); err != nil {
+		l.rollback()
+		return fmt.Errorf("failed to write log: %w", err)
+	}
+	if l.sync {
+		if err := l.f.Sync(); err != nil {
+			l.rollback()
+			return fmt.Errorf("failed to sync log: %w", err)
+		}
+	}
+	l.size += int64(buf.Len())
+	return nil
+}
+
+// rollback descarta o que foi escrito depois do último append bem-sucedido
+func (l *jsonlLog) rollback() {
+	_ = l.f.Truncate(l.size)
+	_, _ = l.f.Seek(l.size, io.SeekStart)
+}
+
+// flush faz fsync do que já foi escrito; usado quando o fsync não é feito a cada append
+func (l *jsonlLog) flush() error {
+	return l.f.Sync()
+}
+
+func (l *jsonlLog) close() error {
+	return l.f.Close()
+}
+
+// writeFileAtomic grava o arquivo por completo ou não grava: escreve num temporário,
+// faz fsync e renomeia por cima do original
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	// fsync do diretório torna o rename durável
+	if dir, err := os.Open(filepath.Dir(path)); err == nil {
+		_ = dir.Sync()
+		dir.Close()
+	}
+	return nil
+}
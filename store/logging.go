@@ -1,6 +1,7 @@
 package store
 
 import (
+	"context"
 	"time"
 
 	"example.com/tasksapi/models"
@@ -23,23 +24,27 @@ func NewLoggingStore(s Store, logger models.Logger) Store {
 	}
 }
 
-func (l *LoggingStore) Create(t models.Task) models.Task {
+func (l *LoggingStore) Create(ctx context.Context, t models.Task) (models.Task, error) {
 	start := time.Now()
 	l.logger.Info("[STORE] Creating task: title=%s, status=%s", t.Title, t.Status)
 
-	result := l.store.Create(t)
+	result, err := l.store.Create(ctx, t)
 
 	duration := time.Since(start)
-	l.logger.Info("[STORE] Created task: id=%s, duration=%v", result.ID, duration)
+	if err != nil {
+		l.logger.Error("[STORE] Failed to create task: error=%v, duration=%v", err, duration)
+	} else {
+		l.logger.Info("[STORE] Created task: id=%s, duration=%v", result.ID, duration)
+	}
 
-	return result
+	return result, err
 }
 
-func (l *LoggingStore) Get(id string) (models.Task, error) {
+func (l *LoggingStore) Get(ctx context.Context, id string) (models.Task, error) {
 	start := time.Now()
 	l.logger.Info("[STORE] Getting task: id=%s", id)
 
-	result, err := l.store.Get(id)
+	result, err := l.store.Get(ctx, id)
 
 	duration := time.Since(start)
 	if err != nil {
@@ -51,23 +56,43 @@ func (l *LoggingStore) Get(id string) (models.Task, error) {
 	return result, err
 }
 
-func (l *LoggingStore) List() []models.Task {
+func (l *LoggingStore) List(ctx context.Context) ([]models.Task, error) {
 	start := time.Now()
 	l.logger.Info("[STORE] Listing all tasks")
 
-	result := l.store.List()
+	result, err := l.store.List(ctx)
+
+	duration := time.Since(start)
+	if err != nil {
+		l.logger.Error("[STORE] Failed to list tasks: error=%v, duration=%v", err, duration)
+	} else {
+		l.logger.Info("[STORE] Listed %d tasks, duration=%v", len(result), duration)
+	}
+
+	return result, err
+}
+
+func (l *LoggingStore) Query(ctx context.Context, q models.TaskQuery) (models.TaskPage, error) {
+	start := time.Now()
+	l.logger.Info("[STORE] Querying tasks: filters=%d, limit=%d, offset=%d", len(q.Filters), q.Limit, q.Offset)
+
+	result, err := l.store.Query(ctx, q)
 
 	duration := time.Since(start)
-	l.logger.Info("[STORE] Listed %d tasks, duration=%v", len(result), duration)
+	if err != nil {
+		l.logger.Error("[STORE] Failed to query tasks: error=%v, duration=%v", err, duration)
+	} else {
+		l.logger.Info("[STORE] Queried %d of %d tasks, duration=%v", len(result.Tasks), result.Total, duration)
+	}
 
-	return result
+	return result, err
 }
 
-func (l *LoggingStore) Update(id string, patch map[string]interface{}) (models.Task, error) {
+func (l *LoggingStore) Update(ctx context.Context, id string, patch map[string]interface{}) (models.Task, error) {
 	start := time.Now()
 	l.logger.Info("[STORE] Updating task: id=%s, fields=%v", id, getFieldNames(patch))
 
-	result, err := l.store.Update(id, patch)
+	result, err := l.store.Update(ctx, id, patch)
 
 	duration := time.Since(start)
 	if err != nil {
@@ -79,11 +104,49 @@ func (l *LoggingStore) Update(id string, patch map[string]interface{}) (models.T
 	return result, err
 }
 
-func (l *LoggingStore) Delete(id string) error {
+func (l *LoggingStore) UpdateIfVersion(ctx context.Context, id string, version int64, patch map[string]interface{}) (models.Task, error) {
+	start := time.Now()
+	l.logger.Info("[STORE] Updating task: id=%s, version=%d, fields=%v", id, version, getFieldNames(patch))
+
+	result, err := l.store.UpdateIfVersion(ctx, id, version, patch)
+
+	duration := time.Since(start)
+	if err != nil {
+		l.logger.Warn("[STORE] Failed to update task: id=%s, version=%d, error=%v, duration=%v", id, version, err, duration)
+	} else {
+		l.logger.Info("[STORE] Updated task: id=%s, version=%d, duration=%v", id, result.Version, duration)
+	}
+
+	return result, err
+}
+
+func (l *LoggingStore) UpdateWith(ctx context.Context, id string, fn UpdateFunc) (models.Task, error) {
+	start := time.Now()
+	l.logger.Info("[STORE] Updating task with compare-and-set: id=%s", id)
+
+	result, err := l.store.UpdateWith(ctx, id, func(current models.Task) (map[string]interface{}, error) {
+		patch, err := fn(current)
+		if err == nil {
+			l.logger.Info("[STORE] Applying patch: id=%s, version=%d, fields=%v", id, current.Version, getFieldNames(patch))
+		}
+		return patch, err
+	})
+
+	duration := time.Since(start)
+	if err != nil {
+		l.logger.Warn("[STORE] Failed to update task: id=%s, error=%v, duration=%v", id, err, duration)
+	} else {
+		l.logger.Info("[STORE] Updated task: id=%s, version=%d, duration=%v", id, result.Version, duration)
+	}
+
+	return result, err
+}
+
+func (l *LoggingStore) Delete(ctx context.Context, id string) error {
 	start := time.Now()
 	l.logger.Info("[STORE] Deleting task: id=%s", id)
 
-	err := l.store.Delete(id)
+	err := l.store.Delete(ctx, id)
 
 	duration := time.Since(start)
 	if err != nil {
@@ -95,6 +158,206 @@ func (l *LoggingStore) Delete(id string) error {
 	return err
 }
 
+func (l *LoggingStore) DeleteIfVersion(ctx context.Context, id string, version int64) error {
+	start := time.Now()
+	l.logger.Info("[STORE] Deleting task: id=%s, version=%d", id, version)
+
+	err := l.store.DeleteIfVersion(ctx, id, version)
+
+	duration := time.Since(start)
+	if err != nil {
+		l.logger.Warn("[STORE] Failed to delete task: id=%s, version=%d, error=%v, duration=%v", id, version, err, duration)
+	} else {
+		l.logger.Info("[STORE] Deleted task: id=%s, duration=%v", id, duration)
+	}
+
+	return err
+}
+
+func (l *LoggingStore) Restore(ctx context.Context, id string) (models.Task, error) {
+	start := time.Now()
+	l.logger.Info("[STORE] Restoring task: id=%s", id)
+
+	result, err := l.store.Restore(ctx, id)
+
+	duration := time.Since(start)
+	if err != nil {
+		l.logger.Warn("[STORE] Failed to restore task: id=%s, error=%v, duration=%v", id, err, duration)
+	} else {
+		l.logger.Info("[STORE] Restored task: id=%s, duration=%v", id, duration)
+	}
+
+	return result, err
+}
+
+func (l *LoggingStore) Purge(ctx context.Context, id string) error {
+	start := time.Now()
+	l.logger.Info("[STORE] Purging task: id=%s", id)
+
+	err := l.store.Purge(ctx, id)
+
+	duration := time.Since(start)
+	if err != nil {
+		l.logger.Warn("[STORE] Failed to purge task: id=%s, error=%v, duration=%v", id, err, duration)
+	} else {
+		l.logger.Info("[STORE] Purged task: id=%s, duration=%v", id, duration)
+	}
+
+	return err
+}
+
+func (l *LoggingStore) PurgeDeleted(ctx context.Context, before time.Time) (int, error) {
+	start := time.Now()
+	l.logger.Info("[STORE] Purging tasks deleted before %s", before.Format(time.RFC3339))
+
+	count, err := l.store.PurgeDeleted(ctx, before)
+
+	duration := time.Since(start)
+	if err != nil {
+		l.logger.Error("[STORE] Failed to purge deleted tasks: error=%v, duration=%v", err, duration)
+	} else {
+		l.logger.Info("[STORE] Purged deleted tasks: count=%d, duration=%v", count, duration)
+	}
+
+	return count, err
+}
+
+// CreateMany, UpdateMany, DeleteMany e as versões atômicas usam o BulkWriter (ou
+// AtomicBulkWriter) do Store encapsulado quando ele existe e logam o lote como uma operação
+func (l *LoggingStore) CreateMany(ctx context.Context, tasks []models.Task) ([]BulkResult, error) {
+	return l.createMany(ctx, "create", tasks, CreateMany)
+}
+
+func (l *LoggingStore) CreateManyAtomic(ctx context.Context, tasks []models.Task) ([]BulkResult, error) {
+	return l.createMany(ctx, "atomic create", tasks, CreateManyAtomic)
+}
+
+func (l *LoggingStore) UpdateMany(ctx context.Context, ids []string, fn UpdateFunc) ([]BulkResult, error) {
+	return l.updateMany(ctx, "update", ids, fn, UpdateMany)
+}
+
+func (l *LoggingStore) UpdateManyAtomic(ctx context.Context, ids []string, fn UpdateFunc) ([]BulkResult, error) {
+	return l.updateMany(ctx, "atomic update", ids, fn, UpdateManyAtomic)
+}
+
+func (l *LoggingStore) DeleteMany(ctx context.Context, ids []string) ([]BulkResult, error) {
+	return l.deleteMany(ctx, "delete", ids, DeleteMany)
+}
+
+func (l *LoggingStore) DeleteManyAtomic(ctx context.Context, ids []string) ([]BulkResult, error) {
+	return l.deleteMany(ctx, "atomic delete", ids, DeleteManyAtomic)
+}
+
+func (l *LoggingStore) createMany(ctx context.Context, op string, tasks []models.Task,
+	create func(context.Context, Store, []models.Task) ([]BulkResult, error)) ([]BulkResult, error) {
+	start := time.Now()
+	l.logger.Info("[STORE] Bulk %s: count=%d", op, len(tasks))
+
+	results, err := create(ctx, l.store, tasks)
+
+	l.logBulk(op, results, err, time.Since(start))
+	return results, err
+}
+
+func (l *LoggingStore) updateMany(ctx context.Context, op string, ids []string, fn UpdateFunc,
+	update func(context.Context, Store, []string, UpdateFunc) ([]BulkResult, error)) ([]BulkResult, error) {
+	start := time.Now()
+	l.logger.Info("[STORE] Bulk %s: count=%d", op, len(ids))
+
+	results, err := update(ctx, l.store, ids, fn)
+
+	l.logBulk(op, results, err, time.Since(start))
+	return results, err
+}
+
+func (l *LoggingStore) deleteMany(ctx context.Context, op string, ids []string,
+	del func(context.Context, Store, []string) ([]BulkResult, error)) ([]BulkResult, error) {
+	start := time.Now()
+	l.logger.Info("[STORE] Bulk %s: count=%d", op, len(ids))
+
+	results, err := del(ctx, l.store, ids)
+
+	l.logBulk(op, results, err, time.Since(start))
+	return results, err
+}
+
+func (l *LoggingStore) logBulk(op string, results []BulkResult, err error, duration time.Duration) {
+	if err != nil {
+		l.logger.Error("[STORE] Failed bulk %s: error=%v, duration=%v", op, err, duration)
+		return
+	}
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		l.logger.Warn("[STORE] Bulk %s finished: succeeded=%d, failed=%d, duration=%v", op, len(results)-failed, failed, duration)
+	} else {
+		l.logger.Info("[STORE] Bulk %s finished: succeeded=%d, duration=%v", op, len(results), duration)
+	}
+}
+
+// GetAsOf repassa para o Store encapsulado quando ele guarda estados anteriores
+func (l *LoggingStore) GetAsOf(ctx context.Context, id string, at time.Time) (models.Task, error) {
+	r, ok := l.store.(AsOfReader)
+	if !ok {
+		return models.Task{}, ErrAsOfUnavailable
+	}
+	start := time.Now()
+	l.logger.Info("[STORE] Getting task as of %s: id=%s", at.Format(time.RFC3339), id)
+
+	result, err := r.GetAsOf(ctx, id, at)
+
+	duration := time.Since(start)
+	if err != nil {
+		l.logger.Warn("[STORE] Failed to get task as of %s: id=%s, error=%v, duration=%v", at.Format(time.RFC3339), id, err, duration)
+	} else {
+		l.logger.Info("[STORE] Got task as of %s: id=%s, version=%d, duration=%v", at.Format(time.RFC3339), id, result.Version, duration)
+	}
+
+	return result, err
+}
+
+// History repassa para o Store encapsulado quando ele registra histórico
+func (l *LoggingStore) History(ctx context.Context, id string) ([]models.AuditEntry, error) {
+	hr, ok := l.store.(HistoryReader)
+	if !ok {
+		return nil, ErrHistoryUnavailable
+	}
+	start := time.Now()
+	l.logger.Info("[STORE] Reading task history: id=%s", id)
+
+	entries, err := hr.History(ctx, id)
+
+	duration := time.Since(start)
+	if err != nil {
+		l.logger.Error("[STORE] Failed to read task history: id=%s, error=%v, duration=%v", id, err, duration)
+	} else {
+		l.logger.Info("[STORE] Read task history: id=%s, entries=%d, duration=%v", id, len(entries), duration)
+	}
+
+	return entries, err
+}
+
+// TagCounts usa o TagCounter do Store encapsulado quando ele existe
+func (l *LoggingStore) TagCounts(ctx context.Context) ([]models.TagCount, error) {
+	start := time.Now()
+	l.logger.Info("[STORE] Counting tags")
+
+	counts, err := TagCounts(ctx, l.store)
+
+	duration := time.Since(start)
+	if err != nil {
+		l.logger.Error("[STORE] Failed to count tags: error=%v, duration=%v", err, duration)
+	} else {
+		l.logger.Info("[STORE] Counted tags: tags=%d, duration=%v", len(counts), duration)
+	}
+
+	return counts, err
+}
+
 // getFieldNames extrai os nomes dos campos do patch para logging
 func getFieldNames(patch map[string]interface{}) []string {
 	fields := make([]string, 0, len(patch))
@@ -2,6 +2,7 @@ package store
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -12,6 +13,9 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// maxUpdateRetries limita as tentativas do UpdateWith sob escritas concorrentes
+const maxUpdateRetries = 5
+
 type MongoStore struct {
 	client *mongo.Client
 	db     *mongo.Database
@@ -27,26 +31,46 @@ func NewMongo(ctx context.Context, connectionString, dbName, collectionName stri
 
 	// Ping to verify connection
 	if err := client.Ping(ctx, nil); err != nil {
+		_ = client.Disconnect(context.Background())
 		return nil, fmt.Errorf("failed to ping: %w", err)
 	}
 
 	db := client.Database(dbName)
 	col := db.Collection(collectionName)
 
-	return &MongoStore{
+	m := &MongoStore{
 		client: client,
 		db:     db,
 		col:    col,
-	}, nil
+	}
+	// Sem os índices, buscas por id e filtros varrem a coleção inteira
+	if err := m.EnsureIndexes(ctx); err != nil {
+		_ = client.Disconnect(context.Background())
+		return nil, err
+	}
+	return m, nil
 }
 
-func (m *MongoStore) Create(t models.Task) models.Task {
-	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+func (m *MongoStore) Create(ctx context.Context, t models.Task) (models.Task, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
 	defer cancel()
 
-	now := time.Now().UTC()
+	// MongoDB guarda datas com precisão de milissegundos; truncar mantém o retorno igual ao documento
+	t, doc := newTaskDocument(t, time.Now().UTC().Truncate(time.Millisecond))
+	if _, err := m.col.InsertOne(ctx, doc); err != nil {
+		return models.Task{}, fmt.Errorf("failed to insert task: %w", err)
+	}
+
+	return t, nil
+}
+
+// newTaskDocument preenche os campos mantidos pelo Store e monta o documento a inserir
+func newTaskDocument(t models.Task, now time.Time) (models.Task, bson.M) {
 	t.CreatedAt = now
 	t.UpdatedAt = nil
+	t.DeletedAt = nil
+	t.Score = 0
+	t.Version = 1
 
 	oid := primitive.NewObjectID()
 	idHex := oid.Hex()
@@ -58,134 +82,258 @@ func (m *MongoStore) Create(t models.Task) models.Task {
 	}
 
 	doc := bson.M{
-		"_id":         oid,
-		"id":          idHex,
-		"title":       t.Title,
-		"description": t.Description,
-		"status":      t.Status,
-		"priority":    t.Priority,
-		"created_at":  t.CreatedAt,
-		"updated_at":  t.UpdatedAt,
+		"_id":        oid,
+		"id":         idHex,
+		"title":      t.Title,
+		"status":     t.Status,
+		"created_at": t.CreatedAt,
+		"updated_at": t.UpdatedAt,
+		"version":    t.Version,
+	}
+	// Campos opcionais vazios não são gravados, igual a um campo limpo no update;
+	// prioridade vazia assim ordena junto com os nulos
+	if t.Description != "" {
+		doc["description"] = t.Description
+	}
+	if t.Priority != "" {
+		doc["priority"] = t.Priority
 	}
 	if t.DueDate != nil {
 		doc["due_date"] = *t.DueDate
 	}
-
-	_, err := m.col.InsertOne(ctx, doc)
-	if err != nil {
-		return t
+	if t.ParentID != "" {
+		doc["parent_id"] = t.ParentID
 	}
-
-	return t
+	if t.ProjectID != "" {
+		doc["project_id"] = t.ProjectID
+	}
+	if len(t.BlockedBy) > 0 {
+		doc["blocked_by"] = t.BlockedBy
+	}
+	if len(t.Tags) > 0 {
+		doc["tags"] = t.Tags
+	}
+	return t, doc
 }
 
-func (m *MongoStore) List() []models.Task {
-	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+func (m *MongoStore) List(ctx context.Context) ([]models.Task, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
 	defer cancel()
 
-	cursor, err := m.col.Find(ctx, bson.M{})
+	cursor, err := m.col.Find(ctx, bson.M{"deleted_at": nil})
 	if err != nil {
-		return []models.Task{}
+		return nil, fmt.Errorf("failed to list tasks: %w", err)
 	}
 	defer cursor.Close(ctx)
 
-	var tasks []models.Task
+	tasks := []models.Task{}
 	if err = cursor.All(ctx, &tasks); err != nil {
-		return []models.Task{}
+		return nil, fmt.Errorf("failed to decode tasks: %w", err)
 	}
 
-	return tasks
+	return tasks, nil
 }
 
-func (m *MongoStore) Get(id string) (models.Task, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+func (m *MongoStore) Get(ctx context.Context, id string) (models.Task, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
 	defer cancel()
 
 	var t models.Task
-	err := m.col.FindOne(ctx, bson.M{"id": id}).Decode(&t)
+	err := m.col.FindOne(ctx, bson.M{"id": id, "deleted_at": nil}).Decode(&t)
 	if err != nil {
-		return models.Task{}, ErrNotFound
+		return models.Task{}, notFoundOr(err)
 	}
 	return t, nil
 }
 
 // Update apenas para as chaves recebidas
-func (m *MongoStore) Update(id string, patch map[string]interface{}) (models.Task, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+func (m *MongoStore) Update(ctx context.Context, id string, patch map[string]interface{}) (models.Task, error) {
+	return m.update(ctx, id, anyVersion, patch)
+}
+
+func (m *MongoStore) UpdateIfVersion(ctx context.Context, id string, version int64, patch map[string]interface{}) (models.Task, error) {
+	return m.update(ctx, id, version, patch)
+}
+
+// update leva a versão esperada no filtro do FindOneAndUpdate, então checagem e escrita são atômicas
+func (m *MongoStore) update(ctx context.Context, id string, version int64, patch map[string]interface{}) (models.Task, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
 	defer cancel()
 
+	p, err := parsePatch(patch)
+	if err != nil {
+		return models.Task{}, err
+	}
+	changes := updateDocument(p, time.Now().UTC())
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var updated models.Task
+
+	err = m.col.FindOneAndUpdate(
+		ctx,
+		versionFilter(id, version),
+		changes,
+		opts,
+	).Decode(&updated)
+
+	if err != nil {
+		return models.Task{}, m.missOr(ctx, id, version, err)
+	}
+
+	return updated, nil
+}
+
+// updateDocument traduz o patch para os operadores de update e incrementa a versão
+func updateDocument(p taskPatch, now time.Time) bson.M {
 	update := bson.M{}
-	if v, ok := patch["title"]; ok {
-		if s, ok := v.(string); ok {
-			update["title"] = s
-		}
+	for field, v := range p.set {
+		update[field] = v
 	}
-	if v, ok := patch["description"]; ok {
-		if s, ok := v.(string); ok {
-			update["description"] = s
+	update["updated_at"] = now
+	changes := bson.M{"$set": update, "$inc": bson.M{"version": 1}}
+	// Campos limpos são removidos do documento, como Create faz com os vazios
+	if len(p.unset) > 0 {
+		unset := bson.M{}
+		for _, field := range p.unset {
+			unset[field] = ""
 		}
+		changes["$unset"] = unset
 	}
-	if v, ok := patch["status"]; ok {
-		if s, ok := v.(string); ok {
-			update["status"] = s
+	return changes
+}
+
+// UpdateWith lê a tarefa, chama fn e grava com o filtro de versão; se outra escrita
+// acontecer no meio, repete com o estado novo até maxUpdateRetries vezes
+func (m *MongoStore) UpdateWith(ctx context.Context, id string, fn UpdateFunc) (models.Task, error) {
+	for attempt := 0; attempt < maxUpdateRetries; attempt++ {
+		current, err := m.Get(ctx, id)
+		if err != nil {
+			return models.Task{}, err
 		}
-	}
-	if v, ok := patch["priority"]; ok {
-		if s, ok := v.(string); ok {
-			update["priority"] = s
+		patch, err := fn(current)
+		if err != nil {
+			return models.Task{}, err
 		}
-	}
-	if v, ok := patch["due_date"]; ok {
-		switch vv := v.(type) {
-		case models.Date:
-			update["due_date"] = vv
-		case *models.Date:
-			if vv != nil {
-				update["due_date"] = *vv
-			}
-		case string:
-			if parsed, err := models.ParseDateOnly(vv); err == nil {
-				update["due_date"] = parsed
-			}
+		updated, err := m.update(ctx, id, current.Version, patch)
+		if errors.Is(err, ErrVersionConflict) {
+			continue
 		}
+		return updated, err
 	}
+	return models.Task{}, ErrVersionConflict
+}
 
-	now := time.Now().UTC()
-	update["updated_at"] = now
+func (m *MongoStore) Delete(ctx context.Context, id string) error {
+	return m.delete(ctx, id, anyVersion)
+}
 
-	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
-	var updated models.Task
+func (m *MongoStore) DeleteIfVersion(ctx context.Context, id string, version int64) error {
+	return m.delete(ctx, id, version)
+}
 
-	err := m.col.FindOneAndUpdate(
-		ctx,
-		bson.M{"id": id},
-		bson.M{"$set": update},
-		opts,
-	).Decode(&updated)
+func (m *MongoStore) delete(ctx context.Context, id string, version int64) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
 
+	result, err := m.col.UpdateOne(ctx, versionFilter(id, version), bson.M{
+		"$set": bson.M{"deleted_at": time.Now().UTC()},
+		"$inc": bson.M{"version": 1},
+	})
 	if err != nil {
-		return models.Task{}, ErrNotFound
+		return fmt.Errorf("failed to delete task: %w", err)
 	}
 
-	return updated, nil
+	if result.MatchedCount == 0 {
+		return m.missOr(ctx, id, version, mongo.ErrNoDocuments)
+	}
+
+	return nil
 }
 
-func (m *MongoStore) Delete(id string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+func (m *MongoStore) Restore(ctx context.Context, id string) (models.Task, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
 	defer cancel()
 
-	result, err := m.col.DeleteOne(ctx, bson.M{"id": id})
+	var restored models.Task
+	err := m.col.FindOneAndUpdate(
+		ctx,
+		bson.M{"id": id, "deleted_at": bson.M{"$ne": nil}},
+		bson.M{"$unset": bson.M{"deleted_at": ""}, "$inc": bson.M{"version": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&restored)
 	if err != nil {
-		return err
+		return models.Task{}, notFoundOr(err)
 	}
+	return restored, nil
+}
 
+func (m *MongoStore) Purge(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	result, err := m.col.DeleteOne(ctx, bson.M{"id": id})
+	if err != nil {
+		return fmt.Errorf("failed to purge task: %w", err)
+	}
 	if result.DeletedCount == 0 {
 		return ErrNotFound
 	}
-
 	return nil
 }
 
+func (m *MongoStore) PurgeDeleted(ctx context.Context, before time.Time) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	result, err := m.col.DeleteMany(ctx, bson.M{"deleted_at": bson.M{"$ne": nil, "$lt": before}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge deleted tasks: %w", err)
+	}
+	return int(result.DeletedCount), nil
+}
+
+// versionFilter seleciona a tarefa ativa pelo id e, se pedido, pela versão. Documentos
+// gravados antes do controle de versão não têm o campo e equivalem à versão 0
+func versionFilter(id string, version int64) bson.M {
+	filter := bson.M{"id": id, "deleted_at": nil}
+	switch {
+	case version == 0:
+		filter["version"] = bson.M{"$in": bson.A{0, nil}}
+	case version != anyVersion:
+		filter["version"] = version
+	}
+	return filter
+}
+
+// missOr distingue, quando uma escrita condicional não encontrou documento, se a tarefa
+// não existe (ErrNotFound) ou se existe com outra versão (ErrVersionConflict)
+func (m *MongoStore) missOr(ctx context.Context, id string, version int64, err error) error {
+	if !errors.Is(err, mongo.ErrNoDocuments) || version == anyVersion {
+		return notFoundOr(err)
+	}
+	count, countErr := m.col.CountDocuments(ctx, bson.M{"id": id, "deleted_at": nil})
+	if countErr != nil {
+		return fmt.Errorf("mongo: %w", countErr)
+	}
+	if count > 0 {
+		return ErrVersionConflict
+	}
+	return ErrNotFound
+}
+
+// notFoundOr converte ErrNoDocuments em ErrNotFound e preserva os demais erros do driver
+func notFoundOr(err error) error {
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrNotFound
+	}
+	return fmt.Errorf("mongo: %w", err)
+}
+
+// Ping verifica a conexão com o MongoDB
+func (m *MongoStore) Ping(ctx context.Context) error {
+	return m.client.Ping(ctx, nil)
+}
+
 // Close closes the MongoDB connection.
 func (m *MongoStore) Close(ctx context.Context) error {
 	return m.client.Disconnect(ctx)
@@ -0,0 +1,58 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"example.com/tasksapi/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoAuditLog guarda o histórico numa coleção própria, no mesmo banco das tarefas
+type MongoAuditLog struct {
+	col *mongo.Collection
+}
+
+// AuditLog cria (se preciso) a coleção de histórico com índice por tarefa e data
+func (m *MongoStore) AuditLog(ctx context.Context, collectionName string) (AuditLog, error) {
+	col := m.db.Collection(collectionName)
+	_, err := col.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "task_id", Value: 1}, {Key: "at", Value: 1}},
+		Options: options.Index().SetName("history_by_task"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create history index: %w", err)
+	}
+	return &MongoAuditLog{col: col}, nil
+}
+
+func (l *MongoAuditLog) Append(ctx context.Context, entry models.AuditEntry) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	if _, err := l.col.InsertOne(ctx, entry); err != nil {
+		return fmt.Errorf("failed to insert history entry: %w", err)
+	}
+	return nil
+}
+
+func (l *MongoAuditLog) History(ctx context.Context, taskID string) ([]models.AuditEntry, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	// _id desempata entradas gravadas no mesmo milissegundo na ordem de inserção
+	opts := options.Find().SetSort(bson.D{{Key: "at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := l.col.Find(ctx, bson.M{"task_id": taskID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	entries := []models.AuditEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode history: %w", err)
+	}
+	return entries, nil
+}
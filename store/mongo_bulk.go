@@ -0,0 +1,400 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"example.com/tasksapi/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CreateMany insere o lote com um único InsertMany. Sem ordem, um documento rejeitado
+// não impede a inserção dos demais
+func (m *MongoStore) CreateMany(ctx context.Context, tasks []models.Task) ([]BulkResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	results := make([]BulkResult, len(tasks))
+	if len(tasks) == 0 {
+		return results, nil
+	}
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	docs := make([]interface{}, len(tasks))
+	for i, t := range tasks {
+		results[i].Task, docs[i] = newTaskDocument(t, now)
+	}
+
+	_, err := m.col.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	if err := bulkWriteErrors(err, results, nil); err != nil {
+		return nil, fmt.Errorf("failed to insert tasks: %w", err)
+	}
+	for i := range results {
+		if results[i].Err != nil {
+			results[i].Task = models.Task{}
+		}
+	}
+	return results, nil
+}
+
+// UpdateMany lê as tarefas de uma vez, chama fn para cada uma e grava todos os patches
+// num único BulkWrite com o filtro de versão de cada item. Itens que perderam a corrida
+// para outra escrita (ou ids repetidos no lote) são refeitos pelo UpdateWith
+func (m *MongoStore) UpdateMany(ctx context.Context, ids []string, fn UpdateFunc) ([]BulkResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	current, err := m.activeByID(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	// Mesma precisão que o MongoDB grava, para reconhecer as escritas deste lote
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	results := make([]BulkResult, len(ids))
+	var writes []mongo.WriteModel
+	var written, retry []int
+	seen := map[string]bool{}
+	for i, id := range ids {
+		t, ok := current[id]
+		switch {
+		case !ok:
+			results[i] = BulkResult{Task: models.Task{ID: id}, Err: ErrNotFound}
+			continue
+		case seen[id]:
+			retry = append(retry, i)
+			continue
+		}
+		seen[id] = true
+		patch, err := fn(t)
+		if err != nil {
+			results[i] = BulkResult{Task: t, Err: err}
+			continue
+		}
+		p, err := parsePatch(patch)
+		if err != nil {
+			results[i] = BulkResult{Task: t, Err: err}
+			continue
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(versionFilter(id, t.Version)).
+			SetUpdate(updateDocument(p, now)))
+		written = append(written, i)
+	}
+
+	if len(writes) > 0 {
+		_, err := m.col.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+		if err := bulkWriteErrors(err, results, written); err != nil {
+			return nil, fmt.Errorf("failed to update tasks: %w", err)
+		}
+		writtenIDs := make([]string, len(written))
+		for j, i := range written {
+			writtenIDs[j] = ids[i]
+		}
+		after, err := m.activeByID(ctx, writtenIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, i := range written {
+			if results[i].Err != nil {
+				continue
+			}
+			t, ok := after[ids[i]]
+			if ok && t.Version == current[ids[i]].Version+1 && t.UpdatedAt != nil && t.UpdatedAt.Equal(now) {
+				results[i].Task = t
+				continue
+			}
+			retry = append(retry, i)
+		}
+	}
+
+	for _, i := range retry {
+		results[i].Task, results[i].Err = m.UpdateWith(ctx, ids[i], fn)
+		if results[i].Err != nil && results[i].Task.ID == "" {
+			results[i].Task.ID = ids[i]
+		}
+	}
+	return results, nil
+}
+
+// DeleteMany move para a lixeira, com um único UpdateMany, as tarefas ativas do lote
+func (m *MongoStore) DeleteMany(ctx context.Context, ids []string) ([]BulkResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	active, err := m.activeByID(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]BulkResult, len(ids))
+	var found []string
+	seen := map[string]bool{}
+	for i, id := range ids {
+		results[i].Task = models.Task{ID: id}
+		if _, ok := active[id]; !ok || seen[id] {
+			results[i].Err = ErrNotFound
+			continue
+		}
+		seen[id] = true
+		found = append(found, id)
+	}
+	if len(found) == 0 {
+		return results, nil
+	}
+
+	_, err = m.col.UpdateMany(ctx, bson.M{"id": bson.M{"$in": found}, "deleted_at": nil}, bson.M{
+		"$set": bson.M{"deleted_at": time.Now().UTC()},
+		"$inc": bson.M{"version": 1},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete tasks: %w", err)
+	}
+
+	// Relê as tarefas excluídas para devolver a versão gravada; uma tarefa alterada entre a
+	// busca e o UpdateMany sai com a versão real, não com a lida antes
+	cursor, err := m.col.Find(ctx, bson.M{"id": bson.M{"$in": found}, "deleted_at": bson.M{"$ne": nil}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find deleted tasks: %w", err)
+	}
+	defer cursor.Close(ctx)
+	var trashed []models.Task
+	if err := cursor.All(ctx, &trashed); err != nil {
+		return nil, fmt.Errorf("failed to decode tasks: %w", err)
+	}
+	byID := make(map[string]models.Task, len(trashed))
+	for _, t := range trashed {
+		byID[t.ID] = t
+	}
+	for i := range results {
+		if t, ok := byID[results[i].Task.ID]; ok && results[i].Err == nil {
+			results[i].Task = t
+		}
+	}
+	return results, nil
+}
+
+// activeByID busca de uma vez as tarefas ativas com os ids informados
+func (m *MongoStore) activeByID(ctx context.Context, ids []string) (map[string]models.Task, error) {
+	cursor, err := m.col.Find(ctx, bson.M{"id": bson.M{"$in": ids}, "deleted_at": nil})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find tasks: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var tasks []models.Task
+	if err := cursor.All(ctx, &tasks); err != nil {
+		return nil, fmt.Errorf("failed to decode tasks: %w", err)
+	}
+	byID := make(map[string]models.Task, len(tasks))
+	for _, t := range tasks {
+		byID[t.ID] = t
+	}
+	return byID, nil
+}
+
+// bulkWriteErrors distribui os erros de escrita de um InsertMany/BulkWrite pelos itens do
+// lote; positions traduz o índice da operação para o do item (nil quando são iguais).
+// Retorna erro apenas quando o lote inteiro falhou
+func bulkWriteErrors(err error, results []BulkResult, positions []int) error {
+	if err == nil {
+		return nil
+	}
+	var bulkErr mongo.BulkWriteException
+	if !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil {
+		return err
+	}
+	for _, we := range bulkErr.WriteErrors {
+		i := we.Index
+		if positions != nil {
+			i = positions[i]
+		}
+		results[i].Err = fmt.Errorf("mongo: %w", we)
+	}
+	return nil
+}
+
+// errAbortTransaction desfaz a transação de um lote atômico em que algum item falhou
+var errAbortTransaction = errors.New("atomic batch aborted")
+
+// inTransaction roda fn numa transação multi-documento; WithTransaction repete fn em
+// erros transitórios, como conflitos com escritas concorrentes. Transações exigem
+// replica set ou mongos: num servidor standalone o erro é ErrAtomicUnavailable
+func (m *MongoStore) inTransaction(ctx context.Context, fn func(sc mongo.SessionContext) error) error {
+	session, err := m.client.StartSession()
+	if err != nil {
+		return fmt.Errorf("failed to start session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	var se mongo.ServerError
+	if errors.As(err, &se) && se.HasErrorCode(illegalOperationCode) {
+		return fmt.Errorf("%w: %v", ErrAtomicUnavailable, err)
+	}
+	return err
+}
+
+// illegalOperationCode é o código devolvido pelo servidor standalone ao receber uma transação
+const illegalOperationCode = 20
+
+// CreateManyAtomic insere o lote numa transação: se um documento for rejeitado, nenhum fica
+func (m *MongoStore) CreateManyAtomic(ctx context.Context, tasks []models.Task) ([]BulkResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var results []BulkResult
+	err := m.inTransaction(ctx, func(sc mongo.SessionContext) error {
+		results = make([]BulkResult, len(tasks))
+		if len(tasks) == 0 {
+			return nil
+		}
+		now := time.Now().UTC().Truncate(time.Millisecond)
+		docs := make([]interface{}, len(tasks))
+		for i, t := range tasks {
+			results[i].Task, docs[i] = newTaskDocument(t, now)
+		}
+		_, err := m.col.InsertMany(sc, docs)
+		if err := bulkWriteErrors(err, results, nil); err != nil {
+			return err
+		}
+		if abortBatch(results) {
+			return errAbortTransaction
+		}
+		return nil
+	})
+	if errors.Is(err, errAbortTransaction) {
+		for i := range results {
+			results[i].Task = models.Task{}
+		}
+		return results, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert tasks: %w", err)
+	}
+	return results, nil
+}
+
+// UpdateManyAtomic lê as tarefas, chama fn e grava os patches dentro de uma transação.
+// Se fn rejeitar algum item, a transação é desfeita sem gravar nada
+func (m *MongoStore) UpdateManyAtomic(ctx context.Context, ids []string, fn UpdateFunc) ([]BulkResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var results []BulkResult
+	err := m.inTransaction(ctx, func(sc mongo.SessionContext) error {
+		current, err := m.activeByID(sc, ids)
+		if err != nil {
+			return err
+		}
+		now := time.Now().UTC().Truncate(time.Millisecond)
+		results = make([]BulkResult, len(ids))
+		var writes []mongo.WriteModel
+		for i, id := range ids {
+			t, ok := current[id]
+			if !ok {
+				results[i] = BulkResult{Task: models.Task{ID: id}, Err: ErrNotFound}
+				continue
+			}
+			patch, err := fn(t)
+			if err != nil {
+				results[i] = BulkResult{Task: t, Err: err}
+				continue
+			}
+			p, err := parsePatch(patch)
+			if err != nil {
+				results[i] = BulkResult{Task: t, Err: err}
+				continue
+			}
+			writes = append(writes, mongo.NewUpdateOneModel().
+				SetFilter(versionFilter(id, t.Version)).
+				SetUpdate(updateDocument(p, now)))
+			// ids repetidos partem do estado deixado pelo item anterior
+			next := p.apply(t)
+			next.UpdatedAt = &now
+			next.Version++
+			current[id] = next
+			results[i].Task = next
+		}
+		if abortBatch(results) {
+			return errAbortTransaction
+		}
+		if len(writes) == 0 {
+			return nil
+		}
+
+		res, err := m.col.BulkWrite(sc, writes)
+		if err != nil {
+			return err
+		}
+		if res.MatchedCount != int64(len(writes)) {
+			return ErrVersionConflict
+		}
+		after, err := m.activeByID(sc, ids)
+		if err != nil {
+			return err
+		}
+		for i := range results {
+			results[i].Task = after[ids[i]]
+		}
+		return nil
+	})
+	if errors.Is(err, errAbortTransaction) {
+		return results, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update tasks: %w", err)
+	}
+	return results, nil
+}
+
+// DeleteManyAtomic exclui o lote numa transação, só se todas as tarefas existirem
+func (m *MongoStore) DeleteManyAtomic(ctx context.Context, ids []string) ([]BulkResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var results []BulkResult
+	err := m.inTransaction(ctx, func(sc mongo.SessionContext) error {
+		active, err := m.activeByID(sc, ids)
+		if err != nil {
+			return err
+		}
+		results = make([]BulkResult, len(ids))
+		seen := map[string]bool{}
+		now := time.Now().UTC().Truncate(time.Millisecond)
+		for i, id := range ids {
+			results[i].Task = models.Task{ID: id}
+			t, ok := active[id]
+			if !ok || seen[id] {
+				results[i].Err = ErrNotFound
+				continue
+			}
+			seen[id] = true
+			// Dentro da transação a versão lida é a que o UpdateMany incrementa
+			t.DeletedAt = &now
+			t.Version++
+			results[i].Task = t
+		}
+		if abortBatch(results) {
+			return errAbortTransaction
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		_, err = m.col.UpdateMany(sc, bson.M{"id": bson.M{"$in": ids}, "deleted_at": nil}, bson.M{
+			"$set": bson.M{"deleted_at": now},
+			"$inc": bson.M{"version": 1},
+		})
+		return err
+	})
+	if errors.Is(err, errAbortTransaction) {
+		return results, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete tasks: %w", err)
+	}
+	return results, nil
+}
@@ -0,0 +1,80 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"example.com/tasksapi/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoIdempotencyStore guarda as chaves numa coleção própria; o índice TTL em expires_at
+// faz o MongoDB remover as expiradas
+type MongoIdempotencyStore struct {
+	col *mongo.Collection
+}
+
+// IdempotencyStore cria (se preciso) a coleção de chaves com o índice TTL
+func (m *MongoStore) IdempotencyStore(ctx context.Context, collectionName string) (IdempotencyStore, error) {
+	col := m.db.Collection(collectionName)
+	_, err := col.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetName("idempotency_ttl").SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create idempotency index: %w", err)
+	}
+	return &MongoIdempotencyStore{col: col}, nil
+}
+
+// Reserve substitui a chave só se ela estiver expirada; se existir e for válida, o upsert
+// esbarra no _id duplicado e o registro existente é lido
+func (s *MongoIdempotencyStore) Reserve(ctx context.Context, rec models.IdempotencyRecord) (*models.IdempotencyRecord, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	// A chave pode ser liberada ou expirar entre o upsert e a leitura; a segunda tentativa resolve
+	for attempt := 0; attempt < 2; attempt++ {
+		filter := bson.M{"_id": rec.Key, "expires_at": bson.M{"$lte": time.Now().UTC()}}
+		_, err := s.col.ReplaceOne(ctx, filter, rec, options.Replace().SetUpsert(true))
+		if err == nil {
+			return nil, nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+		}
+		var existing models.IdempotencyRecord
+		err = s.col.FindOne(ctx, bson.M{"_id": rec.Key}).Decode(&existing)
+		if err == nil {
+			return &existing, nil
+		}
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("failed to read idempotency key: %w", err)
+		}
+	}
+	return nil, fmt.Errorf("failed to reserve idempotency key %q", rec.Key)
+}
+
+func (s *MongoIdempotencyStore) Complete(ctx context.Context, rec models.IdempotencyRecord) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	if _, err := s.col.ReplaceOne(ctx, bson.M{"_id": rec.Key}, rec, options.Replace().SetUpsert(true)); err != nil {
+		return fmt.Errorf("failed to save idempotent response: %w", err)
+	}
+	return nil
+}
+
+func (s *MongoIdempotencyStore) Release(ctx context.Context, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	if _, err := s.col.DeleteOne(ctx, bson.M{"_id": key}); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
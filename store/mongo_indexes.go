@@ -0,0 +1,130 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"example.com/tasksapi/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// taskIndexes é a lista declarativa dos índices da coleção de tarefas. O nome identifica
+// o índice: para mudar a definição de um deles, dê um nome novo e rode o migrate com
+// -drop-obsolete para remover o antigo
+func taskIndexes() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			// Get, Update e Delete buscam pelo id; o índice único também impede ids repetidos
+			Keys:    bson.D{{Key: "id", Value: 1}},
+			Options: options.Index().SetName("tasks_id").SetUnique(true),
+		},
+		{
+			// Listagem padrão: tarefas ativas (ou a lixeira) por data de criação
+			Keys:    bson.D{{Key: "deleted_at", Value: 1}, {Key: "created_at", Value: 1}, {Key: "id", Value: 1}},
+			Options: options.Index().SetName("tasks_deleted_created"),
+		},
+		{
+			Keys:    bson.D{{Key: "status", Value: 1}, {Key: "deleted_at", Value: 1}, {Key: "created_at", Value: 1}},
+			Options: options.Index().SetName("tasks_status_created"),
+		},
+		{
+			Keys:    bson.D{{Key: "priority", Value: 1}, {Key: "deleted_at", Value: 1}, {Key: "created_at", Value: 1}},
+			Options: options.Index().SetName("tasks_priority_created"),
+		},
+		{
+			Keys:    bson.D{{Key: "due_date", Value: 1}, {Key: "deleted_at", Value: 1}},
+			Options: options.Index().SetName("tasks_due_date"),
+		},
+		{
+			// Subtarefas de uma tarefa (GET /tasks/{id}/subtasks, tree=true e regras de hierarquia)
+			Keys:    bson.D{{Key: "parent_id", Value: 1}, {Key: "deleted_at", Value: 1}, {Key: "created_at", Value: 1}},
+			Options: options.Index().SetName("tasks_parent_created"),
+		},
+		{
+			// Tarefas de um projeto (GET /projects/{id}/tasks, estatísticas e exclusão do projeto)
+			Keys:    bson.D{{Key: "project_id", Value: 1}, {Key: "deleted_at", Value: 1}, {Key: "created_at", Value: 1}},
+			Options: options.Index().SetName("tasks_project_created"),
+		},
+		{
+			// tags é uma lista, então o índice é multikey: uma entrada por tag. Atende
+			// tag=, tag_match=all e a contagem de GET /tags
+			Keys:    bson.D{{Key: "tags", Value: 1}, {Key: "deleted_at", Value: 1}},
+			Options: options.Index().SetName("tasks_tags_deleted"),
+		},
+		textIndex(),
+	}
+}
+
+// textIndex é o índice usado pela busca (q=). A linguagem "none" desliga stemming e stop
+// words para que o resultado bata com o índice do InMemoryStore; a versão 3 do índice já
+// ignora maiúsculas e acentos
+func textIndex() mongo.IndexModel {
+	fields := make([]string, 0, len(models.SearchWeights))
+	for field := range models.SearchWeights {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	keys := bson.D{}
+	weights := bson.M{}
+	for _, field := range fields {
+		keys = append(keys, bson.E{Key: field, Value: "text"})
+		weights[field] = models.SearchWeights[field]
+	}
+	return mongo.IndexModel{
+		Keys: keys,
+		Options: options.Index().
+			SetName("tasks_text_search").
+			SetWeights(weights).
+			SetDefaultLanguage("none").
+			SetTextVersion(3),
+	}
+}
+
+// EnsureIndexes cria os índices de taskIndexes que ainda não existem; os existentes com
+// o mesmo nome e definição são mantidos
+func (m *MongoStore) EnsureIndexes(ctx context.Context) error {
+	if _, err := m.col.Indexes().CreateMany(ctx, taskIndexes()); err != nil {
+		return fmt.Errorf("failed to create indexes: %w", err)
+	}
+	return nil
+}
+
+// Migrate prepara a coleção de tarefas; hoje isso se resume aos índices
+func (m *MongoStore) Migrate(ctx context.Context) error {
+	return m.EnsureIndexes(ctx)
+}
+
+// DropObsoleteIndexes remove os índices da coleção que não estão em taskIndexes (exceto
+// o _id) e retorna os nomes removidos
+func (m *MongoStore) DropObsoleteIndexes(ctx context.Context) ([]string, error) {
+	declared := map[string]struct{}{"_id_": {}}
+	for _, idx := range taskIndexes() {
+		declared[*idx.Options.Name] = struct{}{}
+	}
+
+	cursor, err := m.col.Indexes().List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list indexes: %w", err)
+	}
+	var existing []struct {
+		Name string `bson:"name"`
+	}
+	if err := cursor.All(ctx, &existing); err != nil {
+		return nil, fmt.Errorf("failed to decode indexes: %w", err)
+	}
+
+	dropped := []string{}
+	for _, idx := range existing {
+		if _, ok := declared[idx.Name]; ok {
+			continue
+		}
+		if _, err := m.col.Indexes().DropOne(ctx, idx.Name); err != nil {
+			return dropped, fmt.Errorf("failed to drop index %s: %w", idx.Name, err)
+		}
+		dropped = append(dropped, idx.Name)
+	}
+	return dropped, nil
+}
@@ -0,0 +1,125 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"example.com/tasksapi/models"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoProjectStore guarda os projetos numa coleção própria, com o id em _id
+type MongoProjectStore struct {
+	col *mongo.Collection
+}
+
+// ProjectStore cria (se preciso) a coleção de projetos com o índice da listagem
+func (m *MongoStore) ProjectStore(ctx context.Context, collectionName string) (ProjectStore, error) {
+	col := m.db.Collection(collectionName)
+	_, err := col.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}},
+		Options: options.Index().SetName("projects_created"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create project index: %w", err)
+	}
+	return &MongoProjectStore{col: col}, nil
+}
+
+func (s *MongoProjectStore) Create(ctx context.Context, p models.Project) (models.Project, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	p.ID = uuid.New().String()
+	p.CreatedAt = time.Now().UTC().Truncate(time.Millisecond)
+	p.UpdatedAt = nil
+	p.Version = 1
+	if _, err := s.col.InsertOne(ctx, p); err != nil {
+		return models.Project{}, fmt.Errorf("failed to insert project: %w", err)
+	}
+	return p, nil
+}
+
+func (s *MongoProjectStore) Get(ctx context.Context, id string) (models.Project, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var p models.Project
+	if err := s.col.FindOne(ctx, bson.M{"_id": id}).Decode(&p); err != nil {
+		return models.Project{}, projectNotFoundOr(err)
+	}
+	return p, nil
+}
+
+func (s *MongoProjectStore) List(ctx context.Context) ([]models.Project, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	cursor, err := s.col.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list projects: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	projects := []models.Project{}
+	if err := cursor.All(ctx, &projects); err != nil {
+		return nil, fmt.Errorf("failed to decode projects: %w", err)
+	}
+	return projects, nil
+}
+
+// Update grava com a versão lida no filtro; se outra escrita acontecer no meio, repete
+// com o estado novo até maxUpdateRetries vezes, como MongoStore.UpdateWith
+func (s *MongoProjectStore) Update(ctx context.Context, id string, fn ProjectUpdateFunc) (models.Project, error) {
+	for attempt := 0; attempt < maxUpdateRetries; attempt++ {
+		current, err := s.Get(ctx, id)
+		if err != nil {
+			return models.Project{}, err
+		}
+		updated, err := fn(current)
+		if err != nil {
+			return models.Project{}, err
+		}
+		now := time.Now().UTC().Truncate(time.Millisecond)
+		updated.ID, updated.CreatedAt, updated.UpdatedAt = current.ID, current.CreatedAt, &now
+		updated.Version = current.Version + 1
+
+		ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+		result, err := s.col.ReplaceOne(ctx, bson.M{"_id": id, "version": current.Version}, updated)
+		cancel()
+		if err != nil {
+			return models.Project{}, fmt.Errorf("failed to update project: %w", err)
+		}
+		if result.MatchedCount == 1 {
+			return updated, nil
+		}
+	}
+	return models.Project{}, ErrVersionConflict
+}
+
+func (s *MongoProjectStore) Delete(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	result, err := s.col.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return fmt.Errorf("failed to delete project: %w", err)
+	}
+	if result.DeletedCount == 0 {
+		return ErrProjectNotFound
+	}
+	return nil
+}
+
+// projectNotFoundOr converte ErrNoDocuments em ErrProjectNotFound, como notFoundOr
+func projectNotFoundOr(err error) error {
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrProjectNotFound
+	}
+	return fmt.Errorf("mongo: %w", err)
+}
@@ -0,0 +1,269 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"example.com/tasksapi/models"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Query roda como pipeline de agregação: cada chave de ordenação vira um par de campos
+// calculados (_nI indica nulo, _sI guarda o valor comparável) para que prioridade ordene
+// pelo significado e nulos fiquem no início ou no fim, exatamente como no InMemoryStore
+func (m *MongoStore) Query(ctx context.Context, q models.TaskQuery) (models.TaskPage, error) {
+	if err := q.Validate(); err != nil {
+		return models.TaskPage{}, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	filter, err := mongoFilter(q.Filters)
+	if err != nil {
+		return models.TaskPage{}, err
+	}
+	// Tarefas ativas não têm deleted_at; a lixeira é o contrário
+	filter["deleted_at"] = nil
+	if q.Deleted {
+		filter["deleted_at"] = bson.M{"$ne": nil}
+	}
+	if q.Search != "" {
+		// Termos normalizados evitam que aspas e "-" virem operadores do $text
+		filter["$text"] = bson.M{"$search": strings.Join(models.SearchTerms(q.Search), " ")}
+	}
+
+	total, err := m.col.CountDocuments(ctx, filter)
+	if err != nil {
+		return models.TaskPage{}, fmt.Errorf("failed to count tasks: %w", err)
+	}
+
+	keys := q.SortKeys()
+	pipeline := []bson.M{{"$match": filter}}
+	if q.Search != "" {
+		pipeline = append(pipeline, bson.M{"$addFields": bson.M{"score": bson.M{"$meta": "textScore"}}})
+	}
+	pipeline = append(pipeline, bson.M{"$addFields": mongoSortFields(keys, q.NullsFirst)})
+	if q.After != nil {
+		pipeline = append(pipeline, bson.M{"$match": mongoAfter(keys, q.After, q.NullsFirst)})
+	}
+	pipeline = append(pipeline, bson.M{"$sort": mongoSort(keys)})
+	if q.Offset > 0 {
+		pipeline = append(pipeline, bson.M{"$skip": q.Offset})
+	}
+	if q.Limit > 0 {
+		// Um documento a mais indica se existe próxima página
+		pipeline = append(pipeline, bson.M{"$limit": q.Limit + 1})
+	}
+	pipeline = append(pipeline, bson.M{"$project": mongoSortProjection(keys)})
+
+	cursor, err := m.col.Aggregate(ctx, pipeline)
+	if err != nil {
+		return models.TaskPage{}, fmt.Errorf("failed to query tasks: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	tasks := []models.Task{}
+	if err := cursor.All(ctx, &tasks); err != nil {
+		return models.TaskPage{}, fmt.Errorf("failed to decode tasks: %w", err)
+	}
+
+	return models.NewTaskPage(tasks, int(total), q), nil
+}
+
+// mongoFilter traduz os filtros do TaskQuery para um documento de filtro do MongoDB.
+// Os valores passam por models.FilterValue, a mesma conversão usada pelo InMemoryStore
+func mongoFilter(filters []models.TaskFilter) (bson.M, error) {
+	conds := bson.A{}
+	for _, f := range filters {
+		cond, err := mongoCondition(f)
+		if err != nil {
+			return nil, err
+		}
+		conds = append(conds, bson.M{f.Field: cond})
+	}
+	if len(conds) == 0 {
+		return bson.M{}, nil
+	}
+	return bson.M{"$and": conds}, nil
+}
+
+// mongoNulls cobre campos vazios: ausentes, nulos ou gravados como string vazia
+var mongoNulls = bson.A{nil, ""}
+
+var mongoRangeOps = map[models.FilterOp]string{
+	models.FilterLt:  "$lt",
+	models.FilterLte: "$lte",
+	models.FilterGt:  "$gt",
+	models.FilterGte: "$gte",
+}
+
+// mongoCondition traduz um filtro. Em campos de lista (tags) o MongoDB já compara cada
+// elemento, então igualdade, $in e $nin têm a semântica de models.TaskFilter.Match
+func mongoCondition(f models.TaskFilter) (interface{}, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	switch f.Op {
+	case models.FilterIsNull:
+		return bson.M{"$in": mongoNulls}, nil
+	case models.FilterNotNull:
+		return bson.M{"$nin": mongoNulls}, nil
+	case models.FilterIn, models.FilterNotIn:
+		values := bson.A{}
+		for _, raw := range f.Values {
+			if raw == models.NullLiteral {
+				values = append(values, mongoNulls...)
+				continue
+			}
+			v, _ := models.FilterValue(f.Field, raw)
+			values = append(values, v)
+		}
+		if f.Op == models.FilterNotIn {
+			return bson.M{"$nin": values}, nil
+		}
+		return bson.M{"$in": values}, nil
+	case models.FilterAll:
+		values := bson.A{}
+		for _, raw := range f.Values {
+			v, _ := models.FilterValue(f.Field, raw)
+			values = append(values, v)
+		}
+		return bson.M{"$all": values}, nil
+	case models.FilterEq:
+		v, _ := models.FilterValue(f.Field, f.Value)
+		return v, nil
+	}
+	op, ok := mongoRangeOps[f.Op]
+	if !ok {
+		return nil, fmt.Errorf("unsupported filter operator: %s", f.Op)
+	}
+	// Operadores de comparação do MongoDB só casam valores do mesmo tipo, então nulos ficam de fora
+	v, _ := models.FilterValue(f.Field, f.Value)
+	return bson.M{op: v}, nil
+}
+
+func sortValueField(i int) string { return "_s" + strconv.Itoa(i) }
+func sortNullField(i int) string  { return "_n" + strconv.Itoa(i) }
+
+// mongoSortValue é a expressão equivalente a models.SortValue
+func mongoSortValue(field string) interface{} {
+	if field == "priority" {
+		branches := bson.A{}
+		for p, rank := range models.PriorityRanks {
+			branches = append(branches, bson.M{"case": bson.M{"$eq": bson.A{"$priority", p}}, "then": rank})
+		}
+		return bson.M{"$switch": bson.M{"branches": branches, "default": nil}}
+	}
+	return bson.M{"$ifNull": bson.A{"$" + field, nil}}
+}
+
+// nullRank posiciona nulos antes (0) ou depois (1) dos valores presentes
+func nullRank(isNull, nullsFirst bool) int {
+	if isNull == nullsFirst {
+		return 0
+	}
+	return 1
+}
+
+func mongoSortFields(keys []models.SortKey, nullsFirst bool) bson.M {
+	fields := bson.M{}
+	for i, k := range keys {
+		value := mongoSortValue(k.Field)
+		fields[sortValueField(i)] = value
+		fields[sortNullField(i)] = bson.M{"$cond": bson.A{
+			bson.M{"$eq": bson.A{value, nil}},
+			nullRank(true, nullsFirst),
+			nullRank(false, nullsFirst),
+		}}
+	}
+	return fields
+}
+
+func mongoSort(keys []models.SortKey) bson.D {
+	sort := bson.D{}
+	for i, k := range keys {
+		dir := 1
+		if k.Desc {
+			dir = -1
+		}
+		sort = append(sort, bson.E{Key: sortNullField(i), Value: 1}, bson.E{Key: sortValueField(i), Value: dir})
+	}
+	return sort
+}
+
+func mongoSortProjection(keys []models.SortKey) bson.M {
+	projection := bson.M{}
+	for i := range keys {
+		projection[sortValueField(i)] = 0
+		projection[sortNullField(i)] = 0
+	}
+	return projection
+}
+
+// mongoAfter monta a condição de keyset: documentos estritamente depois da posição do cursor.
+// Para chaves (k1, k2, ...) isso é k1 > v1 OU (k1 = v1 E k2 > v2) OU ...
+func mongoAfter(keys []models.SortKey, after []interface{}, nullsFirst bool) bson.M {
+	branches := bson.A{}
+	for i, k := range keys {
+		and := bson.A{}
+		for j := 0; j < i; j++ {
+			and = append(and, bson.M{
+				sortNullField(j):  nullRank(after[j] == nil, nullsFirst),
+				sortValueField(j): after[j],
+			})
+		}
+		branches = append(branches, bson.M{"$and": append(and, mongoBeyond(i, k, after[i], nullsFirst))})
+	}
+	return bson.M{"$or": branches}
+}
+
+// mongoBeyond seleciona o que vem estritamente depois de value na chave i:
+// um grupo de nulidade posterior ou, no mesmo grupo, um valor posterior na direção da chave
+func mongoBeyond(i int, k models.SortKey, value interface{}, nullsFirst bool) bson.M {
+	rank := nullRank(value == nil, nullsFirst)
+	later := bson.M{sortNullField(i): bson.M{"$gt": rank}}
+	if value == nil {
+		return later
+	}
+	op := "$gt"
+	if k.Desc {
+		op = "$lt"
+	}
+	return bson.M{"$or": bson.A{
+		later,
+		bson.M{sortNullField(i): rank, sortValueField(i): bson.M{op: value}},
+	}}
+}
+
+// TagCounts desdobra as tags das tarefas ativas ($unwind) e conta cada uma; o índice
+// multikey tasks_tags_deleted atende o $match
+func (m *MongoStore) TagCounts(ctx context.Context) ([]models.TagCount, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	cursor, err := m.col.Aggregate(ctx, []bson.M{
+		{"$match": bson.M{"deleted_at": nil, "tags": bson.M{"$exists": true}}},
+		{"$unwind": "$tags"},
+		{"$group": bson.M{"_id": "$tags", "count": bson.M{"$sum": 1}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tags: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var groups []struct {
+		Tag   string `bson:"_id"`
+		Count int    `bson:"count"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, fmt.Errorf("failed to decode tag counts: %w", err)
+	}
+	counts := make([]models.TagCount, len(groups))
+	for i, g := range groups {
+		counts[i] = models.TagCount{Tag: g.Tag, Count: g.Count}
+	}
+	models.SortTagCounts(counts)
+	return counts, nil
+}
@@ -0,0 +1,157 @@
+package store
+
+import (
+	"time"
+
+	"example.com/tasksapi/models"
+)
+
+// taskPatch é o patch convertido para os tipos do Store: set guarda os valores a gravar
+// e unset os campos a limpar (nil no patch). Os dois backends usam a mesma conversão,
+// então aceitam e rejeitam exatamente os mesmos patches
+type taskPatch struct {
+	set   map[string]interface{}
+	unset []string
+}
+
+// parsePatch valida os tipos do patch. Só description, priority, due_date, parent_id,
+// project_id, blocked_by e tags podem ser limpos
+func parsePatch(patch map[string]interface{}) (taskPatch, error) {
+	p := taskPatch{set: map[string]interface{}{}}
+	for field, v := range patch {
+		switch field {
+		case "title", "status", "description", "priority", "parent_id", "project_id":
+			switch vv := v.(type) {
+			case nil:
+				if field == "title" || field == "status" {
+					return taskPatch{}, models.NewValidationError(field + " cannot be cleared")
+				}
+				p.unset = append(p.unset, field)
+			case string:
+				p.set[field] = vv
+			default:
+				return taskPatch{}, models.NewValidationError(field + " must be a string")
+			}
+		case "blocked_by", "tags":
+			list, err := patchList(field, v)
+			if err != nil {
+				return taskPatch{}, err
+			}
+			if len(list) > 0 {
+				p.set[field] = list
+			} else {
+				p.unset = append(p.unset, field)
+			}
+		case "due_date":
+			date, ok, err := patchDate(v)
+			if err != nil {
+				return taskPatch{}, err
+			}
+			if ok {
+				p.set[field] = date
+			} else {
+				p.unset = append(p.unset, field)
+			}
+		default:
+			return taskPatch{}, models.NewValidationError("unknown field: " + field)
+		}
+	}
+	return p, nil
+}
+
+// patchDate converte os formatos aceitos de due_date; ok é falso quando o campo deve ser limpo
+func patchDate(v interface{}) (models.Date, bool, error) {
+	switch vv := v.(type) {
+	case nil:
+		return models.Date{}, false, nil
+	case models.Date:
+		return vv, !vv.IsZero(), nil
+	case *models.Date:
+		if vv == nil {
+			return models.Date{}, false, nil
+		}
+		return *vv, !vv.IsZero(), nil
+	case time.Time:
+		return models.NewDate(vv.Year(), vv.Month(), vv.Day()), !vv.IsZero(), nil
+	case *time.Time:
+		if vv == nil {
+			return models.Date{}, false, nil
+		}
+		return patchDate(*vv)
+	case string:
+		parsed, err := models.ParseDateOnly(vv)
+		if err != nil {
+			return models.Date{}, false, models.NewValidationError("invalid date format, expected YYYY-MM-DD")
+		}
+		return parsed, true, nil
+	}
+	return models.Date{}, false, models.NewValidationError("due_date must be a YYYY-MM-DD string or date")
+}
+
+// patchList converte uma lista de strings; []interface{} aparece quando o valor vem de
+// JSON (eventos relidos do log). Lista vazia ou nil limpa o campo
+func patchList(field string, v interface{}) ([]string, error) {
+	switch vv := v.(type) {
+	case nil:
+		return nil, nil
+	case []string:
+		return append([]string(nil), vv...), nil
+	case []interface{}:
+		list := make([]string, len(vv))
+		for i, item := range vv {
+			s, ok := item.(string)
+			if !ok {
+				return nil, models.NewValidationError(field + " must be a list of strings")
+			}
+			list[i] = s
+		}
+		return list, nil
+	}
+	return nil, models.NewValidationError(field + " must be a list of strings")
+}
+
+// apply grava o patch sobre a tarefa
+func (p taskPatch) apply(t models.Task) models.Task {
+	for field, v := range p.set {
+		switch field {
+		case "title":
+			t.Title = v.(string)
+		case "description":
+			t.Description = v.(string)
+		case "status":
+			t.Status = v.(string)
+		case "priority":
+			t.Priority = v.(string)
+		case "parent_id":
+			t.ParentID = v.(string)
+		case "project_id":
+			t.ProjectID = v.(string)
+		case "blocked_by":
+			t.BlockedBy = v.([]string)
+		case "tags":
+			t.Tags = v.([]string)
+		case "due_date":
+			date := v.(models.Date)
+			t.DueDate = &date
+		}
+	}
+	for _, field := range p.unset {
+		switch field {
+		case "description":
+			t.Description = ""
+		case "priority":
+			t.Priority = ""
+		case "parent_id":
+			t.ParentID = ""
+		case "project_id":
+			t.ProjectID = ""
+		case "blocked_by":
+			t.BlockedBy = nil
+		case "tags":
+			t.Tags = nil
+		case "due_date":
+			t.DueDate = nil
+		}
+	}
+	return t
+}
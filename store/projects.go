@@ -0,0 +1,157 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"example.com/tasksapi/models"
+	"github.com/google/uuid"
+)
+
+var ErrProjectNotFound = errors.New("project not found")
+
+// ProjectStore guarda os projetos. As tarefas referenciam o projeto por Task.ProjectID e
+// continuam no Store de tarefas
+type ProjectStore interface {
+	// Create gera o id, a data de criação e a versão 1
+	Create(ctx context.Context, p models.Project) (models.Project, error)
+	Get(ctx context.Context, id string) (models.Project, error)
+	// List retorna todos os projetos, arquivados ou não, por data de criação
+	List(ctx context.Context) ([]models.Project, error)
+	// Update lê o projeto, chama fn e grava o resultado com a versão incrementada, sem
+	// escritas concorrentes no meio. Um erro de fn cancela a escrita
+	Update(ctx context.Context, id string, fn ProjectUpdateFunc) (models.Project, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// ProjectUpdateFunc recebe o projeto armazenado e devolve a nova versão dele; id, criação e
+// versão são mantidos pelo ProjectStore
+type ProjectUpdateFunc func(current models.Project) (models.Project, error)
+
+// MemoryProjectStore é o ProjectStore em memória
+type MemoryProjectStore struct {
+	mu       sync.RWMutex
+	projects map[string]models.Project
+	// persist, quando definido, grava a alteração antes de ela valer em memória
+	// (FileProjectStore); um erro cancela a escrita. deleted é o id removido
+	persist func(put *models.Project, deleted string) error
+}
+
+func NewMemoryProjectStore() *MemoryProjectStore {
+	return &MemoryProjectStore{projects: make(map[string]models.Project)}
+}
+
+func (s *MemoryProjectStore) Create(ctx context.Context, p models.Project) (models.Project, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Project{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p.ID = uuid.New().String()
+	p.CreatedAt = time.Now().UTC()
+	p.UpdatedAt = nil
+	p.Version = 1
+	if err := s.save(&p, ""); err != nil {
+		return models.Project{}, err
+	}
+	s.projects[p.ID] = p
+	return p, nil
+}
+
+func (s *MemoryProjectStore) Get(ctx context.Context, id string) (models.Project, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Project{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.projects[id]
+	if !ok {
+		return models.Project{}, ErrProjectNotFound
+	}
+	return p, nil
+}
+
+func (s *MemoryProjectStore) List(ctx context.Context) ([]models.Project, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	out := make([]models.Project, 0, len(s.projects))
+	for _, p := range s.projects {
+		out = append(out, p)
+	}
+	s.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.Before(out[j].CreatedAt)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out, nil
+}
+
+func (s *MemoryProjectStore) Update(ctx context.Context, id string, fn ProjectUpdateFunc) (models.Project, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Project{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, ok := s.projects[id]
+	if !ok {
+		return models.Project{}, ErrProjectNotFound
+	}
+	updated, err := fn(current)
+	if err != nil {
+		return models.Project{}, err
+	}
+	now := time.Now().UTC()
+	updated.ID, updated.CreatedAt, updated.UpdatedAt = current.ID, current.CreatedAt, &now
+	updated.Version = current.Version + 1
+	if err := s.save(&updated, ""); err != nil {
+		return models.Project{}, err
+	}
+	s.projects[id] = updated
+	return updated, nil
+}
+
+func (s *MemoryProjectStore) Delete(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.projects[id]; !ok {
+		return ErrProjectNotFound
+	}
+	if err := s.save(nil, id); err != nil {
+		return err
+	}
+	delete(s.projects, id)
+	return nil
+}
+
+// save repassa a alteração para persist; deve ser chamado com o lock de escrita
+func (s *MemoryProjectStore) save(put *models.Project, deleted string) error {
+	if s.persist == nil {
+		return nil
+	}
+	return s.persist(put, deleted)
+}
+
+// ArchivedProjectIDs retorna os ids dos projetos arquivados
+func ArchivedProjectIDs(ctx context.Context, ps ProjectStore) ([]string, error) {
+	projects, err := ps.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, p := range projects {
+		if p.Archived() {
+			ids = append(ids, p.ID)
+		}
+	}
+	return ids, nil
+}
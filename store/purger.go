@@ -0,0 +1,45 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"example.com/tasksapi/models"
+)
+
+// StartPurger remove, a cada interval, as tarefas que estão na lixeira há mais de
+// retention. Roda em background até ctx ser cancelado; com interval <= 0 a purga fica
+// desligada
+func StartPurger(ctx context.Context, s Store, retention, interval time.Duration, logger models.Logger) {
+	if logger == nil {
+		logger = models.NewDefaultLogger()
+	}
+	if interval <= 0 {
+		logger.Warn("trash purge interval must be positive, got %v: purger disabled", interval)
+		return
+	}
+	purge := func() {
+		count, err := s.PurgeDeleted(ctx, time.Now().UTC().Add(-retention))
+		if err != nil {
+			logger.Error("failed to purge trash: %v", err)
+			return
+		}
+		if count > 0 {
+			logger.Info("purged %d task(s) deleted more than %v ago", count, retention)
+		}
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		purge()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				purge()
+			}
+		}
+	}()
+}
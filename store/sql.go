@@ -0,0 +1,625 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/tasksapi/models"
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+)
+
+// sqlDialect guarda as diferenças entre os bancos suportados
+type sqlDialect struct {
+	// timestamp e float são os tipos das colunas de data e hora e de ponto flutuante
+	timestamp string
+	float     string
+	// serial é a chave inteira gerada pelo banco, na ordem de inserção
+	serial string
+	// numbered troca os "?" por $1, $2... (PostgreSQL)
+	numbered bool
+	// collate força a comparação de texto byte a byte, como strings.Compare no InMemoryStore
+	collate string
+	// forUpdate trava a linha lida no UpdateWith. O SQLite não precisa: ele usa uma única
+	// conexão, então a transação já é exclusiva
+	forUpdate string
+}
+
+var sqlDialects = map[string]sqlDialect{
+	"postgres": {timestamp: "TIMESTAMPTZ", float: "DOUBLE PRECISION", serial: "BIGSERIAL PRIMARY KEY", numbered: true, collate: ` COLLATE "C"`, forUpdate: " FOR UPDATE"},
+	"sqlite":   {timestamp: "TIMESTAMP", float: "REAL", serial: "INTEGER PRIMARY KEY AUTOINCREMENT"},
+}
+
+// rebind converte os placeholders "?" para o formato do banco
+func (d sqlDialect) rebind(query string) string {
+	if !d.numbered {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+const sqlTaskColumns = "id, title, description, status, priority, due_date, created_at, updated_at, deleted_at, version, parent_id, blocked_by, tags, project_id"
+
+// SQLStore guarda as tarefas num banco SQL via database/sql: PostgreSQL em produção ou
+// SQLite para rodar sem servidor. due_date é DATE e campos opcionais vazios são NULL
+type SQLStore struct {
+	db      *sql.DB
+	dialect sqlDialect
+}
+
+// NewSQL abre o banco com o driver "postgres" ou "sqlite" e aplica as migrações pendentes
+func NewSQL(ctx context.Context, driver, dsn string) (*SQLStore, error) {
+	dialect, ok := sqlDialects[driver]
+	if !ok {
+		return nil, fmt.Errorf("unsupported sql driver %q, allowed: postgres, sqlite", driver)
+	}
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	if driver == "sqlite" {
+		// O SQLite aceita um escritor por vez; uma conexão só evita SQLITE_BUSY
+		db.SetMaxOpenConns(1)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping: %w", err)
+	}
+	s := &SQLStore{db: db, dialect: dialect}
+	if err := s.Migrate(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// sqlDB é o que SQLStore usa tanto do *sql.DB quanto de uma *sql.Tx
+type sqlDB interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// sqlDate lê DATE, que cada driver devolve como time.Time ou texto
+type sqlDate struct {
+	date  models.Date
+	valid bool
+}
+
+func (d *sqlDate) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		d.valid = false
+		return nil
+	case time.Time:
+		d.date, d.valid = models.NewDate(v.Year(), v.Month(), v.Day()), true
+		return nil
+	case []byte:
+		return d.Scan(string(v))
+	case string:
+		// Alguns drivers devolvem a data com hora ("2026-05-01 00:00:00")
+		if len(v) > len(models.DateFormat) {
+			v = v[:len(models.DateFormat)]
+		}
+		parsed, err := models.ParseDateOnly(v)
+		if err != nil {
+			return fmt.Errorf("invalid date %q: %w", v, err)
+		}
+		d.date, d.valid = parsed, true
+		return nil
+	}
+	return fmt.Errorf("unsupported date type %T", src)
+}
+
+// scanTask lê as colunas de sqlTaskColumns seguidas de extra
+func scanTask(row rowScanner, extra ...interface{}) (models.Task, error) {
+	var (
+		t                                                     models.Task
+		description, priority, parent, blocked, tags, project sql.NullString
+		due                                                   sqlDate
+		updated, deleted                                      sql.NullTime
+	)
+	dest := append([]interface{}{&t.ID, &t.Title, &description, &t.Status, &priority, &due, &t.CreatedAt, &updated, &deleted, &t.Version, &parent, &blocked, &tags, &project}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return models.Task{}, err
+	}
+	t.Description, t.Priority, t.ParentID, t.ProjectID = description.String, priority.String, parent.String, project.String
+	if blocked.Valid {
+		if err := json.Unmarshal([]byte(blocked.String), &t.BlockedBy); err != nil {
+			return models.Task{}, fmt.Errorf("invalid blocked_by %q: %w", blocked.String, err)
+		}
+	}
+	if tags.Valid {
+		if err := json.Unmarshal([]byte(tags.String), &t.Tags); err != nil {
+			return models.Task{}, fmt.Errorf("invalid tags %q: %w", tags.String, err)
+		}
+	}
+	t.CreatedAt = t.CreatedAt.UTC()
+	if due.valid {
+		t.DueDate = &due.date
+	}
+	if updated.Valid {
+		at := updated.Time.UTC()
+		t.UpdatedAt = &at
+	}
+	if deleted.Valid {
+		at := deleted.Time.UTC()
+		t.DeletedAt = &at
+	}
+	return t, nil
+}
+
+// sqlValue converte o valor do campo para a coluna: texto vazio, listas vazias e datas
+// ausentes viram NULL; listas são gravadas como JSON
+func sqlValue(field string, v interface{}) interface{} {
+	switch vv := v.(type) {
+	case []string:
+		if len(vv) == 0 {
+			return nil
+		}
+		raw, _ := json.Marshal(vv)
+		return string(raw)
+	case string:
+		if vv == "" && (field == "description" || field == "priority" || field == "parent_id" || field == "project_id") {
+			return nil
+		}
+	case models.Date:
+		return vv.Format(models.DateFormat)
+	case *models.Date:
+		if vv == nil || vv.IsZero() {
+			return nil
+		}
+		return vv.Format(models.DateFormat)
+	case time.Time:
+		if field == "due_date" {
+			return vv.Format(models.DateFormat)
+		}
+		return vv.UTC()
+	}
+	return v
+}
+
+// sqlNow usa a precisão de microssegundos do PostgreSQL, para que o retorno seja igual à linha
+func sqlNow() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+func (s *SQLStore) Create(ctx context.Context, t models.Task) (models.Task, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	t.ID = uuid.New().String()
+	if t.DueDate != nil && t.DueDate.IsZero() {
+		t.DueDate = nil
+	}
+	t.CreatedAt = sqlNow()
+	t.UpdatedAt = nil
+	t.DeletedAt = nil
+	t.Score = 0
+	t.Version = 1
+
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, s.dialect.rebind(`INSERT INTO tasks (`+sqlTaskColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, NULL, NULL, ?, ?, ?, ?, ?)`),
+			t.ID, t.Title, sqlValue("description", t.Description), t.Status, sqlValue("priority", t.Priority),
+			sqlValue("due_date", t.DueDate), t.CreatedAt, t.Version, sqlValue("parent_id", t.ParentID), sqlValue("blocked_by", t.BlockedBy),
+			sqlValue("tags", t.Tags), sqlValue("project_id", t.ProjectID))
+		if err != nil {
+			return fmt.Errorf("failed to insert task: %w", err)
+		}
+		if err := s.index(ctx, tx, t); err != nil {
+			return err
+		}
+		return s.writeHistory(ctx, tx, writeEntry(ctx, nil, &t, t.CreatedAt))
+	})
+	if err != nil {
+		return models.Task{}, err
+	}
+	return t, nil
+}
+
+func (s *SQLStore) List(ctx context.Context) ([]models.Task, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT `+sqlTaskColumns+` FROM tasks WHERE deleted_at IS NULL`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tasks: %w", err)
+	}
+	defer rows.Close()
+
+	tasks := []models.Task{}
+	for rows.Next() {
+		t, err := scanTask(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode task: %w", err)
+		}
+		tasks = append(tasks, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list tasks: %w", err)
+	}
+	return tasks, nil
+}
+
+func (s *SQLStore) Get(ctx context.Context, id string) (models.Task, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+	return s.get(ctx, s.db, id, "")
+}
+
+// get lê a tarefa ativa; lock é acrescentado à consulta (FOR UPDATE dentro de transações)
+func (s *SQLStore) get(ctx context.Context, db sqlDB, id, lock string) (models.Task, error) {
+	row := db.QueryRowContext(ctx, s.dialect.rebind(`SELECT `+sqlTaskColumns+` FROM tasks WHERE id = ? AND deleted_at IS NULL`+lock), id)
+	t, err := scanTask(row)
+	if err != nil {
+		return models.Task{}, sqlNotFoundOr(err)
+	}
+	return t, nil
+}
+
+func (s *SQLStore) Update(ctx context.Context, id string, patch map[string]interface{}) (models.Task, error) {
+	return s.update(ctx, id, anyVersion, patch)
+}
+
+func (s *SQLStore) UpdateIfVersion(ctx context.Context, id string, version int64, patch map[string]interface{}) (models.Task, error) {
+	return s.update(ctx, id, version, patch)
+}
+
+func (s *SQLStore) update(ctx context.Context, id string, version int64, patch map[string]interface{}) (models.Task, error) {
+	return s.UpdateWith(ctx, id, func(current models.Task) (map[string]interface{}, error) {
+		if version != anyVersion && current.Version != version {
+			return nil, ErrVersionConflict
+		}
+		return patch, nil
+	})
+}
+
+// UpdateWith lê a tarefa travando a linha, chama fn e grava a tarefa e o histórico na
+// mesma transação, então nenhuma outra escrita acontece no meio. fn não pode chamar o Store
+func (s *SQLStore) UpdateWith(ctx context.Context, id string, fn UpdateFunc) (models.Task, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var updated models.Task
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		current, err := s.get(ctx, tx, id, s.dialect.forUpdate)
+		if err != nil {
+			return err
+		}
+		patch, err := fn(current)
+		if err != nil {
+			return err
+		}
+		p, err := parsePatch(patch)
+		if err != nil {
+			return err
+		}
+
+		sets := []string{"updated_at = ?", "version = version + 1"}
+		args := []interface{}{sqlNow()}
+		for field, v := range p.set {
+			sets = append(sets, field+" = ?")
+			args = append(args, sqlValue(field, v))
+		}
+		for _, field := range p.unset {
+			sets = append(sets, field+" = NULL")
+		}
+		args = append(args, id, current.Version)
+		row := tx.QueryRowContext(ctx, s.dialect.rebind(`UPDATE tasks SET `+strings.Join(sets, ", ")+
+			` WHERE id = ? AND version = ? AND deleted_at IS NULL RETURNING `+sqlTaskColumns), args...)
+		if updated, err = scanTask(row); errors.Is(err, sql.ErrNoRows) {
+			// A condição de versão não bateu: outra escrita alterou ou excluiu a tarefa
+			// depois da leitura (só possível sem FOR UPDATE)
+			if _, err := s.get(ctx, tx, id, ""); err != nil {
+				return err
+			}
+			return fmt.Errorf("failed to update task: %w", ErrVersionConflict)
+		} else if err != nil {
+			return fmt.Errorf("failed to update task: %w", sqlNotFoundOr(err))
+		}
+		if err := s.index(ctx, tx, updated); err != nil {
+			return err
+		}
+		return s.writeHistory(ctx, tx, writeEntry(ctx, &current, &updated, *updated.UpdatedAt))
+	})
+	if err != nil {
+		return models.Task{}, err
+	}
+	return updated, nil
+}
+
+func (s *SQLStore) Delete(ctx context.Context, id string) error {
+	return s.delete(ctx, id, anyVersion)
+}
+
+func (s *SQLStore) DeleteIfVersion(ctx context.Context, id string, version int64) error {
+	return s.delete(ctx, id, version)
+}
+
+func (s *SQLStore) delete(ctx context.Context, id string, version int64) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	now := sqlNow()
+	query := `UPDATE tasks SET deleted_at = ?, version = version + 1 WHERE id = ? AND deleted_at IS NULL`
+	args := []interface{}{now, id}
+	if version != anyVersion {
+		query += ` AND version = ?`
+		args = append(args, version)
+	}
+	missed := false
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		var deleted int64
+		err := tx.QueryRowContext(ctx, s.dialect.rebind(query+` RETURNING version`), args...).Scan(&deleted)
+		if errors.Is(err, sql.ErrNoRows) {
+			missed = true
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to delete task: %w", sqlNotFoundOr(err))
+		}
+		return s.writeHistory(ctx, tx, newAuditEntry(ctx, id, models.AuditDelete, now, deleted, nil))
+	})
+	if err != nil {
+		return err
+	}
+	if missed {
+		// Fora da transação: o SQLite tem uma conexão só
+		return s.missOr(ctx, id, version)
+	}
+	return nil
+}
+
+func (s *SQLStore) Restore(ctx context.Context, id string) (models.Task, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var t models.Task
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		row := tx.QueryRowContext(ctx, s.dialect.rebind(`UPDATE tasks SET deleted_at = NULL, version = version + 1
+			WHERE id = ? AND deleted_at IS NOT NULL RETURNING `+sqlTaskColumns), id)
+		var err error
+		if t, err = scanTask(row); err != nil {
+			return sqlNotFoundOr(err)
+		}
+		return s.writeHistory(ctx, tx, newAuditEntry(ctx, id, models.AuditRestore, sqlNow(), t.Version, nil))
+	})
+	if err != nil {
+		return models.Task{}, err
+	}
+	return t, nil
+}
+
+func (s *SQLStore) Purge(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		for _, table := range sqlIndexTables {
+			if _, err := tx.ExecContext(ctx, s.dialect.rebind(`DELETE FROM `+table+` WHERE task_id = ?`), id); err != nil {
+				return fmt.Errorf("failed to purge task: %w", err)
+			}
+		}
+		result, err := tx.ExecContext(ctx, s.dialect.rebind(`DELETE FROM tasks WHERE id = ?`), id)
+		if err != nil {
+			return fmt.Errorf("failed to purge task: %w", err)
+		}
+		if n, err := result.RowsAffected(); err != nil {
+			return fmt.Errorf("failed to purge task: %w", err)
+		} else if n == 0 {
+			return ErrNotFound
+		}
+		return s.writeHistory(ctx, tx, newAuditEntry(ctx, id, models.AuditPurge, sqlNow(), 0, nil))
+	})
+}
+
+// PurgeDeleted registra a purga de cada tarefa no histórico antes de removê-las, na
+// mesma transação
+func (s *SQLStore) PurgeDeleted(ctx context.Context, before time.Time) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var purged int64
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		const expired = `deleted_at IS NOT NULL AND deleted_at < ?`
+		ids, err := s.expiredIDs(ctx, tx, `SELECT id FROM tasks WHERE `+expired+s.dialect.forUpdate, before.UTC())
+		if err != nil {
+			return err
+		}
+		now := sqlNow()
+		for _, id := range ids {
+			if err := s.writeHistory(ctx, tx, newAuditEntry(ctx, id, models.AuditPurge, now, 0, nil)); err != nil {
+				return err
+			}
+		}
+		for _, table := range sqlIndexTables {
+			_, err := tx.ExecContext(ctx, s.dialect.rebind(`DELETE FROM `+table+` WHERE task_id IN (SELECT id FROM tasks WHERE `+expired+`)`), before.UTC())
+			if err != nil {
+				return fmt.Errorf("failed to purge deleted tasks: %w", err)
+			}
+		}
+		result, err := tx.ExecContext(ctx, s.dialect.rebind(`DELETE FROM tasks WHERE `+expired), before.UTC())
+		if err != nil {
+			return fmt.Errorf("failed to purge deleted tasks: %w", err)
+		}
+		purged, err = result.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int(purged), nil
+}
+
+// expiredIDs lê os ids da consulta dentro da transação
+func (s *SQLStore) expiredIDs(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, s.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to purge deleted tasks: %w", sqlNotFoundOr(err))
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to purge deleted tasks: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to purge deleted tasks: %w", sqlNotFoundOr(err))
+	}
+	return ids, nil
+}
+
+// sqlIndexTables são as tabelas derivadas das tarefas, regravadas por index
+var sqlIndexTables = []string{"task_terms", "task_tags"}
+
+// index regrava as tabelas derivadas da tarefa: o índice de busca e as tags
+func (s *SQLStore) index(ctx context.Context, tx *sql.Tx, t models.Task) error {
+	if err := s.indexTerms(ctx, tx, t); err != nil {
+		return err
+	}
+	return s.indexTags(ctx, tx, t)
+}
+
+// indexTerms regrava as palavras da tarefa no índice de busca
+func (s *SQLStore) indexTerms(ctx context.Context, tx *sql.Tx, t models.Task) error {
+	if _, err := tx.ExecContext(ctx, s.dialect.rebind(`DELETE FROM task_terms WHERE task_id = ?`), t.ID); err != nil {
+		return fmt.Errorf("failed to index task: %w", err)
+	}
+	weights := models.SearchTermWeights(t)
+	if len(weights) == 0 {
+		return nil
+	}
+	values := make([]string, 0, len(weights))
+	args := make([]interface{}, 0, 3*len(weights))
+	for term, weight := range weights {
+		values = append(values, "(?, ?, ?)")
+		args = append(args, t.ID, term, weight)
+	}
+	_, err := tx.ExecContext(ctx, s.dialect.rebind(`INSERT INTO task_terms (task_id, term, weight) VALUES `+strings.Join(values, ", ")), args...)
+	if err != nil {
+		return fmt.Errorf("failed to index task: %w", err)
+	}
+	return nil
+}
+
+// indexTags regrava as tags da tarefa em task_tags, usada nos filtros e em TagCounts
+func (s *SQLStore) indexTags(ctx context.Context, tx *sql.Tx, t models.Task) error {
+	if _, err := tx.ExecContext(ctx, s.dialect.rebind(`DELETE FROM task_tags WHERE task_id = ?`), t.ID); err != nil {
+		return fmt.Errorf("failed to index tags: %w", err)
+	}
+	if len(t.Tags) == 0 {
+		return nil
+	}
+	values := make([]string, len(t.Tags))
+	args := make([]interface{}, 0, 2*len(t.Tags))
+	for i, tag := range t.Tags {
+		values[i] = "(?, ?)"
+		args = append(args, t.ID, tag)
+	}
+	_, err := tx.ExecContext(ctx, s.dialect.rebind(`INSERT INTO task_tags (task_id, tag) VALUES `+strings.Join(values, ", ")), args...)
+	if err != nil {
+		return fmt.Errorf("failed to index tags: %w", err)
+	}
+	return nil
+}
+
+// TagCounts conta as tarefas ativas por tag em task_tags
+func (s *SQLStore) TagCounts(ctx context.Context) ([]models.TagCount, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT g.tag, COUNT(*) FROM task_tags g
+		JOIN tasks t ON t.id = g.task_id WHERE t.deleted_at IS NULL GROUP BY g.tag`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tags: %w", sqlNotFoundOr(err))
+	}
+	defer rows.Close()
+
+	counts := []models.TagCount{}
+	for rows.Next() {
+		var c models.TagCount
+		if err := rows.Scan(&c.Tag, &c.Count); err != nil {
+			return nil, fmt.Errorf("failed to decode tag count: %w", err)
+		}
+		counts = append(counts, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to count tags: %w", sqlNotFoundOr(err))
+	}
+	models.SortTagCounts(counts)
+	return counts, nil
+}
+
+// inTx roda fn numa transação, desfeita se fn retornar erro
+func (s *SQLStore) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return sqlNotFoundOr(err)
+	}
+	defer tx.Rollback()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+	return nil
+}
+
+// missOr distingue, quando uma escrita condicional não afetou linhas, se a tarefa
+// não existe (ErrNotFound) ou se existe com outra versão (ErrVersionConflict)
+func (s *SQLStore) missOr(ctx context.Context, id string, version int64) error {
+	if version == anyVersion {
+		return ErrNotFound
+	}
+	if _, err := s.get(ctx, s.db, id, ""); err != nil {
+		return err
+	}
+	return ErrVersionConflict
+}
+
+// sqlNotFoundOr converte ErrNoRows em ErrNotFound; erros de contexto são preservados
+// para que o handler responda 504 em timeouts
+func sqlNotFoundOr(err error) error {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return ErrNotFound
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return err
+	}
+	return fmt.Errorf("sql: %w", err)
+}
+
+// Ping verifica a conexão com o banco
+func (s *SQLStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// Close fecha as conexões com o banco
+func (s *SQLStore) Close() error {
+	return s.db.Close()
+}
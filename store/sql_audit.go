@@ -0,0 +1,68 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"example.com/tasksapi/models"
+)
+
+// WritesHistory indica que cada escrita grava sua entrada em task_history na mesma
+// transação da tarefa
+func (s *SQLStore) WritesHistory() bool {
+	return true
+}
+
+// writeHistory grava a entrada do histórico na transação da escrita da tarefa
+func (s *SQLStore) writeHistory(ctx context.Context, tx *sql.Tx, entry models.AuditEntry) error {
+	var changes interface{}
+	if len(entry.Changes) > 0 {
+		raw, err := json.Marshal(entry.Changes)
+		if err != nil {
+			return fmt.Errorf("failed to encode history changes: %w", err)
+		}
+		changes = string(raw)
+	}
+	_, err := tx.ExecContext(ctx, s.dialect.rebind(`INSERT INTO task_history (task_id, action, actor, at, version, changes) VALUES (?, ?, ?, ?, ?, ?)`),
+		entry.TaskID, entry.Action, entry.Actor, entry.At.UTC(), entry.Version, changes)
+	if err != nil {
+		return fmt.Errorf("failed to insert history entry: %w", sqlNotFoundOr(err))
+	}
+	return nil
+}
+
+func (s *SQLStore) History(ctx context.Context, taskID string) ([]models.AuditEntry, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, s.dialect.rebind(`SELECT task_id, action, actor, at, version, changes FROM task_history
+		WHERE task_id = ? ORDER BY seq`), taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", sqlNotFoundOr(err))
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var (
+			e       models.AuditEntry
+			changes sql.NullString
+		)
+		if err := rows.Scan(&e.TaskID, &e.Action, &e.Actor, &e.At, &e.Version, &changes); err != nil {
+			return nil, fmt.Errorf("failed to decode history: %w", err)
+		}
+		e.At = e.At.UTC()
+		if changes.Valid {
+			if err := json.Unmarshal([]byte(changes.String), &e.Changes); err != nil {
+				return nil, fmt.Errorf("invalid history changes %q: %w", changes.String, err)
+			}
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", sqlNotFoundOr(err))
+	}
+	return entries, nil
+}
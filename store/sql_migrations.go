@@ -0,0 +1,197 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// sqlMigration é uma versão do schema. Up recebe o dialeto para usar os tipos do banco;
+// migrações já aplicadas nunca mudam, alterações entram como uma versão nova
+type sqlMigration struct {
+	Version int
+	Name    string
+	Up      func(d sqlDialect) []string
+}
+
+var sqlMigrations = []sqlMigration{
+	{
+		Version: 1,
+		Name:    "create tasks",
+		Up: func(d sqlDialect) []string {
+			return []string{
+				`CREATE TABLE tasks (
+					id          TEXT PRIMARY KEY,
+					title       TEXT NOT NULL,
+					description TEXT,
+					status      TEXT NOT NULL,
+					priority    TEXT,
+					due_date    DATE,
+					created_at  ` + d.timestamp + ` NOT NULL,
+					updated_at  ` + d.timestamp + `,
+					deleted_at  ` + d.timestamp + `,
+					version     BIGINT NOT NULL DEFAULT 1
+				)`,
+				`CREATE INDEX tasks_created_at ON tasks (created_at, id)`,
+				`CREATE INDEX tasks_deleted_at ON tasks (deleted_at)`,
+			}
+		},
+	},
+	{
+		// Índice invertido da busca textual, com os mesmos pesos do InMemoryStore
+		Version: 2,
+		Name:    "create task_terms",
+		Up: func(d sqlDialect) []string {
+			return []string{
+				`CREATE TABLE task_terms (
+					task_id TEXT NOT NULL,
+					term    TEXT NOT NULL,
+					weight  ` + d.float + ` NOT NULL,
+					PRIMARY KEY (task_id, term)
+				)`,
+				`CREATE INDEX task_terms_term ON task_terms (term)`,
+			}
+		},
+	},
+	{
+		Version: 3,
+		Name:    "add tasks.parent_id",
+		Up: func(d sqlDialect) []string {
+			return []string{
+				`ALTER TABLE tasks ADD COLUMN parent_id TEXT`,
+				`CREATE INDEX tasks_parent_id ON tasks (parent_id)`,
+			}
+		},
+	},
+	{
+		// Lista de ids em JSON; as dependências são lidas sempre pela tarefa, sem filtro
+		Version: 4,
+		Name:    "add tasks.blocked_by",
+		Up: func(d sqlDialect) []string {
+			return []string{`ALTER TABLE tasks ADD COLUMN blocked_by TEXT`}
+		},
+	},
+	{
+		// tasks.tags guarda a lista em JSON, como blocked_by; task_tags repete as tags
+		// uma por linha para os filtros e a contagem de GET /tags
+		Version: 5,
+		Name:    "add tasks.tags",
+		Up: func(d sqlDialect) []string {
+			return []string{
+				`ALTER TABLE tasks ADD COLUMN tags TEXT`,
+				`CREATE TABLE task_tags (
+					task_id TEXT NOT NULL,
+					tag     TEXT NOT NULL,
+					PRIMARY KEY (task_id, tag)
+				)`,
+				`CREATE INDEX task_tags_tag ON task_tags (tag)`,
+			}
+		},
+	},
+	{
+		// Só a referência; a tabela projects vem na migração 8
+		Version: 6,
+		Name:    "add tasks.project_id",
+		Up: func(d sqlDialect) []string {
+			return []string{
+				`ALTER TABLE tasks ADD COLUMN project_id TEXT`,
+				`CREATE INDEX tasks_project_id ON tasks (project_id)`,
+			}
+		},
+	},
+	{
+		// Histórico (SQLStore.History) no mesmo banco das tarefas. seq mantém a ordem de
+		// gravação das entradas de uma tarefa
+		Version: 7,
+		Name:    "create task_history",
+		Up: func(d sqlDialect) []string {
+			return []string{
+				`CREATE TABLE task_history (
+					seq     ` + d.serial + `,
+					task_id TEXT NOT NULL,
+					action  TEXT NOT NULL,
+					actor   TEXT NOT NULL,
+					at      ` + d.timestamp + ` NOT NULL,
+					version BIGINT NOT NULL DEFAULT 0,
+					changes TEXT
+				)`,
+				`CREATE INDEX task_history_task ON task_history (task_id, seq)`,
+			}
+		},
+	},
+	{
+		// Projetos (SQLProjectStore) no mesmo banco das tarefas
+		Version: 8,
+		Name:    "create projects",
+		Up: func(d sqlDialect) []string {
+			return []string{
+				`CREATE TABLE projects (
+					id          TEXT PRIMARY KEY,
+					name        TEXT NOT NULL,
+					description TEXT,
+					archived_at ` + d.timestamp + `,
+					created_at  ` + d.timestamp + ` NOT NULL,
+					updated_at  ` + d.timestamp + `,
+					version     BIGINT NOT NULL DEFAULT 1
+				)`,
+				`CREATE INDEX projects_created_at ON projects (created_at, id)`,
+			}
+		},
+	},
+}
+
+// Migrate aplica as migrações pendentes, cada uma na sua transação, e registra a versão
+// em schema_migrations. Rodar de novo não faz nada
+func (s *SQLStore) Migrate(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at `+s.dialect.timestamp+` NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	current, err := s.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+	for _, m := range sqlMigrations {
+		if m.Version <= current {
+			continue
+		}
+		if err := s.applyMigration(ctx, m); err != nil {
+			return fmt.Errorf("failed to apply migration %d (%s): %w", m.Version, m.Name, err)
+		}
+	}
+	return nil
+}
+
+// SchemaVersion retorna a última migração aplicada; zero num banco vazio
+func (s *SQLStore) SchemaVersion(ctx context.Context) (int, error) {
+	var version int
+	err := s.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
+
+func (s *SQLStore) applyMigration(ctx context.Context, m sqlMigration) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, stmt := range m.Up(s.dialect) {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	// A chave primária impede que duas instâncias apliquem a mesma versão
+	_, err = tx.ExecContext(ctx, s.dialect.rebind(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`),
+		m.Version, m.Name, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
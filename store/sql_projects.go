@@ -0,0 +1,165 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"example.com/tasksapi/models"
+	"github.com/google/uuid"
+)
+
+const sqlProjectColumns = "id, name, description, archived_at, created_at, updated_at, version"
+
+// SQLProjectStore guarda os projetos na tabela projects, no mesmo banco das tarefas
+type SQLProjectStore struct {
+	s *SQLStore
+}
+
+// ProjectStore usa a tabela projects criada pelas migrações
+func (s *SQLStore) ProjectStore() (ProjectStore, error) {
+	return &SQLProjectStore{s: s}, nil
+}
+
+func scanProject(row rowScanner) (models.Project, error) {
+	var (
+		p                 models.Project
+		description       sql.NullString
+		archived, updated sql.NullTime
+	)
+	if err := row.Scan(&p.ID, &p.Name, &description, &archived, &p.CreatedAt, &updated, &p.Version); err != nil {
+		return models.Project{}, err
+	}
+	p.Description = description.String
+	p.CreatedAt = p.CreatedAt.UTC()
+	if archived.Valid {
+		at := archived.Time.UTC()
+		p.ArchivedAt = &at
+	}
+	if updated.Valid {
+		at := updated.Time.UTC()
+		p.UpdatedAt = &at
+	}
+	return p, nil
+}
+
+// sqlTime converte datas opcionais para a coluna; nil vira NULL
+func sqlTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
+
+func (ps *SQLProjectStore) Create(ctx context.Context, p models.Project) (models.Project, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	p.ID = uuid.New().String()
+	p.CreatedAt = sqlNow()
+	p.UpdatedAt = nil
+	p.Version = 1
+	_, err := ps.s.db.ExecContext(ctx, ps.s.dialect.rebind(`INSERT INTO projects (`+sqlProjectColumns+`) VALUES (?, ?, ?, ?, ?, NULL, ?)`),
+		p.ID, p.Name, sqlValue("description", p.Description), sqlTime(p.ArchivedAt), p.CreatedAt, p.Version)
+	if err != nil {
+		return models.Project{}, fmt.Errorf("failed to insert project: %w", sqlNotFoundOr(err))
+	}
+	return p, nil
+}
+
+func (ps *SQLProjectStore) Get(ctx context.Context, id string) (models.Project, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+	return ps.get(ctx, ps.s.db, id, "")
+}
+
+// get lê o projeto; lock é acrescentado à consulta (FOR UPDATE dentro de transações)
+func (ps *SQLProjectStore) get(ctx context.Context, db sqlDB, id, lock string) (models.Project, error) {
+	row := db.QueryRowContext(ctx, ps.s.dialect.rebind(`SELECT `+sqlProjectColumns+` FROM projects WHERE id = ?`+lock), id)
+	p, err := scanProject(row)
+	if err != nil {
+		return models.Project{}, sqlProjectNotFoundOr(err)
+	}
+	return p, nil
+}
+
+func (ps *SQLProjectStore) List(ctx context.Context) ([]models.Project, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := ps.s.db.QueryContext(ctx, `SELECT `+sqlProjectColumns+` FROM projects ORDER BY created_at, id`+ps.s.dialect.collate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list projects: %w", sqlNotFoundOr(err))
+	}
+	defer rows.Close()
+
+	projects := []models.Project{}
+	for rows.Next() {
+		p, err := scanProject(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode project: %w", err)
+		}
+		projects = append(projects, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list projects: %w", sqlNotFoundOr(err))
+	}
+	return projects, nil
+}
+
+// Update lê o projeto travando a linha, chama fn e grava na mesma transação, como
+// SQLStore.UpdateWith. fn não pode chamar o ProjectStore
+func (ps *SQLProjectStore) Update(ctx context.Context, id string, fn ProjectUpdateFunc) (models.Project, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var updated models.Project
+	err := ps.s.inTx(ctx, func(tx *sql.Tx) error {
+		current, err := ps.get(ctx, tx, id, ps.s.dialect.forUpdate)
+		if err != nil {
+			return err
+		}
+		if updated, err = fn(current); err != nil {
+			return err
+		}
+		now := sqlNow()
+		updated.ID, updated.CreatedAt, updated.UpdatedAt = current.ID, current.CreatedAt, &now
+		updated.Version = current.Version + 1
+		_, err = tx.ExecContext(ctx, ps.s.dialect.rebind(`UPDATE projects SET name = ?, description = ?, archived_at = ?, updated_at = ?, version = ? WHERE id = ?`),
+			updated.Name, sqlValue("description", updated.Description), sqlTime(updated.ArchivedAt), now, updated.Version, id)
+		if err != nil {
+			return fmt.Errorf("failed to update project: %w", sqlNotFoundOr(err))
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Project{}, err
+	}
+	return updated, nil
+}
+
+func (ps *SQLProjectStore) Delete(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	result, err := ps.s.db.ExecContext(ctx, ps.s.dialect.rebind(`DELETE FROM projects WHERE id = ?`), id)
+	if err != nil {
+		return fmt.Errorf("failed to delete project: %w", sqlNotFoundOr(err))
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to delete project: %w", err)
+	} else if n == 0 {
+		return ErrProjectNotFound
+	}
+	return nil
+}
+
+// sqlProjectNotFoundOr converte ErrNoRows em ErrProjectNotFound, como sqlNotFoundOr
+func sqlProjectNotFoundOr(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrProjectNotFound
+	}
+	return sqlNotFoundOr(err)
+}
@@ -0,0 +1,314 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"example.com/tasksapi/models"
+)
+
+// Query traduz o TaskQuery para SQL. Cada chave de ordenação vira um par de expressões
+// (posição dos nulos e valor comparável), como no pipeline do MongoStore, para que
+// prioridade ordene pelo significado e nulos fiquem no início ou no fim
+func (s *SQLStore) Query(ctx context.Context, q models.TaskQuery) (models.TaskPage, error) {
+	if err := q.Validate(); err != nil {
+		return models.TaskPage{}, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	from := "tasks t"
+	var args []interface{}
+	if q.Search != "" {
+		// A relevância é a soma dos pesos dos termos encontrados, como no índice do InMemoryStore
+		terms := models.SearchTerms(q.Search)
+		from += ` JOIN (SELECT task_id, SUM(weight) AS score FROM task_terms WHERE term IN (` +
+			sqlPlaceholders(len(terms)) + `) GROUP BY task_id) s ON s.task_id = t.id`
+		for _, term := range terms {
+			args = append(args, term)
+		}
+	}
+
+	// Tarefas ativas não têm deleted_at; a lixeira é o contrário
+	where := []string{"t.deleted_at IS NULL"}
+	if q.Deleted {
+		where[0] = "t.deleted_at IS NOT NULL"
+	}
+	for _, f := range q.Filters {
+		cond, condArgs, err := sqlCondition(f)
+		if err != nil {
+			return models.TaskPage{}, err
+		}
+		where = append(where, cond)
+		args = append(args, condArgs...)
+	}
+
+	var total int
+	countQuery := `SELECT COUNT(*) FROM ` + from + ` WHERE ` + strings.Join(where, " AND ")
+	if err := s.db.QueryRowContext(ctx, s.dialect.rebind(countQuery), args...).Scan(&total); err != nil {
+		return models.TaskPage{}, fmt.Errorf("failed to count tasks: %w", sqlNotFoundOr(err))
+	}
+
+	keys := q.SortKeys()
+	if q.After != nil {
+		cond, afterArgs := s.sqlAfter(keys, q.After, q.NullsFirst)
+		where = append(where, cond)
+		args = append(args, afterArgs...)
+	}
+	order := make([]string, 0, 2*len(keys))
+	for _, k := range keys {
+		value := s.sqlSortValue(k.Field)
+		dir := ""
+		if k.Desc {
+			dir = " DESC"
+		}
+		order = append(order, sqlNullRank(value, q.NullsFirst), value+dir)
+	}
+
+	columns := "t." + strings.ReplaceAll(sqlTaskColumns, ", ", ", t.")
+	if q.Search != "" {
+		columns += ", s.score"
+	}
+	query := `SELECT ` + columns + ` FROM ` + from + ` WHERE ` + strings.Join(where, " AND ") +
+		` ORDER BY ` + strings.Join(order, ", ")
+	if q.Limit > 0 {
+		// Uma linha a mais indica se existe próxima página
+		query += ` LIMIT ?`
+		args = append(args, q.Limit+1)
+	} else if q.Offset > 0 && !s.dialect.numbered {
+		// O SQLite só aceita OFFSET depois de LIMIT
+		query += ` LIMIT -1`
+	}
+	if q.Offset > 0 {
+		query += ` OFFSET ?`
+		args = append(args, q.Offset)
+	}
+
+	rows, err := s.db.QueryContext(ctx, s.dialect.rebind(query), args...)
+	if err != nil {
+		return models.TaskPage{}, fmt.Errorf("failed to query tasks: %w", sqlNotFoundOr(err))
+	}
+	defer rows.Close()
+
+	tasks := []models.Task{}
+	for rows.Next() {
+		var extra []interface{}
+		var score float64
+		if q.Search != "" {
+			extra = append(extra, &score)
+		}
+		t, err := scanTask(rows, extra...)
+		if err != nil {
+			return models.TaskPage{}, fmt.Errorf("failed to decode task: %w", err)
+		}
+		t.Score = score
+		tasks = append(tasks, t)
+	}
+	if err := rows.Err(); err != nil {
+		return models.TaskPage{}, fmt.Errorf("failed to query tasks: %w", sqlNotFoundOr(err))
+	}
+	return models.NewTaskPage(tasks, total, q), nil
+}
+
+func sqlPlaceholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+var sqlRangeOps = map[models.FilterOp]string{
+	models.FilterLt:  "<",
+	models.FilterLte: "<=",
+	models.FilterGt:  ">",
+	models.FilterGte: ">=",
+}
+
+// sqlCondition traduz um filtro. Os valores passam por models.FilterValue, a mesma
+// conversão usada pelo InMemoryStore; campos vazios são NULL e nunca satisfazem comparações
+func sqlCondition(f models.TaskFilter) (string, []interface{}, error) {
+	if err := f.Validate(); err != nil {
+		return "", nil, err
+	}
+	if f.Field == "tags" {
+		cond, args := sqlTagCondition(f)
+		return cond, args, nil
+	}
+	col := "t." + f.Field
+	switch f.Op {
+	case models.FilterIsNull:
+		return col + " IS NULL", nil, nil
+	case models.FilterNotNull:
+		return col + " IS NOT NULL", nil, nil
+	case models.FilterIn, models.FilterNotIn:
+		var args []interface{}
+		withNull := false
+		for _, raw := range f.Values {
+			if raw == models.NullLiteral {
+				withNull = true
+				continue
+			}
+			v, _ := models.FilterValue(f.Field, raw)
+			args = append(args, sqlValue(f.Field, v))
+		}
+		in := col + " IN (" + sqlPlaceholders(len(args)) + ")"
+		if f.Op == models.FilterIn {
+			switch {
+			case len(args) == 0:
+				return col + " IS NULL", nil, nil
+			case withNull:
+				return "(" + in + " OR " + col + " IS NULL)", args, nil
+			}
+			return in, args, nil
+		}
+		switch {
+		case len(args) == 0:
+			return col + " IS NOT NULL", nil, nil
+		case withNull:
+			return "(" + col + " IS NOT NULL AND NOT " + in + ")", args, nil
+		}
+		return "(" + col + " IS NULL OR NOT " + in + ")", args, nil
+	case models.FilterEq:
+		v, _ := models.FilterValue(f.Field, f.Value)
+		return col + " = ?", []interface{}{sqlValue(f.Field, v)}, nil
+	}
+	op, ok := sqlRangeOps[f.Op]
+	if !ok {
+		return "", nil, fmt.Errorf("unsupported filter operator: %s", f.Op)
+	}
+	v, _ := models.FilterValue(f.Field, f.Value)
+	return col + " " + op + " ?", []interface{}{sqlValue(f.Field, v)}, nil
+}
+
+// sqlTagCondition filtra pelas tags em task_tags. A lista vazia é NULL em t.tags; com
+// FilterAll a tarefa precisa ter uma linha para cada valor
+func sqlTagCondition(f models.TaskFilter) (string, []interface{}) {
+	const tagged = "t.id IN (SELECT task_id FROM task_tags WHERE tag IN ("
+	args := sqlTagArgs(f)
+	n := len(args)
+	switch f.Op {
+	case models.FilterIsNull:
+		return "t.tags IS NULL", nil
+	case models.FilterNotNull:
+		return "t.tags IS NOT NULL", nil
+	case models.FilterAll:
+		return tagged + sqlPlaceholders(n) + ") GROUP BY task_id HAVING COUNT(*) = " + strconv.Itoa(n) + ")", args
+	}
+	withNull := false
+	for _, raw := range f.Values {
+		withNull = withNull || raw == models.NullLiteral
+	}
+	in := tagged + sqlPlaceholders(n) + "))"
+	if f.Op != models.FilterNotIn {
+		switch {
+		case n == 0:
+			return "t.tags IS NULL", nil
+		case withNull:
+			return "(" + in + " OR t.tags IS NULL)", args
+		}
+		return in, args
+	}
+	switch {
+	case n == 0:
+		return "t.tags IS NOT NULL", nil
+	case withNull:
+		return "(t.tags IS NOT NULL AND NOT " + in + ")", args
+	}
+	return "NOT " + in, args
+}
+
+// sqlTagArgs são as tags normalizadas do filtro, sem repetição e sem NullLiteral
+func sqlTagArgs(f models.TaskFilter) []interface{} {
+	raws := f.Values
+	if f.Op == models.FilterEq {
+		raws = []string{f.Value}
+	}
+	var args []interface{}
+	seen := map[interface{}]bool{}
+	for _, raw := range raws {
+		if raw == models.NullLiteral {
+			continue
+		}
+		v, _ := models.FilterValue(f.Field, raw)
+		if !seen[v] {
+			seen[v] = true
+			args = append(args, v)
+		}
+	}
+	return args
+}
+
+// sqlSortValue é a expressão equivalente a models.SortValue
+func (s *SQLStore) sqlSortValue(field string) string {
+	switch field {
+	case "priority":
+		// Os nomes das prioridades são constantes do código, não entrada do usuário
+		names := make([]string, 0, len(models.PriorityRanks))
+		for p := range models.PriorityRanks {
+			names = append(names, p)
+		}
+		sort.Strings(names)
+		expr := "CASE t.priority"
+		for _, p := range names {
+			expr += " WHEN '" + p + "' THEN " + strconv.Itoa(models.PriorityRanks[p])
+		}
+		return expr + " END"
+	case "score":
+		return "s.score"
+	case "id", "title", "status":
+		return "t." + field + s.dialect.collate
+	}
+	return "t." + field
+}
+
+// sqlNullRank posiciona nulos antes (0) ou depois (1) dos valores presentes
+func sqlNullRank(value string, nullsFirst bool) string {
+	return "CASE WHEN " + value + " IS NULL THEN " + strconv.Itoa(nullRank(true, nullsFirst)) +
+		" ELSE " + strconv.Itoa(nullRank(false, nullsFirst)) + " END"
+}
+
+// sqlAfter monta a condição de keyset, como mongoAfter: para chaves (k1, k2, ...) as
+// linhas com k1 depois de v1 OU (k1 = v1 E k2 depois de v2) OU ...
+func (s *SQLStore) sqlAfter(keys []models.SortKey, after []interface{}, nullsFirst bool) (string, []interface{}) {
+	var (
+		branches []string
+		args     []interface{}
+	)
+	for i, k := range keys {
+		var and []string
+		for j := 0; j < i; j++ {
+			value := s.sqlSortValue(keys[j].Field)
+			if after[j] == nil {
+				and = append(and, value+" IS NULL")
+				continue
+			}
+			and = append(and, value+" = ?")
+			args = append(args, sqlValue(keys[j].Field, after[j]))
+		}
+		cond, condArgs := s.sqlBeyond(k, after[i], nullsFirst)
+		branches = append(branches, "("+strings.Join(append(and, cond), " AND ")+")")
+		args = append(args, condArgs...)
+	}
+	return "(" + strings.Join(branches, " OR ") + ")", args
+}
+
+// sqlBeyond seleciona o que vem estritamente depois de value na chave: o outro grupo de
+// nulidade, se ele vier depois, ou um valor posterior na direção da chave
+func (s *SQLStore) sqlBeyond(k models.SortKey, value interface{}, nullsFirst bool) (string, []interface{}) {
+	expr := s.sqlSortValue(k.Field)
+	if value == nil {
+		if nullsFirst {
+			return expr + " IS NOT NULL", nil
+		}
+		return "1 = 0", nil
+	}
+	op := ">"
+	if k.Desc {
+		op = "<"
+	}
+	cond := expr + " " + op + " ?"
+	if !nullsFirst {
+		cond = "(" + cond + " OR " + expr + " IS NULL)"
+	}
+	return cond, []interface{}{sqlValue(k.Field, value)}
+}
@@ -1,6 +1,7 @@
 package store
 
 import (
+	"context"
 	"errors"
 	"sync"
 	"time"
@@ -13,32 +14,90 @@ const queryTimeout = 10 * time.Second
 
 var ErrNotFound = errors.New("task not found")
 
+// ErrVersionConflict indica que a tarefa mudou desde a versão esperada pelo chamador
+var ErrVersionConflict = errors.New("task version conflict")
+
+// anyVersion desliga a checagem de versão nas escritas internas
+const anyVersion int64 = -1
+
+// TaskReader recebe o contexto da requisição para propagar cancelamento e deadline
 type TaskReader interface {
-	Get(id string) (models.Task, error)
-	List() []models.Task
+	Get(ctx context.Context, id string) (models.Task, error)
+	List(ctx context.Context) ([]models.Task, error)
+	// Query aplica filtros, ordenação e paginação no próprio backend
+	Query(ctx context.Context, q models.TaskQuery) (models.TaskPage, error)
 }
 
+// TaskWriter retorna erro em todas as operações para que falhas de escrita não sejam silenciadas
 type TaskWriter interface {
-	Create(t models.Task) models.Task
-	Update(id string, patch map[string]interface{}) (models.Task, error)
-	Delete(id string) error
+	Create(ctx context.Context, t models.Task) (models.Task, error)
+	Update(ctx context.Context, id string, patch map[string]interface{}) (models.Task, error)
+	// Delete move a tarefa para a lixeira (DeletedAt). Tarefas na lixeira não são
+	// encontradas por Get, List, Update e Delete; só por Query com Deleted, Restore e Purge
+	Delete(ctx context.Context, id string) error
+	// UpdateIfVersion e DeleteIfVersion só escrevem se a versão armazenada for igual a
+	// version, de forma atômica; caso contrário retornam ErrVersionConflict
+	UpdateIfVersion(ctx context.Context, id string, version int64, patch map[string]interface{}) (models.Task, error)
+	DeleteIfVersion(ctx context.Context, id string, version int64) error
+	// UpdateWith lê o estado atual, chama fn e grava o patch retornado como uma operação
+	// compare-and-set, para que regras de negócio rodem contra o estado realmente armazenado.
+	// Um erro de fn cancela a escrita e é devolvido ao chamador
+	UpdateWith(ctx context.Context, id string, fn UpdateFunc) (models.Task, error)
+	// Restore tira a tarefa da lixeira; ErrNotFound se ela não estiver lá
+	Restore(ctx context.Context, id string) (models.Task, error)
+	// Purge remove a tarefa definitivamente, esteja ela na lixeira ou não
+	Purge(ctx context.Context, id string) error
+	// PurgeDeleted remove definitivamente as tarefas excluídas antes de before e
+	// retorna quantas foram removidas
+	PurgeDeleted(ctx context.Context, before time.Time) (int, error)
 }
 
+// UpdateFunc recebe a tarefa armazenada e devolve o patch a aplicar. Pode ser chamada
+// mais de uma vez se o backend precisar repetir a operação após uma escrita concorrente
+type UpdateFunc func(current models.Task) (map[string]interface{}, error)
+
 type Store interface {
 	TaskReader
 	TaskWriter
 }
 
+// Pinger é implementado pelos Stores que dependem de uma conexão (MongoDB, SQL) e
+// permite ao health check saber se ela está de pé
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Migrator é implementado pelos Stores com esquema próprio (tabelas, índices). Migrate é
+// idempotente e também roda quando o Store é aberto
+type Migrator interface {
+	Migrate(ctx context.Context) error
+}
+
 type InMemoryStore struct {
 	mu    sync.RWMutex
 	items map[string]models.Task
+	// index é o índice invertido da busca textual: palavra -> id da tarefa -> peso
+	index map[string]map[string]float64
+	// persist, quando definido, grava as alterações antes de elas valerem em memória
+	// (FileStore); um erro cancela a escrita. entries são as entradas do histórico das
+	// alterações, na ordem de put e depois de purged, quando history está definido
+	persist func(put []models.Task, purged []string, entries []models.AuditEntry) error
+	// history, quando definido, recebe as entradas do histórico de cada escrita
+	// persistida (FileStore)
+	history *MemoryAuditLog
 }
 
 func New() Store {
-	return &InMemoryStore{items: make(map[string]models.Task)}
+	return &InMemoryStore{
+		items: make(map[string]models.Task),
+		index: make(map[string]map[string]float64),
+	}
 }
 
-func (s *InMemoryStore) Create(t models.Task) models.Task {
+func (s *InMemoryStore) Create(ctx context.Context, t models.Task) (models.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Task{}, err
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	id := uuid.New().String()
@@ -50,89 +109,286 @@ func (s *InMemoryStore) Create(t models.Task) models.Task {
 	}
 	t.CreatedAt = time.Now().UTC()
 	t.UpdatedAt = nil
+	t.DeletedAt = nil
+	t.Score = 0
+	t.Version = 1
+	if err := s.save(ctx, []models.Task{t}, nil); err != nil {
+		return models.Task{}, err
+	}
 	s.items[id] = t
-	return t
+	s.indexTask(t)
+	return t, nil
 }
 
-func (s *InMemoryStore) List() []models.Task {
+func (s *InMemoryStore) List(ctx context.Context) ([]models.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	out := make([]models.Task, 0, len(s.items))
 	for _, v := range s.items {
-		out = append(out, v)
+		if v.DeletedAt == nil {
+			out = append(out, v)
+		}
+	}
+	return out, nil
+}
+
+func (s *InMemoryStore) Query(ctx context.Context, q models.TaskQuery) (models.TaskPage, error) {
+	if err := ctx.Err(); err != nil {
+		return models.TaskPage{}, err
+	}
+	if err := q.Validate(); err != nil {
+		return models.TaskPage{}, err
+	}
+	s.mu.RLock()
+	matched := make([]models.Task, 0)
+	if q.Search != "" {
+		for id, score := range s.searchScores(models.SearchTerms(q.Search)) {
+			t := s.items[id]
+			if q.Match(t) {
+				t.Score = score
+				matched = append(matched, t)
+			}
+		}
+	} else {
+		for _, t := range s.items {
+			if q.Match(t) {
+				matched = append(matched, t)
+			}
+		}
 	}
-	return out
+	s.mu.RUnlock()
+
+	models.SortTasks(matched, q)
+	return models.Paginate(matched, q), nil
 }
 
-func (s *InMemoryStore) Get(id string) (models.Task, error) {
+func (s *InMemoryStore) Get(ctx context.Context, id string) (models.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Task{}, err
+	}
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	t, ok := s.items[id]
-	if !ok {
+	if !ok || t.DeletedAt != nil {
 		return models.Task{}, ErrNotFound
 	}
 	return t, nil
 }
 
-func (s *InMemoryStore) Update(id string, patch map[string]interface{}) (models.Task, error) {
+func (s *InMemoryStore) Update(ctx context.Context, id string, patch map[string]interface{}) (models.Task, error) {
+	return s.update(ctx, id, anyVersion, patch)
+}
+
+func (s *InMemoryStore) UpdateIfVersion(ctx context.Context, id string, version int64, patch map[string]interface{}) (models.Task, error) {
+	return s.update(ctx, id, version, patch)
+}
+
+func (s *InMemoryStore) update(ctx context.Context, id string, version int64, patch map[string]interface{}) (models.Task, error) {
+	return s.UpdateWith(ctx, id, func(current models.Task) (map[string]interface{}, error) {
+		if version != anyVersion && current.Version != version {
+			return nil, ErrVersionConflict
+		}
+		return patch, nil
+	})
+}
+
+// UpdateWith executa fn e aplica o patch retornado na mesma seção crítica, então nenhuma
+// outra escrita acontece entre a leitura do estado e a gravação. fn não pode chamar o Store
+func (s *InMemoryStore) UpdateWith(ctx context.Context, id string, fn UpdateFunc) (models.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Task{}, err
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	t, ok := s.items[id]
-	if !ok {
+	current, ok := s.items[id]
+	if !ok || current.DeletedAt != nil {
 		return models.Task{}, ErrNotFound
 	}
-	if v, ok := patch["title"]; ok {
-		if s, ok := v.(string); ok {
-			t.Title = s
-		}
+	patch, err := fn(current)
+	if err != nil {
+		return models.Task{}, err
 	}
-	if v, ok := patch["description"]; ok {
-		if s, ok := v.(string); ok {
-			t.Description = s
-		}
+	p, err := parsePatch(patch)
+	if err != nil {
+		return models.Task{}, err
 	}
-	if v, ok := patch["status"]; ok {
-		if s2, ok := v.(string); ok {
-			t.Status = s2
-		}
+
+	t := p.apply(current)
+	now := time.Now().UTC()
+	t.UpdatedAt = &now
+	t.Version++
+	if err := s.save(ctx, []models.Task{t}, nil); err != nil {
+		return models.Task{}, err
 	}
-	if v, ok := patch["priority"]; ok {
-		if s2, ok := v.(string); ok {
-			t.Priority = s2
-		}
+	s.unindexTask(current)
+	s.items[id] = t
+	s.indexTask(t)
+	return t, nil
+}
+
+func (s *InMemoryStore) Delete(ctx context.Context, id string) error {
+	return s.delete(ctx, id, anyVersion)
+}
+
+func (s *InMemoryStore) DeleteIfVersion(ctx context.Context, id string, version int64) error {
+	return s.delete(ctx, id, version)
+}
+
+func (s *InMemoryStore) delete(ctx context.Context, id string, version int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
 	}
-	if v, ok := patch["due_date"]; ok {
-		switch vv := v.(type) {
-		case models.Date:
-			t.DueDate = &vv
-		case *models.Date:
-			t.DueDate = vv
-		case time.Time:
-			date := models.NewDate(vv.Year(), vv.Month(), vv.Day())
-			t.DueDate = &date
-		case *time.Time:
-			if vv != nil {
-				date := models.NewDate(vv.Year(), vv.Month(), vv.Day())
-				t.DueDate = &date
-			}
-		case string:
-			if parsed, err := models.ParseDateOnly(vv); err == nil {
-				t.DueDate = &parsed
-			}
-		}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.items[id]
+	if !ok || t.DeletedAt != nil {
+		return ErrNotFound
+	}
+	if version != anyVersion && t.Version != version {
+		return ErrVersionConflict
 	}
 	now := time.Now().UTC()
-	t.UpdatedAt = &now
+	t.DeletedAt = &now
+	t.Version++
+	if err := s.save(ctx, []models.Task{t}, nil); err != nil {
+		return err
+	}
+	s.items[id] = t
+	return nil
+}
+
+func (s *InMemoryStore) Restore(ctx context.Context, id string) (models.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Task{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.items[id]
+	if !ok || t.DeletedAt == nil {
+		return models.Task{}, ErrNotFound
+	}
+	t.DeletedAt = nil
+	t.Version++
+	if err := s.save(ctx, []models.Task{t}, nil); err != nil {
+		return models.Task{}, err
+	}
 	s.items[id] = t
 	return t, nil
 }
 
-func (s *InMemoryStore) Delete(id string) error {
+func (s *InMemoryStore) Purge(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if _, ok := s.items[id]; !ok {
+	t, ok := s.items[id]
+	if !ok {
 		return ErrNotFound
 	}
+	if err := s.save(ctx, nil, []string{id}); err != nil {
+		return err
+	}
+	s.unindexTask(t)
 	delete(s.items, id)
 	return nil
 }
+
+func (s *InMemoryStore) PurgeDeleted(ctx context.Context, before time.Time) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var purged []string
+	for id, t := range s.items {
+		if t.DeletedAt != nil && t.DeletedAt.Before(before) {
+			purged = append(purged, id)
+		}
+	}
+	if len(purged) == 0 {
+		return 0, nil
+	}
+	if err := s.save(ctx, nil, purged); err != nil {
+		return 0, err
+	}
+	for _, id := range purged {
+		s.unindexTask(s.items[id])
+		delete(s.items, id)
+	}
+	return len(purged), nil
+}
+
+// save repassa as alterações para persist, com as entradas do histórico quando history
+// está definido; deve ser chamado com o lock de escrita, antes de alterar items
+func (s *InMemoryStore) save(ctx context.Context, put []models.Task, purged []string) error {
+	if s.persist == nil {
+		return nil
+	}
+	var entries []models.AuditEntry
+	if s.history != nil {
+		now := time.Now().UTC()
+		// O mesmo id pode aparecer mais de uma vez em put (lotes); cada entrada parte do
+		// estado deixado pela anterior
+		seen := map[string]models.Task{}
+		before := func(id string) *models.Task {
+			if t, ok := seen[id]; ok {
+				return &t
+			}
+			if t, ok := s.items[id]; ok {
+				return &t
+			}
+			return nil
+		}
+		entries = make([]models.AuditEntry, 0, len(put)+len(purged))
+		for i := range put {
+			entries = append(entries, writeEntry(ctx, before(put[i].ID), &put[i], now))
+			seen[put[i].ID] = put[i]
+		}
+		for _, id := range purged {
+			entries = append(entries, writeEntry(ctx, before(id), nil, now))
+		}
+	}
+	if err := s.persist(put, purged, entries); err != nil {
+		return err
+	}
+	if s.history != nil {
+		s.history.add(entries...)
+	}
+	return nil
+}
+
+// indexTask e unindexTask mantêm o índice invertido; devem ser chamados com o lock de escrita
+func (s *InMemoryStore) indexTask(t models.Task) {
+	for term, weight := range models.SearchTermWeights(t) {
+		postings, ok := s.index[term]
+		if !ok {
+			postings = make(map[string]float64)
+			s.index[term] = postings
+		}
+		postings[t.ID] = weight
+	}
+}
+
+func (s *InMemoryStore) unindexTask(t models.Task) {
+	for term := range models.SearchTermWeights(t) {
+		delete(s.index[term], t.ID)
+		if len(s.index[term]) == 0 {
+			delete(s.index, term)
+		}
+	}
+}
+
+// searchScores soma os pesos de cada termo por tarefa; só tarefas com algum termo aparecem
+func (s *InMemoryStore) searchScores(terms []string) map[string]float64 {
+	scores := make(map[string]float64)
+	for _, term := range terms {
+		for id, weight := range s.index[term] {
+			scores[id] += weight
+		}
+	}
+	return scores
+}
@@ -0,0 +1,890 @@
+// Package storetest é a suíte de conformidade do store.Store: todo backend (e todo
+// decorator) roda os mesmos testes, para que nenhum deles se comporte diferente dos outros
+package storetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"example.com/tasksapi/models"
+	"example.com/tasksapi/store"
+)
+
+// Factory abre um Store vazio para um teste; a limpeza fica a cargo de t.Cleanup
+type Factory func(t *testing.T) store.Store
+
+// Run executa a suíte contra Stores criados por newStore, um por subteste
+func Run(t *testing.T, newStore Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s store.Store)
+	}{
+		{"CreateAndGet", testCreateAndGet},
+		{"NotFound", testNotFound},
+		{"CanceledContext", testCanceledContext},
+		{"Timestamps", testTimestamps},
+		{"PatchTypes", testPatchTypes},
+		{"ClearFields", testClearFields},
+		{"Versioning", testVersioning},
+		{"UpdateWith", testUpdateWith},
+		{"ConcurrentCreates", testConcurrentCreates},
+		{"Query", testQuery},
+		{"Ordering", testOrdering},
+		{"CursorPagination", testCursorPagination},
+		{"Search", testSearch},
+		{"Trash", testTrash},
+		{"Hierarchy", testHierarchy},
+		{"Dependencies", testDependencies},
+		{"Tags", testTags},
+		{"ProjectID", testProjectID},
+		{"Bulk", testBulk},
+		{"AtomicBulk", testAtomicBulk},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStore(t))
+		})
+	}
+}
+
+func create(t *testing.T, s store.Store, task models.Task) models.Task {
+	t.Helper()
+	created, err := s.Create(context.Background(), task)
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	return created
+}
+
+func date(year int, month time.Month, day int) *models.Date {
+	d := models.NewDate(year, month, day)
+	return &d
+}
+
+func titles(tasks []models.Task) []string {
+	out := make([]string, len(tasks))
+	for i, t := range tasks {
+		out[i] = t.Title
+	}
+	return out
+}
+
+func testCreateAndGet(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	created := create(t, s, models.Task{
+		ID:          "ignored",
+		Title:       "Test",
+		Description: "Details",
+		Status:      "pending",
+		Priority:    "high",
+		DueDate:     date(2030, 5, 1),
+		Version:     42,
+	})
+	if created.ID == "" || created.ID == "ignored" {
+		t.Errorf("expected the store to assign an id, got %q", created.ID)
+	}
+	if created.Version != 1 {
+		t.Errorf("expected version 1 on create, got %d", created.Version)
+	}
+
+	got, err := s.Get(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got.Title != "Test" || got.Description != "Details" || got.Status != "pending" || got.Priority != "high" {
+		t.Errorf("unexpected task: %+v", got)
+	}
+	if got.DueDate == nil || got.DueDate.String() != "2030-05-01" {
+		t.Errorf("expected due date 2030-05-01, got %v", got.DueDate)
+	}
+	if !got.CreatedAt.Equal(created.CreatedAt) || got.Version != created.Version {
+		t.Errorf("expected Get to return what Create returned, got %+v and %+v", got, created)
+	}
+
+	// Data zerada equivale a não informar a data
+	noDue := create(t, s, models.Task{Title: "No due", Status: "pending", DueDate: &models.Date{}})
+	if got, _ := s.Get(ctx, noDue.ID); got.DueDate != nil || noDue.DueDate != nil {
+		t.Errorf("expected zero due date to be stored as empty, got %v", got.DueDate)
+	}
+
+	tasks, err := s.List(ctx)
+	if err != nil || len(tasks) != 2 {
+		t.Errorf("expected 2 tasks in list, got %d (%v)", len(tasks), err)
+	}
+}
+
+func testNotFound(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	const missing = "00000000-0000-0000-0000-000000000000"
+	noop := func(models.Task) (map[string]interface{}, error) { return nil, nil }
+	patch := map[string]interface{}{"title": "x"}
+
+	checks := map[string]error{}
+	_, checks["Get"] = s.Get(ctx, missing)
+	_, checks["Update"] = s.Update(ctx, missing, patch)
+	_, checks["UpdateIfVersion"] = s.UpdateIfVersion(ctx, missing, 1, patch)
+	_, checks["UpdateWith"] = s.UpdateWith(ctx, missing, noop)
+	checks["Delete"] = s.Delete(ctx, missing)
+	checks["DeleteIfVersion"] = s.DeleteIfVersion(ctx, missing, 1)
+	_, checks["Restore"] = s.Restore(ctx, missing)
+	checks["Purge"] = s.Purge(ctx, missing)
+	for op, err := range checks {
+		if !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("%s: expected ErrNotFound, got %v", op, err)
+		}
+	}
+}
+
+func testCanceledContext(t *testing.T, s store.Store) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := s.Create(ctx, models.Task{Title: "Canceled", Status: "pending"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled on create, got %v", err)
+	}
+	if _, err := s.List(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled on list, got %v", err)
+	}
+
+	tasks, _ := s.List(context.Background())
+	if len(tasks) != 0 {
+		t.Errorf("expected canceled create to store nothing, got %d tasks", len(tasks))
+	}
+}
+
+func testTimestamps(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	before := time.Now().UTC().Add(-time.Second)
+	created := create(t, s, models.Task{Title: "Timed", Status: "pending", CreatedAt: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)})
+	if created.CreatedAt.Before(before) || created.CreatedAt.After(time.Now().Add(time.Second)) {
+		t.Errorf("expected CreatedAt to be set by the store, got %v", created.CreatedAt)
+	}
+	if created.CreatedAt.Location() != time.UTC {
+		t.Errorf("expected CreatedAt in UTC, got %v", created.CreatedAt.Location())
+	}
+	if created.UpdatedAt != nil {
+		t.Errorf("expected UpdatedAt to be nil on create, got %v", created.UpdatedAt)
+	}
+
+	updated, err := s.Update(ctx, created.ID, map[string]interface{}{"title": "Timed again"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if updated.UpdatedAt == nil || updated.UpdatedAt.Before(created.CreatedAt) {
+		t.Errorf("expected UpdatedAt after CreatedAt, got %v", updated.UpdatedAt)
+	}
+	if !updated.CreatedAt.Equal(created.CreatedAt) {
+		t.Errorf("expected CreatedAt to be kept on update, got %v and %v", updated.CreatedAt, created.CreatedAt)
+	}
+	got, _ := s.Get(ctx, created.ID)
+	if got.UpdatedAt == nil || !got.UpdatedAt.Equal(*updated.UpdatedAt) {
+		t.Errorf("expected stored UpdatedAt %v, got %v", updated.UpdatedAt, got.UpdatedAt)
+	}
+}
+
+func testPatchTypes(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	created := create(t, s, models.Task{Title: "Typed", Status: "pending"})
+
+	d := models.NewDate(2031, 2, 3)
+	tm := time.Date(2032, 4, 5, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		value    interface{}
+		expected string
+	}{
+		{models.NewDate(2030, 1, 2), "2030-01-02"},
+		{&d, "2031-02-03"},
+		{"2033-06-07", "2033-06-07"},
+		{tm, "2032-04-05"},
+		{&tm, "2032-04-05"},
+	}
+	for _, c := range cases {
+		updated, err := s.Update(ctx, created.ID, map[string]interface{}{"due_date": c.value})
+		if err != nil {
+			t.Errorf("%T: expected no error, got %v", c.value, err)
+			continue
+		}
+		got, _ := s.Get(ctx, created.ID)
+		for _, task := range []models.Task{updated, got} {
+			if task.DueDate == nil || task.DueDate.String() != c.expected {
+				t.Errorf("%T: expected due date %s, got %v", c.value, c.expected, task.DueDate)
+			}
+		}
+	}
+
+	for _, clear := range []interface{}{nil, (*models.Date)(nil)} {
+		s.Update(ctx, created.ID, map[string]interface{}{"due_date": "2030-01-01"})
+		updated, err := s.Update(ctx, created.ID, map[string]interface{}{"due_date": clear})
+		if err != nil || updated.DueDate != nil {
+			t.Errorf("%T: expected due date to be cleared, got %v (%v)", clear, updated.DueDate, err)
+		}
+	}
+
+	for _, patch := range []map[string]interface{}{
+		{"due_date": "05/01/2030"},
+		{"due_date": 20300501},
+		{"title": 1},
+		{"priority": true},
+	} {
+		var apiErr *models.APIError
+		if _, err := s.Update(ctx, created.ID, patch); !errors.As(err, &apiErr) || apiErr.Code != 400 {
+			t.Errorf("expected validation error for %v, got %v", patch, err)
+		}
+	}
+}
+
+func testClearFields(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	created := create(t, s, models.Task{Title: "Clear me", Description: "Text", Status: "pending", Priority: "high", DueDate: date(2030, 5, 1)})
+
+	updated, err := s.Update(ctx, created.ID, map[string]interface{}{"description": nil, "priority": nil, "due_date": nil})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if updated.Description != "" || updated.Priority != "" || updated.DueDate != nil {
+		t.Errorf("expected optional fields to be cleared, got %+v", updated)
+	}
+	page, _ := s.Query(ctx, models.TaskQuery{Filters: []models.TaskFilter{
+		{Field: "priority", Op: models.FilterIsNull},
+		{Field: "due_date", Op: models.FilterIsNull},
+	}})
+	if page.Total != 1 {
+		t.Errorf("expected cleared task to match null filters, got %d", page.Total)
+	}
+
+	// Patches inválidos são rejeitados sem gravar nada
+	for _, patch := range []map[string]interface{}{
+		{"title": nil},
+		{"status": 1},
+		{"due_date": "not-a-date"},
+		{"owner": "x"},
+	} {
+		var apiErr *models.APIError
+		if _, err := s.Update(ctx, created.ID, patch); !errors.As(err, &apiErr) || apiErr.Code != 400 {
+			t.Errorf("expected validation error for %v, got %v", patch, err)
+		}
+	}
+	if got, _ := s.Get(ctx, created.ID); got.Version != updated.Version {
+		t.Errorf("expected rejected patches to keep version %d, got %d", updated.Version, got.Version)
+	}
+}
+
+func testVersioning(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	created := create(t, s, models.Task{Title: "Versioned", Status: "pending"})
+
+	updated, err := s.UpdateIfVersion(ctx, created.ID, 1, map[string]interface{}{"title": "Changed"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if updated.Version != 2 {
+		t.Errorf("expected version 2 after update, got %d", updated.Version)
+	}
+
+	if _, err := s.UpdateIfVersion(ctx, created.ID, 1, map[string]interface{}{"title": "Stale"}); !errors.Is(err, store.ErrVersionConflict) {
+		t.Errorf("expected ErrVersionConflict, got %v", err)
+	}
+	if err := s.DeleteIfVersion(ctx, created.ID, 1); !errors.Is(err, store.ErrVersionConflict) {
+		t.Errorf("expected ErrVersionConflict on delete, got %v", err)
+	}
+	if err := s.DeleteIfVersion(ctx, created.ID, 2); err != nil {
+		t.Errorf("expected delete at current version to succeed, got %v", err)
+	}
+}
+
+func testUpdateWith(t *testing.T, s store.Store) {
+	svc := models.NewTaskService(nil)
+	ctx := context.Background()
+	created := create(t, s, models.Task{Title: "Concurrent", Status: "pending"})
+
+	// Várias edições concorrem com a conclusão: depois que a tarefa é concluída,
+	// nenhuma edição pode passar pela regra de tarefa concluída
+	var wg sync.WaitGroup
+	var completedVersion int64
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			patch := map[string]interface{}{"title": "Edit"}
+			if i == 10 {
+				patch = map[string]interface{}{"status": "completed"}
+			}
+			updated, err := s.UpdateWith(ctx, created.ID, func(current models.Task) (map[string]interface{}, error) {
+				if err := svc.ValidateUpdate(current, patch); err != nil {
+					return nil, err
+				}
+				return patch, nil
+			})
+			if i == 10 && err == nil {
+				completedVersion = updated.Version
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	final, _ := s.Get(ctx, created.ID)
+	if final.Status != "completed" {
+		t.Fatalf("expected task to be completed, got %q", final.Status)
+	}
+	if final.Version != completedVersion {
+		t.Fatalf("expected no edits after completion (version %d), got version %d", completedVersion, final.Version)
+	}
+	rejected := errors.New("rejected")
+	_, err := s.UpdateWith(ctx, created.ID, func(current models.Task) (map[string]interface{}, error) {
+		return nil, rejected
+	})
+	if !errors.Is(err, rejected) {
+		t.Errorf("expected error from fn, got %v", err)
+	}
+	if after, _ := s.Get(ctx, created.ID); after.Version != final.Version {
+		t.Errorf("expected failed UpdateWith to keep version %d, got %d", final.Version, after.Version)
+	}
+}
+
+func testConcurrentCreates(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	const n = 30
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := s.Create(ctx, models.Task{Title: fmt.Sprintf("Task %d", i), Status: "pending"})
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	}
+
+	tasks, _ := s.List(ctx)
+	ids := map[string]struct{}{}
+	for _, task := range tasks {
+		ids[task.ID] = struct{}{}
+	}
+	if len(tasks) != n || len(ids) != n {
+		t.Errorf("expected %d distinct tasks, got %d tasks with %d ids", n, len(tasks), len(ids))
+	}
+}
+
+func testQuery(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	for _, task := range []models.Task{
+		{Title: "First", Status: "pending", Priority: "high"},
+		{Title: "Second", Status: "completed"},
+		{Title: "Third", Status: "pending"},
+		{Title: "Fourth", Status: "pending", Priority: "low"},
+	} {
+		create(t, s, task)
+	}
+
+	t.Run("total reports all matches, not the page size", func(t *testing.T) {
+		q := models.TaskQuery{
+			Filters: []models.TaskFilter{{Field: "status", Op: models.FilterEq, Value: "pending"}},
+			Sort:    []models.SortKey{{Field: "title"}},
+			Limit:   2,
+			Offset:  1,
+		}
+		page, err := s.Query(ctx, q)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if page.Total != 3 {
+			t.Errorf("expected total 3, got %d", page.Total)
+		}
+		if len(page.Tasks) != 2 {
+			t.Fatalf("expected 2 tasks in page, got %d", len(page.Tasks))
+		}
+		if page.Tasks[0].Title != "Fourth" || page.Tasks[1].Title != "Third" {
+			t.Errorf("unexpected page order: %s, %s", page.Tasks[0].Title, page.Tasks[1].Title)
+		}
+	})
+
+	t.Run("null filter", func(t *testing.T) {
+		q := models.TaskQuery{Filters: []models.TaskFilter{{Field: "priority", Op: models.FilterIsNull}}}
+		page, err := s.Query(ctx, q)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if page.Total != 2 {
+			t.Errorf("expected 2 tasks without priority, got %d", page.Total)
+		}
+	})
+
+	t.Run("list filters", func(t *testing.T) {
+		for _, c := range []struct {
+			filter   models.TaskFilter
+			expected int
+		}{
+			{models.TaskFilter{Field: "priority", Op: models.FilterIn, Values: []string{"high", "null"}}, 3},
+			{models.TaskFilter{Field: "priority", Op: models.FilterNotIn, Values: []string{"high"}}, 3},
+			{models.TaskFilter{Field: "priority", Op: models.FilterNotIn, Values: []string{"high", "null"}}, 1},
+			{models.TaskFilter{Field: "status", Op: models.FilterNotNull}, 4},
+		} {
+			page, err := s.Query(ctx, models.TaskQuery{Filters: []models.TaskFilter{c.filter}})
+			if err != nil || page.Total != c.expected {
+				t.Errorf("%+v: expected %d tasks, got %d (%v)", c.filter, c.expected, page.Total, err)
+			}
+		}
+	})
+
+	t.Run("default order is creation order", func(t *testing.T) {
+		page, _ := s.Query(ctx, models.TaskQuery{})
+		if len(page.Tasks) != 4 || page.Tasks[0].Title != "First" || page.Tasks[3].Title != "Fourth" {
+			t.Errorf("expected tasks in creation order, got %v", titles(page.Tasks))
+		}
+	})
+
+	t.Run("unknown sort field", func(t *testing.T) {
+		_, err := s.Query(ctx, models.TaskQuery{Sort: []models.SortKey{{Field: "secret"}}})
+		if err == nil {
+			t.Error("expected error for unknown sort field")
+		}
+	})
+}
+
+func testOrdering(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	for _, task := range []models.Task{
+		{Title: "b-low", Status: "pending", Priority: "low", DueDate: date(2030, 3, 1)},
+		{Title: "a-none", Status: "pending"},
+		{Title: "C-high", Status: "pending", Priority: "high", DueDate: date(2030, 1, 1)},
+		{Title: "d-medium", Status: "pending", Priority: "medium", DueDate: date(2030, 2, 1)},
+	} {
+		create(t, s, task)
+	}
+
+	for _, c := range []struct {
+		name     string
+		q        models.TaskQuery
+		expected []string
+	}{
+		{"priority by meaning", models.TaskQuery{Sort: []models.SortKey{{Field: "priority"}}}, []string{"b-low", "d-medium", "C-high", "a-none"}},
+		{"descending keeps nulls last", models.TaskQuery{Sort: []models.SortKey{{Field: "priority", Desc: true}}}, []string{"C-high", "d-medium", "b-low", "a-none"}},
+		{"nulls first", models.TaskQuery{Sort: []models.SortKey{{Field: "due_date"}}, NullsFirst: true}, []string{"a-none", "C-high", "d-medium", "b-low"}},
+		{"title compares bytes", models.TaskQuery{Sort: []models.SortKey{{Field: "title"}}}, []string{"C-high", "a-none", "b-low", "d-medium"}},
+	} {
+		page, err := s.Query(ctx, c.q)
+		if err != nil {
+			t.Errorf("%s: expected no error, got %v", c.name, err)
+			continue
+		}
+		if got := titles(page.Tasks); fmt.Sprint(got) != fmt.Sprint(c.expected) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.expected, got)
+		}
+	}
+}
+
+func testCursorPagination(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	priorities := []string{"high", "", "low", "high", "medium", "", "low"}
+	for i, p := range priorities {
+		create(t, s, models.Task{Title: fmt.Sprintf("Task %d", i), Status: "pending", Priority: p})
+	}
+
+	sort := []models.SortKey{{Field: "priority", Desc: true}}
+	full, _ := s.Query(ctx, models.TaskQuery{Sort: sort})
+	var paged []models.Task
+	q := models.TaskQuery{Sort: sort, Limit: 2}
+	for i := 0; i < len(priorities); i++ {
+		page, err := s.Query(ctx, q)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		paged = append(paged, page.Tasks...)
+		if page.NextCursor == "" {
+			break
+		}
+		q.After, err = models.DecodeCursor(page.NextCursor, q)
+		if err != nil {
+			t.Fatalf("invalid cursor: %v", err)
+		}
+	}
+	if fmt.Sprint(titles(paged)) != fmt.Sprint(titles(full.Tasks)) {
+		t.Errorf("expected pages to follow the full order %v, got %v", titles(full.Tasks), titles(paged))
+	}
+}
+
+func testSearch(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	inDescription := create(t, s, models.Task{Title: "Deploy", Description: "Revisar a configuração do servidor", Status: "pending"})
+	create(t, s, models.Task{Title: "Configuração do CI", Status: "pending"})
+	create(t, s, models.Task{Title: "Comprar café", Status: "completed"})
+
+	page, err := s.Query(ctx, models.TaskQuery{Search: "CONFIGURACAO"})
+	if err != nil || page.Total != 2 {
+		t.Fatalf("expected 2 matches, got %d (%v)", page.Total, err)
+	}
+	if page.Tasks[0].Title != "Configuração do CI" || page.Tasks[0].Score <= page.Tasks[1].Score {
+		t.Errorf("expected title match first with higher score, got %v", titles(page.Tasks))
+	}
+
+	s.Update(ctx, inDescription.ID, map[string]interface{}{"description": "Atualizar dependências"})
+	if page, _ := s.Query(ctx, models.TaskQuery{Search: "servidor"}); page.Total != 0 {
+		t.Errorf("expected old words to leave the index, got %d matches", page.Total)
+	}
+	if page, _ := s.Query(ctx, models.TaskQuery{Search: "dependencias"}); page.Total != 1 {
+		t.Errorf("expected new words in the index, got %d matches", page.Total)
+	}
+}
+
+func testTrash(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	kept := create(t, s, models.Task{Title: "Kept", Status: "pending"})
+	trashed := create(t, s, models.Task{Title: "Trashed", Status: "pending"})
+
+	if err := s.Delete(ctx, trashed.ID); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := s.Get(ctx, trashed.ID); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("expected trashed task to be hidden from Get, got %v", err)
+	}
+	if _, err := s.Update(ctx, trashed.ID, map[string]interface{}{"title": "x"}); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("expected ErrNotFound updating a trashed task, got %v", err)
+	}
+	if err := s.Delete(ctx, trashed.ID); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("expected ErrNotFound deleting twice, got %v", err)
+	}
+	if tasks, _ := s.List(ctx); len(tasks) != 1 {
+		t.Errorf("expected trashed task to be hidden from List, got %d tasks", len(tasks))
+	}
+
+	live, _ := s.Query(ctx, models.TaskQuery{})
+	trash, _ := s.Query(ctx, models.TaskQuery{Deleted: true})
+	if live.Total != 1 || live.Tasks[0].ID != kept.ID {
+		t.Errorf("expected only the kept task in the live listing, got %v", titles(live.Tasks))
+	}
+	if trash.Total != 1 || trash.Tasks[0].ID != trashed.ID || trash.Tasks[0].DeletedAt == nil {
+		t.Errorf("expected only the trashed task in the trash, got %+v", trash.Tasks)
+	}
+
+	restored, err := s.Restore(ctx, trashed.ID)
+	if err != nil || restored.DeletedAt != nil || restored.Version != trashed.Version+2 {
+		t.Fatalf("expected task to be restored at version %d, got %+v, %v", trashed.Version+2, restored, err)
+	}
+	if _, err := s.Restore(ctx, kept.ID); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("expected ErrNotFound restoring a live task, got %v", err)
+	}
+
+	// PurgeDeleted só remove o que está na lixeira antes do corte
+	_ = s.Delete(ctx, trashed.ID)
+	if n, _ := s.PurgeDeleted(ctx, time.Now().Add(-time.Hour)); n != 0 {
+		t.Errorf("expected nothing purged before retention, got %d", n)
+	}
+	if n, _ := s.PurgeDeleted(ctx, time.Now().Add(time.Second)); n != 1 {
+		t.Errorf("expected 1 task purged, got %d", n)
+	}
+	if _, err := s.Restore(ctx, trashed.ID); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("expected purged task to be gone, got %v", err)
+	}
+
+	if err := s.Purge(ctx, kept.ID); err != nil {
+		t.Errorf("expected live task to be purged, got %v", err)
+	}
+	if err := s.Purge(ctx, kept.ID); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("expected ErrNotFound purging twice, got %v", err)
+	}
+
+	// deleted_at vindo no corpo da criação é ignorado: a tarefa nasce ativa
+	past := time.Now().UTC().Add(-time.Hour)
+	fresh := create(t, s, models.Task{Title: "Fresh", Status: "pending", DeletedAt: &past})
+	if fresh.DeletedAt != nil {
+		t.Errorf("expected created task to have no deleted_at, got %v", fresh.DeletedAt)
+	}
+	if _, err := s.Get(ctx, fresh.ID); err != nil {
+		t.Errorf("expected created task to be active, got %v", err)
+	}
+}
+
+// testBulk passa pelos helpers do pacote store, então cobre tanto os BulkWriters quanto
+// o caminho item a item dos backends sem escrita em lote
+func testHierarchy(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	parent := create(t, s, models.Task{Title: "Parent", Status: "pending"})
+	child := create(t, s, models.Task{Title: "Child", Status: "pending", ParentID: parent.ID})
+	create(t, s, models.Task{Title: "Other", Status: "pending"})
+
+	got, err := s.Get(ctx, child.ID)
+	if err != nil || got.ParentID != parent.ID {
+		t.Fatalf("expected parent_id %s, got %q (%v)", parent.ID, got.ParentID, err)
+	}
+
+	for _, c := range []struct {
+		filter   models.TaskFilter
+		expected int
+	}{
+		{models.TaskFilter{Field: "parent_id", Op: models.FilterEq, Value: parent.ID}, 1},
+		{models.TaskFilter{Field: "parent_id", Op: models.FilterIn, Values: []string{parent.ID, "missing"}}, 1},
+		{models.TaskFilter{Field: "parent_id", Op: models.FilterIsNull}, 2},
+	} {
+		page, err := s.Query(ctx, models.TaskQuery{Filters: []models.TaskFilter{c.filter}})
+		if err != nil || page.Total != c.expected {
+			t.Errorf("%+v: expected %d tasks, got %d (%v)", c.filter, c.expected, page.Total, err)
+		}
+	}
+
+	moved, err := s.Update(ctx, child.ID, map[string]interface{}{"parent_id": nil})
+	if err != nil || moved.ParentID != "" {
+		t.Fatalf("expected parent_id to be cleared, got %q (%v)", moved.ParentID, err)
+	}
+	if got, _ := s.Get(ctx, child.ID); got.ParentID != "" {
+		t.Errorf("expected cleared parent_id to persist, got %q", got.ParentID)
+	}
+}
+
+func testDependencies(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	blocker := create(t, s, models.Task{Title: "Blocker", Status: "pending"})
+	task := create(t, s, models.Task{Title: "Blocked", Status: "pending"})
+
+	blocked, err := s.Update(ctx, task.ID, map[string]interface{}{"blocked_by": []string{blocker.ID}})
+	if err != nil || len(blocked.BlockedBy) != 1 || blocked.BlockedBy[0] != blocker.ID {
+		t.Fatalf("expected blocked_by [%s], got %v (%v)", blocker.ID, blocked.BlockedBy, err)
+	}
+	if got, _ := s.Get(ctx, task.ID); len(got.BlockedBy) != 1 || got.BlockedBy[0] != blocker.ID {
+		t.Errorf("expected blocked_by to persist, got %v", got.BlockedBy)
+	}
+	withDeps, err := s.Query(ctx, models.TaskQuery{Filters: []models.TaskFilter{{Field: "blocked_by", Op: models.FilterNotNull}}})
+	if err != nil || withDeps.Total != 1 || withDeps.Tasks[0].ID != task.ID {
+		t.Errorf("expected only the blocked task with blocked_by!=null, got %v (%v)", titles(withDeps.Tasks), err)
+	}
+	withoutDeps, _ := s.Query(ctx, models.TaskQuery{Filters: []models.TaskFilter{{Field: "blocked_by", Op: models.FilterIsNull}}})
+	if withoutDeps.Total != 1 || withoutDeps.Tasks[0].ID != blocker.ID {
+		t.Errorf("expected only the blocker with blocked_by=null, got %v", titles(withoutDeps.Tasks))
+	}
+	byID, _ := s.Query(ctx, models.TaskQuery{Filters: []models.TaskFilter{{Field: "id", Op: models.FilterIn, Values: []string{blocker.ID, "missing"}}}})
+	if byID.Total != 1 || byID.Tasks[0].ID != blocker.ID {
+		t.Errorf("expected the id filter to select the blocker, got %v", titles(byID.Tasks))
+	}
+	if cleared, err := s.Update(ctx, task.ID, map[string]interface{}{"blocked_by": nil}); err != nil || cleared.BlockedBy != nil {
+		t.Errorf("expected blocked_by to be cleared, got %v (%v)", cleared.BlockedBy, err)
+	}
+}
+
+func testTags(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	create(t, s, models.Task{Title: "Backend urgent", Status: "pending", Tags: []string{"backend", "urgent"}})
+	create(t, s, models.Task{Title: "Backend", Status: "pending", Tags: []string{"backend"}})
+	frontend := create(t, s, models.Task{Title: "Frontend", Status: "pending", Tags: []string{"frontend", "urgent"}})
+	create(t, s, models.Task{Title: "Untagged", Status: "pending"})
+	if got, _ := s.Get(ctx, frontend.ID); !reflect.DeepEqual(got.Tags, []string{"frontend", "urgent"}) {
+		t.Errorf("expected tags to persist in order, got %v", got.Tags)
+	}
+
+	cases := []struct {
+		filter models.TaskFilter
+		want   int
+	}{
+		{models.TaskFilter{Field: "tags", Op: models.FilterEq, Value: "backend"}, 2},
+		{models.TaskFilter{Field: "tags", Op: models.FilterIn, Values: []string{"backend", "frontend"}}, 3},
+		{models.TaskFilter{Field: "tags", Op: models.FilterAll, Values: []string{"backend", "urgent"}}, 1},
+		{models.TaskFilter{Field: "tags", Op: models.FilterNotIn, Values: []string{"urgent"}}, 2},
+		{models.TaskFilter{Field: "tags", Op: models.FilterNotIn, Values: []string{"urgent", "null"}}, 1},
+		{models.TaskFilter{Field: "tags", Op: models.FilterIn, Values: []string{"frontend", "null"}}, 2},
+		{models.TaskFilter{Field: "tags", Op: models.FilterIsNull}, 1},
+	}
+	for _, c := range cases {
+		page, err := s.Query(ctx, models.TaskQuery{Filters: []models.TaskFilter{c.filter}})
+		if err != nil || page.Total != c.want {
+			t.Errorf("%s %s %v%s: expected %d tasks, got %d %v (%v)", c.filter.Field, c.filter.Op, c.filter.Values, c.filter.Value, c.want, page.Total, titles(page.Tasks), err)
+		}
+	}
+
+	if cleared, err := s.Update(ctx, frontend.ID, map[string]interface{}{"tags": nil}); err != nil || cleared.Tags != nil {
+		t.Fatalf("expected tags to be cleared, got %v (%v)", cleared.Tags, err)
+	}
+	counts, err := store.TagCounts(ctx, s)
+	want := []models.TagCount{{Tag: "backend", Count: 2}, {Tag: "urgent", Count: 1}}
+	if err != nil || !reflect.DeepEqual(counts, want) {
+		t.Errorf("expected %v, got %v (%v)", want, counts, err)
+	}
+}
+
+func testProjectID(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	alpha := create(t, s, models.Task{Title: "Alpha one", Status: "pending", ProjectID: "alpha"})
+	create(t, s, models.Task{Title: "Alpha two", Status: "pending", ProjectID: "alpha"})
+	create(t, s, models.Task{Title: "Beta", Status: "pending", ProjectID: "beta"})
+	create(t, s, models.Task{Title: "No project", Status: "pending"})
+	if got, _ := s.Get(ctx, alpha.ID); got.ProjectID != "alpha" {
+		t.Errorf("expected project_id alpha, got %q", got.ProjectID)
+	}
+
+	cases := []struct {
+		filter models.TaskFilter
+		want   int
+	}{
+		{models.TaskFilter{Field: "project_id", Op: models.FilterEq, Value: "alpha"}, 2},
+		{models.TaskFilter{Field: "project_id", Op: models.FilterIsNull}, 1},
+		{models.TaskFilter{Field: "project_id", Op: models.FilterNotIn, Values: []string{"alpha"}}, 2},
+		{models.TaskFilter{Field: "project_id", Op: models.FilterIn, Values: []string{"beta", "null"}}, 2},
+	}
+	for _, c := range cases {
+		page, err := s.Query(ctx, models.TaskQuery{Filters: []models.TaskFilter{c.filter}})
+		if err != nil || page.Total != c.want {
+			t.Errorf("%s %s %v%s: expected %d tasks, got %d %v (%v)", c.filter.Field, c.filter.Op, c.filter.Values, c.filter.Value, c.want, page.Total, titles(page.Tasks), err)
+		}
+	}
+
+	moved, err := s.Update(ctx, alpha.ID, map[string]interface{}{"project_id": "beta"})
+	if err != nil || moved.ProjectID != "beta" {
+		t.Fatalf("expected project_id beta, got %q (%v)", moved.ProjectID, err)
+	}
+	if cleared, err := s.Update(ctx, alpha.ID, map[string]interface{}{"project_id": nil}); err != nil || cleared.ProjectID != "" {
+		t.Fatalf("expected project_id to be cleared, got %q (%v)", cleared.ProjectID, err)
+	}
+	if got, _ := s.Get(ctx, alpha.ID); got.ProjectID != "" {
+		t.Errorf("expected no project after reload, got %q", got.ProjectID)
+	}
+}
+
+func testBulk(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	created, err := store.CreateMany(ctx, s, []models.Task{
+		{Title: "Bulk A", Status: "pending"},
+		{Title: "Bulk B", Status: "in_progress", Priority: "high"},
+		{Title: "Bulk C", Status: "in_progress", DueDate: date(2030, 1, 1)},
+	})
+	if err != nil {
+		t.Fatalf("create many failed: %v", err)
+	}
+	if len(created) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(created))
+	}
+	ids := make([]string, len(created))
+	for i, r := range created {
+		if r.Err != nil || r.Task.ID == "" || r.Task.Version != 1 {
+			t.Fatalf("unexpected create result %d: %+v", i, r)
+		}
+		ids[i] = r.Task.ID
+	}
+	// O lote mantém a ordem de criação
+	page, err := s.Query(ctx, models.TaskQuery{})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if got := titles(page.Tasks); fmt.Sprint(got) != "[Bulk A Bulk B Bulk C]" {
+		t.Errorf("expected tasks in batch order, got %v", got)
+	}
+
+	rejected := errors.New("rejected")
+	updated, err := store.UpdateMany(ctx, s, []string{ids[0], "missing", ids[1], ids[2]}, func(current models.Task) (map[string]interface{}, error) {
+		if current.ID == ids[2] {
+			return nil, rejected
+		}
+		return map[string]interface{}{"status": "completed", "priority": nil}, nil
+	})
+	if err != nil {
+		t.Fatalf("update many failed: %v", err)
+	}
+	if r := updated[0]; r.Err != nil || r.Task.Status != "completed" || r.Task.Version != 2 || r.Task.UpdatedAt == nil {
+		t.Errorf("unexpected update result: %+v", r)
+	}
+	if r := updated[1]; !errors.Is(r.Err, store.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for missing task, got %v", r.Err)
+	}
+	if r := updated[2]; r.Err != nil || r.Task.Priority != "" {
+		t.Errorf("expected priority to be cleared, got %+v", r)
+	}
+	if r := updated[3]; !errors.Is(r.Err, rejected) {
+		t.Errorf("expected fn error to fail only its item, got %v", r.Err)
+	}
+	if got, _ := s.Get(ctx, ids[2]); got.Version != 1 || got.Status != "in_progress" {
+		t.Errorf("rejected item must not change, got %+v", got)
+	}
+
+	deleted, err := store.DeleteMany(ctx, s, []string{ids[0], "missing"})
+	if err != nil {
+		t.Fatalf("delete many failed: %v", err)
+	}
+	if deleted[0].Err != nil || deleted[0].Task.ID != ids[0] || deleted[0].Task.Version != 3 || !errors.Is(deleted[1].Err, store.ErrNotFound) {
+		t.Errorf("unexpected delete results: %+v", deleted)
+	}
+	if _, err := s.Get(ctx, ids[0]); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("expected deleted task to be in the trash, got %v", err)
+	}
+	trash, _ := s.Query(ctx, models.TaskQuery{Deleted: true})
+	if trash.Total != 1 || trash.Tasks[0].Version != 3 {
+		t.Errorf("expected one task in the trash with version 3, got %+v", trash.Tasks)
+	}
+}
+
+// testAtomicBulk só roda nos Stores que implementam store.AtomicBulkWriter
+func testAtomicBulk(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	created, err := store.CreateManyAtomic(ctx, s, []models.Task{
+		{Title: "Atomic A", Status: "pending"},
+		{Title: "Atomic B", Status: "pending"},
+	})
+	if errors.Is(err, store.ErrAtomicUnavailable) {
+		t.Skip("store does not support atomic batches")
+	}
+	if err != nil {
+		t.Fatalf("atomic create failed: %v", err)
+	}
+	a, b := created[0].Task, created[1].Task
+	if created[0].Err != nil || created[1].Err != nil || a.ID == "" || b.ID == "" {
+		t.Fatalf("unexpected create results: %+v", created)
+	}
+
+	rejected := errors.New("rejected")
+	updated, err := store.UpdateManyAtomic(ctx, s, []string{a.ID, b.ID}, func(current models.Task) (map[string]interface{}, error) {
+		if current.ID == b.ID {
+			return nil, rejected
+		}
+		return map[string]interface{}{"status": "completed"}, nil
+	})
+	if err != nil {
+		t.Fatalf("atomic update failed: %v", err)
+	}
+	if !errors.Is(updated[0].Err, store.ErrBatchAborted) || !errors.Is(updated[1].Err, rejected) {
+		t.Errorf("expected the valid item to be aborted, got %+v", updated)
+	}
+	if got, _ := s.Get(ctx, a.ID); got.Version != 1 || got.Status != "pending" {
+		t.Errorf("aborted batch must not change tasks, got %+v", got)
+	}
+
+	updated, err = store.UpdateManyAtomic(ctx, s, []string{a.ID, b.ID, a.ID}, func(current models.Task) (map[string]interface{}, error) {
+		return map[string]interface{}{"priority": "high", "title": current.Title + "!"}, nil
+	})
+	if err != nil {
+		t.Fatalf("atomic update failed: %v", err)
+	}
+	for i, r := range updated {
+		if r.Err != nil {
+			t.Fatalf("item %d: unexpected error %v", i, r.Err)
+		}
+	}
+	if got, _ := s.Get(ctx, a.ID); got.Title != "Atomic A!!" || got.Version != 3 || got.Priority != "high" {
+		t.Errorf("expected repeated id to see the previous change, got %+v", got)
+	}
+
+	deleted, err := store.DeleteManyAtomic(ctx, s, []string{a.ID, "missing"})
+	if err != nil {
+		t.Fatalf("atomic delete failed: %v", err)
+	}
+	if !errors.Is(deleted[0].Err, store.ErrBatchAborted) || !errors.Is(deleted[1].Err, store.ErrNotFound) {
+		t.Errorf("unexpected delete results: %+v", deleted)
+	}
+	if _, err := s.Get(ctx, a.ID); err != nil {
+		t.Errorf("aborted delete must keep the task, got %v", err)
+	}
+	deleted, err = store.DeleteManyAtomic(ctx, s, []string{a.ID, b.ID})
+	if err != nil || deleted[0].Err != nil || deleted[1].Err != nil {
+		t.Fatalf("atomic delete failed: %v %+v", err, deleted)
+	}
+	if page, _ := s.Query(ctx, models.TaskQuery{}); page.Total != 0 {
+		t.Errorf("expected no active tasks, got %d", page.Total)
+	}
+}
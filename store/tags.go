@@ -0,0 +1,38 @@
+package store
+
+import (
+	"context"
+
+	"example.com/tasksapi/models"
+)
+
+// TagCounter é implementado pelos Stores que contam as tags no próprio banco (agregação
+// no MongoDB, GROUP BY no SQL). O resultado considera só as tarefas ativas e vem
+// ordenado por models.SortTagCounts
+type TagCounter interface {
+	TagCounts(ctx context.Context) ([]models.TagCount, error)
+}
+
+// TagCounts usa o TagCounter do Store ou, sem ele, conta as tags das tarefas ativas
+// retornadas por Query
+func TagCounts(ctx context.Context, s Store) ([]models.TagCount, error) {
+	if tc, ok := s.(TagCounter); ok {
+		return tc.TagCounts(ctx)
+	}
+	page, err := s.Query(ctx, models.TaskQuery{Filters: []models.TaskFilter{{Field: "tags", Op: models.FilterNotNull}}})
+	if err != nil {
+		return nil, err
+	}
+	byTag := map[string]int{}
+	for _, t := range page.Tasks {
+		for _, tag := range t.Tags {
+			byTag[tag]++
+		}
+	}
+	counts := make([]models.TagCount, 0, len(byTag))
+	for tag, n := range byTag {
+		counts = append(counts, models.TagCount{Tag: tag, Count: n})
+	}
+	models.SortTagCounts(counts)
+	return counts, nil
+}
@@ -0,0 +1,299 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/tasksapi/models"
+	"example.com/tasksapi/router"
+	"example.com/tasksapi/store"
+)
+
+func TestAuditStoreRecordsChanges(t *testing.T) {
+	history := store.NewMemoryAuditLog()
+	s := store.NewAuditStore(store.New(), history, &models.NoOpLogger{})
+	ctx := models.ContextWithActor(context.Background(), "maria")
+
+	created, _ := s.Create(ctx, models.Task{Title: "Audited", Status: "pending"})
+	if _, err := s.Update(ctx, created.ID, map[string]interface{}{"status": "in_progress", "priority": "high"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	// Uma escrita rejeitada não entra no histórico
+	if _, err := s.UpdateIfVersion(ctx, created.ID, 1, map[string]interface{}{"title": "Stale"}); err == nil {
+		t.Fatal("expected version conflict")
+	}
+	if err := s.Delete(context.Background(), created.ID); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	entries, err := s.(store.HistoryReader).History(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d: %+v", len(entries), entries)
+	}
+	if entries[0].Action != models.AuditCreate || entries[0].Actor != "maria" {
+		t.Errorf("unexpected create entry: %+v", entries[0])
+	}
+
+	update := entries[1]
+	if update.Action != models.AuditUpdate || update.Version != 2 || len(update.Changes) != 2 {
+		t.Fatalf("unexpected update entry: %+v", update)
+	}
+	// Campos em ordem alfabética, com valores antigo e novo
+	if c := update.Changes[0]; c.Field != "priority" || c.Old != nil || c.New != "high" {
+		t.Errorf("unexpected priority change: %+v", c)
+	}
+	if c := update.Changes[1]; c.Field != "status" || c.Old != "pending" || c.New != "in_progress" {
+		t.Errorf("unexpected status change: %+v", c)
+	}
+
+	// A exclusão registra a versão gravada por ela (0 fica para a purga)
+	if entries[2].Action != models.AuditDelete || entries[2].Actor != models.SystemActor || entries[2].Version != 3 {
+		t.Errorf("unexpected delete entry: %+v", entries[2])
+	}
+}
+
+func TestTaskHistoryEndpoint(t *testing.T) {
+	t.Setenv("STORE_BACKEND", "memory")
+	r := router.NewWithLogger(&models.NoOpLogger{})
+
+	req := httptest.NewRequest("POST", "/tasks", bytes.NewBufferString(`{"title":"With history","status":"pending"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Actor", "joao")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var task models.Task
+	json.NewDecoder(w.Body).Decode(&task)
+
+	req = httptest.NewRequest("PATCH", "/tasks/"+task.ID, bytes.NewBufferString(`{"status":"completed"}`))
+	req.Header.Set("Content-Type", models.MergePatchContentType)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	req = httptest.NewRequest("GET", "/tasks/"+task.ID+"/history", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var response models.TaskHistoryResponse
+	json.NewDecoder(w.Body).Decode(&response)
+	if len(response.Entries) != 2 {
+		t.Fatalf("expected 2 entries, got %+v", response.Entries)
+	}
+	if response.Entries[0].Actor != "joao" || response.Entries[1].Actor != models.AnonymousActor {
+		t.Errorf("unexpected actors: %s, %s", response.Entries[0].Actor, response.Entries[1].Actor)
+	}
+
+	req = httptest.NewRequest("GET", "/tasks/missing/history", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown task, got %d", w.Code)
+	}
+}
+
+func TestActorHeaderValidation(t *testing.T) {
+	t.Setenv("STORE_BACKEND", "memory")
+	r := router.NewWithLogger(&models.NoOpLogger{})
+	for name, actor := range map[string]string{
+		"system":  "System",
+		"control": "maria\x00",
+		"long":    strings.Repeat("a", 101),
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/tasks", bytes.NewBufferString(`{"title":"Actor","status":"pending"}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-Actor", actor)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("expected 400, got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestAuditStoreBulkDeleteVersion(t *testing.T) {
+	history := store.NewMemoryAuditLog()
+	s := store.NewAuditStore(store.New(), history, &models.NoOpLogger{})
+	ctx := context.Background()
+
+	created, _ := s.Create(ctx, models.Task{Title: "Bulk audited", Status: "pending"})
+	s.Update(ctx, created.ID, map[string]interface{}{"priority": "low"})
+	if _, err := store.DeleteMany(ctx, s, []string{created.ID}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	entries, _ := s.(store.HistoryReader).History(ctx, created.ID)
+	if len(entries) != 3 || entries[2].Action != models.AuditDelete || entries[2].Version != 3 {
+		t.Errorf("expected the bulk delete to be recorded at version 3, got %+v", entries)
+	}
+}
+
+func TestDurableStoresWriteHistory(t *testing.T) {
+	openers := map[string]func(t *testing.T, dir string) (store.Store, func()){
+		"file": func(t *testing.T, dir string) (store.Store, func()) {
+			s, err := store.NewFileStore(store.FileStoreOptions{Path: filepath.Join(dir, "tasks.jsonl")})
+			if err != nil {
+				t.Fatalf("failed to open file store: %v", err)
+			}
+			return s, func() { s.Close() }
+		},
+		"events": func(t *testing.T, dir string) (store.Store, func()) {
+			s, err := store.NewEventStore(store.EventStoreOptions{Dir: dir, SnapshotEvery: 2})
+			if err != nil {
+				t.Fatalf("failed to open event store: %v", err)
+			}
+			return s, func() { s.Close() }
+		},
+		"sql": func(t *testing.T, dir string) (store.Store, func()) {
+			s, err := store.NewSQL(context.Background(), "sqlite", filepath.Join(dir, "tasks.db"))
+			if err != nil {
+				t.Fatalf("failed to open sqlite store: %v", err)
+			}
+			return s, func() { s.Close() }
+		},
+	}
+	for name, open := range openers {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			ctx := models.ContextWithActor(context.Background(), "maria")
+			base, closeStore := open(t, dir)
+			// Sem AuditLog: o AuditStore lê o histórico do próprio backend
+			s := store.NewAuditStore(base, nil, &models.NoOpLogger{})
+
+			created, err := s.Create(ctx, models.Task{Title: "Durable", Status: "pending"})
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			// O mesmo id duas vezes no lote: cada entrada parte do estado anterior
+			_, err = store.UpdateMany(ctx, s, []string{created.ID, created.ID}, func(current models.Task) (map[string]interface{}, error) {
+				if current.Status == "pending" {
+					return map[string]interface{}{"status": "in_progress"}, nil
+				}
+				return map[string]interface{}{"status": "completed"}, nil
+			})
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			other, _ := s.Create(ctx, models.Task{Title: "Other", Status: "pending"})
+			if err := s.Delete(ctx, created.ID); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if _, err := s.Restore(ctx, created.ID); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if err := s.Delete(ctx, created.ID); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if err := s.DeleteIfVersion(ctx, created.ID, 1); err == nil {
+				t.Fatal("expected the stale delete to fail")
+			}
+			if n, err := s.PurgeDeleted(context.Background(), time.Now().Add(time.Hour)); err != nil || n != 1 {
+				t.Fatalf("expected 1 purged task, got %d %v", n, err)
+			}
+			if c, ok := base.(interface{ Compact() error }); ok {
+				if err := c.Compact(); err != nil {
+					t.Fatalf("failed to compact: %v", err)
+				}
+			}
+			closeStore()
+
+			// O histórico sobrevive à reabertura (e à compactação do arquivo)
+			base, closeStore = open(t, dir)
+			defer closeStore()
+			s = store.NewAuditStore(base, nil, &models.NoOpLogger{})
+			entries, err := s.(store.HistoryReader).History(ctx, created.ID)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			want := []struct {
+				action  string
+				actor   string
+				version int64
+			}{
+				{models.AuditCreate, "maria", 1},
+				{models.AuditUpdate, "maria", 2},
+				{models.AuditUpdate, "maria", 3},
+				{models.AuditDelete, "maria", 4},
+				{models.AuditRestore, "maria", 5},
+				{models.AuditDelete, "maria", 6},
+				{models.AuditPurge, models.SystemActor, 0},
+			}
+			if len(entries) != len(want) {
+				t.Fatalf("expected %d entries, got %+v", len(want), entries)
+			}
+			for i, w := range want {
+				if e := entries[i]; e.Action != w.action || e.Actor != w.actor || e.Version != w.version {
+					t.Errorf("entry %d: expected %s by %s at version %d, got %+v", i, w.action, w.actor, w.version, e)
+				}
+			}
+			if c := findChange(entries[0].Changes, "title"); c == nil || c.Old != nil || c.New != "Durable" {
+				t.Errorf("unexpected create changes: %+v", entries[0].Changes)
+			}
+			if c := entries[2].Changes; len(c) != 1 || c[0].Old != "in_progress" || c[0].New != "completed" {
+				t.Errorf("unexpected second update changes: %+v", c)
+			}
+			if entries, _ := s.(store.HistoryReader).History(ctx, other.ID); len(entries) != 1 {
+				t.Errorf("expected only the create of the other task, got %+v", entries)
+			}
+			if entries, _ := s.(store.HistoryReader).History(ctx, "missing"); entries == nil || len(entries) != 0 {
+				t.Errorf("expected an empty history, got %#v", entries)
+			}
+		})
+	}
+}
+
+func findChange(changes []models.FieldChange, field string) *models.FieldChange {
+	for i := range changes {
+		if changes[i].Field == field {
+			return &changes[i]
+		}
+	}
+	return nil
+}
+
+func TestSQLHistoryInTaskTransaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.db")
+	ctx := context.Background()
+	s, err := store.NewSQL(ctx, "sqlite", path)
+	if err != nil {
+		t.Fatalf("failed to open sqlite store: %v", err)
+	}
+	defer s.Close()
+	created, _ := s.Create(ctx, models.Task{Title: "Kept", Status: "pending"})
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if _, err := db.Exec(`DROP TABLE task_history`); err != nil {
+		t.Fatalf("failed to drop task_history: %v", err)
+	}
+	db.Close()
+
+	// Sem onde gravar o histórico, a escrita da tarefa é desfeita
+	if _, err := s.Create(ctx, models.Task{Title: "Lost", Status: "pending"}); err == nil {
+		t.Error("expected create to fail without task_history")
+	}
+	if _, err := s.Update(ctx, created.ID, map[string]interface{}{"title": "Changed"}); err == nil {
+		t.Error("expected update to fail without task_history")
+	}
+	if err := s.Delete(ctx, created.ID); err == nil {
+		t.Error("expected delete to fail without task_history")
+	}
+	tasks, _ := s.List(ctx)
+	if len(tasks) != 1 || tasks[0].Title != "Kept" || tasks[0].Version != 1 {
+		t.Errorf("expected only the untouched task, got %+v", tasks)
+	}
+}
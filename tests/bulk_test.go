@@ -0,0 +1,252 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"example.com/tasksapi/handlers"
+	"example.com/tasksapi/models"
+	"example.com/tasksapi/store"
+)
+
+func bulkRequest(t *testing.T, handler http.HandlerFunc, method, target, body string) (int, models.BulkResponse) {
+	t.Helper()
+	r := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	handler(w, r)
+	var resp models.BulkResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	return w.Code, resp
+}
+
+func TestBulkCreateTasks(t *testing.T) {
+	s := store.NewLoggingStore(store.New(), &models.NoOpLogger{})
+	h := handlers.NewAPI(s, &models.NoOpLogger{})
+
+	code, resp := bulkRequest(t, h.BulkCreateTasks, "POST", "/tasks/bulk", `{"tasks": [
+		{"title": "First", "status": "pending"},
+		{"title": "No", "status": "pending"},
+		{"title": "Third", "status": "in_progress", "priority": "urgent"},
+		{"title": "Fourth", "status": "pending", "due_date": "2030-01-01"}
+	]}`)
+	if code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if resp.Succeeded != 2 || resp.Failed != 2 || len(resp.Results) != 4 {
+		t.Fatalf("unexpected counters: %+v", resp)
+	}
+	for i, want := range []int{201, 400, 400, 201} {
+		item := resp.Results[i]
+		if item.Index != i || item.Status != want {
+			t.Errorf("item %d: expected status %d, got %+v", i, want, item)
+		}
+		if want == 201 && (item.Task == nil || item.ID != item.Task.ID) {
+			t.Errorf("item %d: expected created task, got %+v", i, item)
+		}
+		if want == 400 && item.Error == nil {
+			t.Errorf("item %d: expected validation error", i)
+		}
+	}
+	if tasks := listTasksViaAPI(t, h, ""); tasks.TotalItems != 2 {
+		t.Errorf("expected 2 stored tasks, got %d", tasks.TotalItems)
+	}
+
+	if code, _ := bulkRequest(t, h.BulkCreateTasks, "POST", "/tasks/bulk", `{"tasks": []}`); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for empty batch, got %d", code)
+	}
+}
+
+func TestBulkUpdateTasks(t *testing.T) {
+	h := handlers.NewAPI(store.New(), &models.NoOpLogger{})
+	a := createTaskViaAPI(t, h, `{"title": "Task A", "status": "in_progress"}`)
+	b := createTaskViaAPI(t, h, `{"title": "Task B", "status": "in_progress"}`)
+	c := createTaskViaAPI(t, h, `{"title": "Task C", "status": "pending"}`)
+	done := createTaskViaAPI(t, h, `{"title": "Task D", "status": "completed"}`)
+
+	t.Run("by filter", func(t *testing.T) {
+		code, resp := bulkRequest(t, h.BulkUpdateTasks, "PATCH", "/tasks/bulk?status=in_progress", `{"patch": {"status": "completed"}}`)
+		if code != http.StatusOK || resp.Succeeded != 2 || resp.Failed != 0 {
+			t.Fatalf("expected 2 updates, got %d %+v", code, resp)
+		}
+		for _, item := range resp.Results {
+			if item.Task.Status != "completed" || (item.ID != a.ID && item.ID != b.ID) {
+				t.Errorf("unexpected result: %+v", item)
+			}
+		}
+	})
+
+	t.Run("by ids", func(t *testing.T) {
+		body := `{"ids": ["` + c.ID + `", "` + done.ID + `", "missing"], "patch": {"priority": "high"}}`
+		code, resp := bulkRequest(t, h.BulkUpdateTasks, "PATCH", "/tasks/bulk", body)
+		if code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", code)
+		}
+		for i, want := range []int{200, 409, 404} {
+			if resp.Results[i].Status != want {
+				t.Errorf("item %d: expected %d, got %+v", i, want, resp.Results[i])
+			}
+		}
+		if resp.Results[0].Task.Priority != "high" {
+			t.Errorf("expected priority to be updated, got %+v", resp.Results[0].Task)
+		}
+	})
+
+	t.Run("ids outside the filter", func(t *testing.T) {
+		body := `{"ids": ["` + c.ID + `"], "patch": {"priority": "low"}}`
+		_, resp := bulkRequest(t, h.BulkUpdateTasks, "PATCH", "/tasks/bulk?status=in_progress", body)
+		if resp.Results[0].Status != http.StatusConflict {
+			t.Errorf("expected 409 for a task outside the filter, got %+v", resp.Results[0])
+		}
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		for _, tc := range []struct{ target, body string }{
+			{"/tasks/bulk", `{"patch": {"status": "completed"}}`},
+			{"/tasks/bulk?status=pending", `{"patch": {}}`},
+			{"/tasks/bulk", `{"ids": ["x", "x"], "patch": {"status": "completed"}}`},
+			{"/tasks/bulk?due_before=tomorrow", `{"patch": {"status": "completed"}}`},
+		} {
+			if code, _ := bulkRequest(t, h.BulkUpdateTasks, "PATCH", tc.target, tc.body); code != http.StatusBadRequest {
+				t.Errorf("%s %s: expected 400, got %d", tc.target, tc.body, code)
+			}
+		}
+	})
+
+	t.Run("field validation per item", func(t *testing.T) {
+		body := `{"ids": ["` + c.ID + `"], "patch": {"status": "unknown"}}`
+		_, resp := bulkRequest(t, h.BulkUpdateTasks, "PATCH", "/tasks/bulk", body)
+		if resp.Results[0].Status != http.StatusBadRequest {
+			t.Errorf("expected 400 for an invalid status, got %+v", resp.Results[0])
+		}
+	})
+}
+
+func TestBulkDeleteTasks(t *testing.T) {
+	h := handlers.NewAPI(store.New(), &models.NoOpLogger{})
+	a := createTaskViaAPI(t, h, `{"title": "Task A", "status": "cancelled"}`)
+	createTaskViaAPI(t, h, `{"title": "Task B", "status": "cancelled"}`)
+	c := createTaskViaAPI(t, h, `{"title": "Task C", "status": "pending"}`)
+
+	code, resp := bulkRequest(t, h.BulkDeleteTasks, "DELETE", "/tasks/bulk?status=cancelled", "")
+	if code != http.StatusOK || resp.Succeeded != 2 {
+		t.Fatalf("expected 2 deletions, got %d %+v", code, resp)
+	}
+	if resp.Results[0].Status != http.StatusNoContent || resp.Results[0].Task != nil {
+		t.Errorf("unexpected delete result: %+v", resp.Results[0])
+	}
+
+	body := `{"ids": ["` + a.ID + `", "` + c.ID + `"]}`
+	_, resp = bulkRequest(t, h.BulkDeleteTasks, "DELETE", "/tasks/bulk?status=pending", body)
+	if resp.Results[0].Status != http.StatusConflict || resp.Results[1].Status != http.StatusNoContent {
+		t.Errorf("expected only the task matching the filter to be deleted, got %+v", resp.Results)
+	}
+	if tasks := listTasksViaAPI(t, h, ""); tasks.TotalItems != 0 {
+		t.Errorf("expected no active tasks, got %d", tasks.TotalItems)
+	}
+
+	if code, _ := bulkRequest(t, h.BulkDeleteTasks, "DELETE", "/tasks/bulk", ""); code != http.StatusBadRequest {
+		t.Errorf("expected 400 without ids or filters, got %d", code)
+	}
+}
+
+func TestAtomicBulkOperations(t *testing.T) {
+	s := store.New()
+	h := handlers.NewAPI(s, &models.NoOpLogger{})
+	a := createTaskViaAPI(t, h, `{"title": "Task A", "status": "pending"}`)
+	done := createTaskViaAPI(t, h, `{"title": "Task B", "status": "completed"}`)
+
+	t.Run("completed task aborts the whole update", func(t *testing.T) {
+		body := `{"ids": ["` + a.ID + `", "` + done.ID + `"], "patch": {"title": "Renamed"}}`
+		code, resp := bulkRequest(t, h.BulkUpdateTasks, "PATCH", "/tasks/bulk?atomic=true", body)
+		if code != http.StatusUnprocessableEntity {
+			t.Fatalf("expected 422, got %d", code)
+		}
+		if !resp.Atomic || resp.Succeeded != 0 || resp.Failed != 2 || resp.Error == nil {
+			t.Fatalf("unexpected response: %+v", resp)
+		}
+		if resp.Results[0].Status != http.StatusFailedDependency || resp.Results[1].Status != http.StatusConflict {
+			t.Errorf("unexpected item statuses: %+v", resp.Results)
+		}
+		if resp.Error.Message != "atomic batch aborted: 1 of 2 items failed" {
+			t.Errorf("unexpected combined error: %+v", resp.Error)
+		}
+		if got, _ := s.Get(context.Background(), a.ID); got.Title != "Task A" || got.Version != 1 {
+			t.Errorf("expected task A unchanged, got %+v", got)
+		}
+	})
+
+	t.Run("invalid item aborts the whole create", func(t *testing.T) {
+		code, resp := bulkRequest(t, h.BulkCreateTasks, "POST", "/tasks/bulk?atomic=true", `{"tasks": [
+			{"title": "Valid", "status": "pending"},
+			{"title": "No", "status": "pending"}
+		]}`)
+		if code != http.StatusUnprocessableEntity || resp.Results[0].Status != http.StatusFailedDependency || resp.Results[1].Status != http.StatusBadRequest {
+			t.Fatalf("expected aborted batch, got %d %+v", code, resp)
+		}
+		if tasks := listTasksViaAPI(t, h, ""); tasks.TotalItems != 2 {
+			t.Errorf("expected no new tasks, got %d", tasks.TotalItems)
+		}
+	})
+
+	t.Run("delete outside the filter aborts the batch", func(t *testing.T) {
+		body := `{"ids": ["` + a.ID + `", "` + done.ID + `"]}`
+		code, resp := bulkRequest(t, h.BulkDeleteTasks, "DELETE", "/tasks/bulk?atomic=true&status=pending", body)
+		if code != http.StatusUnprocessableEntity || resp.Results[0].Status != http.StatusFailedDependency {
+			t.Fatalf("expected aborted batch, got %d %+v", code, resp)
+		}
+		if tasks := listTasksViaAPI(t, h, ""); tasks.TotalItems != 2 {
+			t.Errorf("expected no deletions, got %d", tasks.TotalItems)
+		}
+	})
+
+	t.Run("successful batch", func(t *testing.T) {
+		body := `{"ids": ["` + a.ID + `"], "patch": {"status": "in_progress"}}`
+		code, resp := bulkRequest(t, h.BulkUpdateTasks, "PATCH", "/tasks/bulk?atomic=true", body)
+		if code != http.StatusOK || resp.Succeeded != 1 || resp.Error != nil {
+			t.Errorf("expected the batch to be applied, got %d %+v", code, resp)
+		}
+	})
+}
+
+func TestAtomicBulkUnsupportedStore(t *testing.T) {
+	es, err := store.NewEventStore(store.EventStoreOptions{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("failed to open event store: %v", err)
+	}
+	defer es.Close()
+	h := handlers.NewAPI(es, &models.NoOpLogger{})
+
+	code, _ := bulkRequest(t, h.BulkCreateTasks, "POST", "/tasks/bulk?atomic=true", `{"tasks": [{"title": "Task", "status": "pending"}]}`)
+	if code != http.StatusNotImplemented {
+		t.Errorf("expected 501, got %d", code)
+	}
+}
+
+func TestBulkOperationsAreAudited(t *testing.T) {
+	history := store.NewMemoryAuditLog()
+	s := store.NewAuditStore(store.New(), history, &models.NoOpLogger{})
+	h := handlers.NewAPI(s, &models.NoOpLogger{})
+
+	_, created := bulkRequest(t, h.BulkCreateTasks, "POST", "/tasks/bulk", `{"tasks": [{"title": "Audited", "status": "pending"}]}`)
+	id := created.Results[0].ID
+	bulkRequest(t, h.BulkUpdateTasks, "PATCH", "/tasks/bulk", `{"ids": ["`+id+`"], "patch": {"status": "in_progress"}}`)
+	bulkRequest(t, h.BulkDeleteTasks, "DELETE", "/tasks/bulk", `{"ids": ["`+id+`"]}`)
+
+	entries, _ := history.History(context.Background(), id)
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 history entries, got %+v", entries)
+	}
+	for i, action := range []string{models.AuditCreate, models.AuditUpdate, models.AuditDelete} {
+		if entries[i].Action != action {
+			t.Errorf("entry %d: expected %s, got %s", i, action, entries[i].Action)
+		}
+	}
+	if changes := entries[1].Changes; len(changes) != 1 || changes[0].Field != "status" || changes[0].Old != "pending" {
+		t.Errorf("expected status change from pending, got %+v", changes)
+	}
+}
@@ -0,0 +1,244 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"example.com/tasksapi/handlers"
+	"example.com/tasksapi/models"
+	"example.com/tasksapi/store"
+)
+
+func dependencyRouter(api *handlers.API) *mux.Router {
+	r := mux.NewRouter()
+	r.HandleFunc("/tasks", api.CreateTask).Methods("POST")
+	r.HandleFunc("/tasks/actionable", api.ListActionable).Methods("GET")
+	r.HandleFunc("/tasks/bulk", api.BulkUpdateTasks).Methods("PATCH")
+	r.HandleFunc("/tasks/{id}", api.PatchTask).Methods("PATCH")
+	r.HandleFunc("/tasks/{id}", api.DeleteTask).Methods("DELETE")
+	r.HandleFunc("/tasks/{id}/dependencies", api.TaskDependencies).Methods("GET")
+	r.HandleFunc("/tasks/{id}/dependencies", api.AddDependency).Methods("POST")
+	r.HandleFunc("/tasks/{id}/dependencies/{blocker_id}", api.RemoveDependency).Methods("DELETE")
+	return r
+}
+
+func addDependency(t *testing.T, r http.Handler, id, blocker string) int {
+	t.Helper()
+	return serve(r, "POST", "/tasks/"+id+"/dependencies", "application/json", `{"task_id": "`+blocker+`"}`).Code
+}
+
+func TestAddAndRemoveDependency(t *testing.T) {
+	api := handlers.NewAPI(store.New(), &models.NoOpLogger{})
+	r := dependencyRouter(api)
+	a := createTaskViaAPI(t, api, `{"title": "Task A", "status": "pending"}`)
+	b := createTaskViaAPI(t, api, `{"title": "Task B", "status": "pending"}`)
+	c := createTaskViaAPI(t, api, `{"title": "Task C", "status": "pending"}`)
+
+	w := serve(r, "POST", "/tasks/"+b.ID+"/dependencies", "application/json", `{"task_id": "`+a.ID+`"}`)
+	var blocked models.Task
+	json.NewDecoder(w.Body).Decode(&blocked)
+	if w.Code != http.StatusOK || len(blocked.BlockedBy) != 1 || blocked.BlockedBy[0] != a.ID {
+		t.Fatalf("expected B blocked by A, got %d %+v", w.Code, blocked)
+	}
+	if code := addDependency(t, r, b.ID, a.ID); code != http.StatusOK {
+		t.Errorf("expected adding the same dependency to be a no-op, got %d", code)
+	}
+	if code := addDependency(t, r, c.ID, b.ID); code != http.StatusOK {
+		t.Fatalf("expected C blocked by B, got %d", code)
+	}
+
+	tests := []struct {
+		name    string
+		id      string
+		blocker string
+		want    int
+	}{
+		{"self dependency", a.ID, a.ID, http.StatusConflict},
+		{"direct cycle", a.ID, b.ID, http.StatusConflict},
+		{"transitive cycle", a.ID, c.ID, http.StatusConflict},
+		{"missing blocker", a.ID, "missing", http.StatusBadRequest},
+		{"missing task", "missing", a.ID, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := addDependency(t, r, tt.id, tt.blocker); code != tt.want {
+				t.Errorf("expected %d, got %d", tt.want, code)
+			}
+		})
+	}
+
+	t.Run("read-only in create and patch", func(t *testing.T) {
+		w := serve(r, "POST", "/tasks", "application/json", `{"title": "Early", "status": "pending", "blocked_by": ["`+a.ID+`"]}`)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 on create, got %d", w.Code)
+		}
+		w = serve(r, "PATCH", "/tasks/"+a.ID, models.MergePatchContentType, `{"blocked_by": ["`+c.ID+`"]}`)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 on patch, got %d", w.Code)
+		}
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		if w := serve(r, "DELETE", "/tasks/"+b.ID+"/dependencies/"+a.ID, "", ""); w.Code != http.StatusNoContent {
+			t.Fatalf("expected 204, got %d", w.Code)
+		}
+		if w := serve(r, "DELETE", "/tasks/"+b.ID+"/dependencies/"+a.ID, "", ""); w.Code != http.StatusNotFound {
+			t.Errorf("expected 404 for a missing dependency, got %d", w.Code)
+		}
+		if code := addDependency(t, r, a.ID, c.ID); code != http.StatusOK {
+			t.Errorf("expected A blocked by C once the cycle is gone, got %d", code)
+		}
+	})
+}
+
+func TestConcurrentOppositeDependencies(t *testing.T) {
+	api := handlers.NewAPI(slowReads{store.New()}, &models.NoOpLogger{})
+	r := dependencyRouter(api)
+
+	// A bloqueada por B e B bloqueada por A ao mesmo tempo: só uma das duas pode passar
+	for i := 0; i < 20; i++ {
+		a := createTaskViaAPI(t, api, `{"title": "Task A", "status": "pending"}`)
+		b := createTaskViaAPI(t, api, `{"title": "Task B", "status": "pending"}`)
+		codes := make([]int, 2)
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			codes[0] = addDependency(t, r, a.ID, b.ID)
+		}()
+		go func() {
+			defer wg.Done()
+			codes[1] = addDependency(t, r, b.ID, a.ID)
+		}()
+		wg.Wait()
+		if codes[0] == http.StatusOK && codes[1] == http.StatusOK {
+			t.Fatal("expected one of the opposite dependencies to be rejected as a cycle")
+		}
+	}
+}
+
+func TestDependencyRulesWithInterleavedWrites(t *testing.T) {
+	ctx := context.Background()
+	s := &interleaved{Store: store.New()}
+	api := handlers.NewAPI(s, &models.NoOpLogger{})
+	r := dependencyRouter(api)
+
+	t.Run("dependency closing a cycle in between is undone", func(t *testing.T) {
+		a := createTaskViaAPI(t, api, `{"title": "Task A", "status": "pending"}`)
+		b := createTaskViaAPI(t, api, `{"title": "Task B", "status": "pending"}`)
+		s.hook = func(inner store.Store) {
+			inner.Update(ctx, b.ID, map[string]interface{}{"blocked_by": []string{a.ID}})
+		}
+		if code := addDependency(t, r, a.ID, b.ID); code != http.StatusConflict {
+			t.Fatalf("expected 409, got %d", code)
+		}
+		if stored, _ := s.Get(ctx, a.ID); len(stored.BlockedBy) != 0 {
+			t.Errorf("expected the dependency to be undone, got %v", stored.BlockedBy)
+		}
+	})
+
+	t.Run("blocker added in between stops the start", func(t *testing.T) {
+		task := createTaskViaAPI(t, api, `{"title": "Task", "status": "pending"}`)
+		blocker := createTaskViaAPI(t, api, `{"title": "Blocker", "status": "pending"}`)
+		s.hook = func(inner store.Store) {
+			inner.Update(ctx, task.ID, map[string]interface{}{"blocked_by": []string{blocker.ID}})
+		}
+		w := serve(r, "PATCH", "/tasks/"+task.ID, models.MergePatchContentType, `{"status": "in_progress"}`)
+		if w.Code != http.StatusConflict {
+			t.Fatalf("expected 409, got %d: %s", w.Code, w.Body)
+		}
+		if stored, _ := s.Get(ctx, task.ID); stored.Status != "pending" {
+			t.Errorf("expected the task to stay pending, got %q", stored.Status)
+		}
+	})
+}
+
+func TestBlockedTaskCannotStart(t *testing.T) {
+	api := handlers.NewAPI(store.New(), &models.NoOpLogger{})
+	r := dependencyRouter(api)
+	blocker := createTaskViaAPI(t, api, `{"title": "Blocker", "status": "pending"}`)
+	task := createTaskViaAPI(t, api, `{"title": "Blocked", "status": "pending"}`)
+	addDependency(t, r, task.ID, blocker.ID)
+
+	for _, status := range []string{"in_progress", "completed"} {
+		w := serve(r, "PATCH", "/tasks/"+task.ID, models.MergePatchContentType, `{"status": "`+status+`"}`)
+		if w.Code != http.StatusConflict {
+			t.Errorf("%s: expected 409, got %d", status, w.Code)
+		}
+	}
+	if w := serve(r, "PATCH", "/tasks/"+task.ID, models.MergePatchContentType, `{"status": "cancelled"}`); w.Code != http.StatusOK {
+		t.Errorf("expected cancelling a blocked task to be allowed, got %d", w.Code)
+	}
+
+	other := createTaskViaAPI(t, api, `{"title": "Also blocked", "status": "pending"}`)
+	addDependency(t, r, other.ID, blocker.ID)
+	code, resp := bulkRequest(t, api.BulkUpdateTasks, "PATCH", "/tasks/bulk", `{"ids": ["`+other.ID+`"], "patch": {"status": "in_progress"}}`)
+	if code != http.StatusOK || resp.Results[0].Status != http.StatusConflict {
+		t.Errorf("expected bulk item 409, got %d %+v", code, resp)
+	}
+
+	serve(r, "PATCH", "/tasks/"+blocker.ID, models.MergePatchContentType, `{"status": "completed"}`)
+	if w := serve(r, "PATCH", "/tasks/"+other.ID, models.MergePatchContentType, `{"status": "in_progress"}`); w.Code != http.StatusOK {
+		t.Errorf("expected 200 once the blocker is completed, got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestDependencyGraphAndActionable(t *testing.T) {
+	api := handlers.NewAPI(store.New(), &models.NoOpLogger{})
+	r := dependencyRouter(api)
+	design := createTaskViaAPI(t, api, `{"title": "Design", "status": "pending"}`)
+	build := createTaskViaAPI(t, api, `{"title": "Build", "status": "pending"}`)
+	test := createTaskViaAPI(t, api, `{"title": "Test", "status": "pending"}`)
+	docs := createTaskViaAPI(t, api, `{"title": "Docs", "status": "pending"}`)
+	createTaskViaAPI(t, api, `{"title": "Done", "status": "completed"}`)
+	addDependency(t, r, build.ID, design.ID)
+	addDependency(t, r, test.ID, build.ID)
+
+	t.Run("graph", func(t *testing.T) {
+		w := serve(r, "GET", "/tasks/"+build.ID+"/dependencies", "", "")
+		var graph models.DependencyGraphResponse
+		json.NewDecoder(w.Body).Decode(&graph)
+		if w.Code != http.StatusOK || !graph.Blocked {
+			t.Fatalf("expected a blocked task, got %d %+v", w.Code, graph)
+		}
+		if len(graph.Upstream) != 1 || graph.Upstream[0].ID != design.ID || graph.Upstream[0].Depth != 1 {
+			t.Errorf("unexpected upstream: %+v", graph.Upstream)
+		}
+		if len(graph.Downstream) != 1 || graph.Downstream[0].ID != test.ID || graph.Downstream[0].BlockedBy[0] != build.ID {
+			t.Errorf("unexpected downstream: %+v", graph.Downstream)
+		}
+
+		w = serve(r, "GET", "/tasks/"+test.ID+"/dependencies", "", "")
+		json.NewDecoder(w.Body).Decode(&graph)
+		if len(graph.Upstream) != 2 || graph.Upstream[1].ID != design.ID || graph.Upstream[1].Depth != 2 {
+			t.Errorf("expected the transitive upstream, got %+v", graph.Upstream)
+		}
+	})
+
+	t.Run("actionable", func(t *testing.T) {
+		w := serve(r, "GET", "/tasks/actionable", "", "")
+		var resp models.ActionableResponse
+		json.NewDecoder(w.Body).Decode(&resp)
+		if w.Code != http.StatusOK || resp.TotalItems != 2 {
+			t.Fatalf("expected 2 actionable tasks, got %d %+v", w.Code, resp)
+		}
+		if resp.Tasks[0].ID != design.ID || resp.Tasks[0].Unblocks != 2 || resp.Tasks[1].ID != docs.ID {
+			t.Errorf("expected Design (unblocks 2) then Docs, got %+v", resp.Tasks)
+		}
+	})
+
+	t.Run("deleted blockers do not block", func(t *testing.T) {
+		serve(r, "DELETE", "/tasks/"+design.ID, "", "")
+		w := serve(r, "GET", "/tasks/actionable?limit=1", "", "")
+		var resp models.ActionableResponse
+		json.NewDecoder(w.Body).Decode(&resp)
+		if resp.TotalItems != 2 || len(resp.Tasks) != 1 || resp.Tasks[0].ID != build.ID {
+			t.Errorf("expected Build first, got %+v", resp)
+		}
+	})
+}
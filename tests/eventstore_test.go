@@ -0,0 +1,184 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"example.com/tasksapi/handlers"
+	"example.com/tasksapi/models"
+	"example.com/tasksapi/store"
+	"github.com/gorilla/mux"
+)
+
+func TestEventStoreReplay(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	s, err := store.NewEventStore(store.EventStoreOptions{Dir: dir, SnapshotEvery: 3})
+	if err != nil {
+		t.Fatalf("failed to open event store: %v", err)
+	}
+
+	task, _ := s.Create(ctx, models.Task{Title: "Evented", Status: "pending", Priority: "low"})
+	afterCreate := time.Now().UTC()
+	time.Sleep(2 * time.Millisecond)
+	if _, err := s.Update(ctx, task.ID, map[string]interface{}{"status": "in_progress", "priority": nil}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	afterUpdate := time.Now().UTC()
+	time.Sleep(2 * time.Millisecond)
+	other, _ := s.Create(ctx, models.Task{Title: "Removed", Status: "pending"})
+	_ = s.Delete(ctx, other.ID)
+	if _, err := s.Update(ctx, task.ID, map[string]interface{}{"title": "Evented v3"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	current, _ := s.Get(ctx, task.ID)
+	s.Close()
+
+	// Reabrir refaz snapshot + eventos e chega ao mesmo estado
+	reopened, err := store.NewEventStore(store.EventStoreOptions{Dir: dir, SnapshotEvery: 3})
+	if err != nil {
+		t.Fatalf("failed to reopen event store: %v", err)
+	}
+	defer reopened.Close()
+	got, err := reopened.Get(ctx, task.ID)
+	if err != nil || got.Title != current.Title || got.Version != current.Version || !got.UpdatedAt.Equal(*current.UpdatedAt) {
+		t.Fatalf("expected replayed task %+v, got %+v (%v)", current, got, err)
+	}
+	if _, err := reopened.Get(ctx, other.ID); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("expected deleted task to stay deleted, got %v", err)
+	}
+	trash, _ := reopened.Query(ctx, models.TaskQuery{Deleted: true})
+	if trash.Total != 1 {
+		t.Errorf("expected 1 task in trash after replay, got %d", trash.Total)
+	}
+
+	// Estados passados
+	v1, err := reopened.GetAsOf(ctx, task.ID, afterCreate)
+	if err != nil || v1.Status != "pending" || v1.Priority != "low" || v1.Version != 1 {
+		t.Errorf("unexpected state after create: %+v (%v)", v1, err)
+	}
+	v2, err := reopened.GetAsOf(ctx, task.ID, afterUpdate)
+	if err != nil || v2.Status != "in_progress" || v2.Priority != "" || v2.Title != "Evented" {
+		t.Errorf("unexpected state after update: %+v (%v)", v2, err)
+	}
+	if _, err := reopened.GetAsOf(ctx, task.ID, afterCreate.Add(-time.Hour)); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("expected ErrNotFound before creation, got %v", err)
+	}
+}
+
+func TestEventStoreRecoversTruncatedLog(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	s, _ := store.NewEventStore(store.EventStoreOptions{Dir: dir, Sync: true})
+	task, _ := s.Create(ctx, models.Task{Title: "Survivor", Status: "pending"})
+	s.Close()
+
+	// Simula um crash no meio de uma escrita: último registro sem "\n"
+	f, _ := os.OpenFile(filepath.Join(dir, "events.jsonl"), os.O_APPEND|os.O_WRONLY, 0o644)
+	f.WriteString(`{"seq":2,"type":"FieldCha`)
+	f.Close()
+
+	reopened, err := store.NewEventStore(store.EventStoreOptions{Dir: dir, Sync: true})
+	if err != nil {
+		t.Fatalf("expected recovery, got %v", err)
+	}
+	defer reopened.Close()
+	if _, err := reopened.Get(ctx, task.ID); err != nil {
+		t.Errorf("expected task to survive, got %v", err)
+	}
+	if _, err := reopened.Update(ctx, task.ID, map[string]interface{}{"title": "After crash"}); err != nil {
+		t.Errorf("expected log to accept writes after recovery, got %v", err)
+	}
+}
+
+func TestEventStoreAsOfWithClockSkew(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	t0 := time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC)
+	created := models.Task{ID: "skewed", Title: "Skewed", Status: "pending", CreatedAt: t0, Version: 1}
+	// O relógio voltou entre os eventos 2 e 3: o 3 tem data anterior ao 2
+	events := []store.Event{
+		{Seq: 1, Type: store.TaskCreated, TaskID: "skewed", At: t0, Version: 1, Task: &created},
+		{Seq: 2, Type: store.FieldChanged, TaskID: "skewed", At: t0.Add(2 * time.Hour), Version: 2, Field: "title", Value: "Later"},
+		{Seq: 3, Type: store.StatusChanged, TaskID: "skewed", At: t0.Add(time.Hour), Version: 3, Field: "status", Value: "completed"},
+	}
+	f, _ := os.Create(filepath.Join(dir, "events.jsonl"))
+	enc := json.NewEncoder(f)
+	for _, e := range events {
+		enc.Encode(e)
+	}
+	f.Close()
+
+	s, err := store.NewEventStore(store.EventStoreOptions{Dir: dir})
+	if err != nil {
+		t.Fatalf("failed to open event store: %v", err)
+	}
+	defer s.Close()
+	// Snapshot com o estado depois do evento 3: não serve para instantes antes do evento 2
+	if err := s.Snapshot(); err != nil {
+		t.Fatalf("failed to snapshot: %v", err)
+	}
+
+	got, err := s.GetAsOf(ctx, "skewed", t0.Add(90*time.Minute))
+	if err != nil || got.Status != "completed" || got.Title != "Skewed" {
+		t.Errorf("expected the event after the skewed one to be applied, got %+v (%v)", got, err)
+	}
+	got, err = s.GetAsOf(ctx, "skewed", t0.Add(3*time.Hour))
+	if err != nil || got.Status != "completed" || got.Title != "Later" || got.Version != 3 {
+		t.Errorf("expected the current state, got %+v (%v)", got, err)
+	}
+
+	// Patch vazio não grava evento nem muda a versão
+	before := countLines(t, filepath.Join(dir, "events.jsonl"))
+	unchanged, err := s.UpdateWith(ctx, "skewed", func(models.Task) (map[string]interface{}, error) {
+		return map[string]interface{}{}, nil
+	})
+	if err != nil || unchanged.Version != 3 {
+		t.Errorf("expected the task unchanged at version 3, got %+v (%v)", unchanged, err)
+	}
+	if after := countLines(t, filepath.Join(dir, "events.jsonl")); after != before {
+		t.Errorf("expected no event for an empty patch, got %d new lines", after-before)
+	}
+}
+
+func TestGetTaskAsOf(t *testing.T) {
+	es, err := store.NewEventStore(store.EventStoreOptions{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("failed to open event store: %v", err)
+	}
+	defer es.Close()
+	ctx := context.Background()
+	task, _ := es.Create(ctx, models.Task{Title: "Old title", Status: "pending"})
+	before := time.Now().UTC()
+	time.Sleep(2 * time.Millisecond)
+	es.Update(ctx, task.ID, map[string]interface{}{"title": "New title"})
+
+	get := func(s store.Store, asOf string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/tasks/"+task.ID+"?as_of="+url.QueryEscape(asOf), nil)
+		r = mux.SetURLVars(r, map[string]string{"id": task.ID})
+		w := httptest.NewRecorder()
+		handlers.NewAPI(s, &models.NoOpLogger{}).GetTask(w, r)
+		return w
+	}
+
+	w := get(es, before.Format(time.RFC3339Nano))
+	var old models.Task
+	json.NewDecoder(w.Body).Decode(&old)
+	if w.Code != http.StatusOK || old.Title != "Old title" || w.Header().Get("ETag") != "" {
+		t.Errorf("expected old title without ETag, got %d %+v", w.Code, old)
+	}
+	if w := get(es, "yesterday"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid as_of, got %d", w.Code)
+	}
+	if w := get(store.New(), "2026-05-01"); w.Code != http.StatusNotImplemented {
+		t.Errorf("expected 501 from memory store, got %d", w.Code)
+	}
+}
@@ -0,0 +1,154 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/tasksapi/models"
+	"example.com/tasksapi/store"
+)
+
+func countLines(t *testing.T, path string) int {
+	return countRecords(t, path, "")
+}
+
+// countRecords conta as linhas do log que começam com prefix
+func countRecords(t *testing.T, path, prefix string) int {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", path, err)
+	}
+	defer f.Close()
+	n := 0
+	for sc := bufio.NewScanner(f); sc.Scan(); {
+		if strings.HasPrefix(sc.Text(), prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestFileStorePersistsAcrossRestarts(t *testing.T) {
+	for _, mode := range []store.FileSyncMode{store.FileSyncAlways, store.FileSyncInterval, store.FileSyncNever} {
+		t.Run(string(mode), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "tasks.jsonl")
+			opts := store.FileStoreOptions{Path: path, Sync: mode, SyncInterval: 10 * time.Millisecond}
+			ctx := context.Background()
+			s, err := store.NewFileStore(opts)
+			if err != nil {
+				t.Fatalf("failed to open file store: %v", err)
+			}
+
+			kept, _ := s.Create(ctx, models.Task{Title: "Kept", Status: "pending", Priority: "high"})
+			s.Update(ctx, kept.ID, map[string]interface{}{"status": "in_progress", "priority": nil})
+			trashed, _ := s.Create(ctx, models.Task{Title: "Trashed", Status: "pending"})
+			s.Delete(ctx, trashed.ID)
+			purged, _ := s.Create(ctx, models.Task{Title: "Purged", Status: "pending"})
+			s.Purge(ctx, purged.ID)
+			if err := s.Close(); err != nil {
+				t.Fatalf("failed to close: %v", err)
+			}
+
+			reopened, err := store.NewFileStore(opts)
+			if err != nil {
+				t.Fatalf("failed to reopen file store: %v", err)
+			}
+			defer reopened.Close()
+			got, err := reopened.Get(ctx, kept.ID)
+			if err != nil || got.Status != "in_progress" || got.Priority != "" || got.Version != 2 {
+				t.Errorf("unexpected task after restart: %+v (%v)", got, err)
+			}
+			if _, err := reopened.Get(ctx, trashed.ID); !errors.Is(err, store.ErrNotFound) {
+				t.Errorf("expected trashed task to stay in trash, got %v", err)
+			}
+			if _, err := reopened.Restore(ctx, trashed.ID); err != nil {
+				t.Errorf("expected trashed task to be restorable, got %v", err)
+			}
+			if err := reopened.Purge(ctx, purged.ID); !errors.Is(err, store.ErrNotFound) {
+				t.Errorf("expected purged task to be gone, got %v", err)
+			}
+			page, _ := reopened.Query(ctx, models.TaskQuery{Search: "kept"})
+			if page.Total != 1 {
+				t.Errorf("expected search index to be rebuilt, got %d results", page.Total)
+			}
+		})
+	}
+}
+
+func TestFileStoreCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.jsonl")
+	opts := store.FileStoreOptions{Path: path, CompactThreshold: 10}
+	ctx := context.Background()
+	s, _ := store.NewFileStore(opts)
+
+	task, _ := s.Create(ctx, models.Task{Title: "Busy", Status: "pending"})
+	for i := 0; i < 25; i++ {
+		if _, err := s.Update(ctx, task.ID, map[string]interface{}{"description": "rev"}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	// A compactação descarta os estados antigos da tarefa, mas mantém o histórico
+	if n := countRecords(t, path, `{"task":`); n >= 10 {
+		t.Errorf("expected log to be compacted, got %d task records", n)
+	}
+	if err := s.Compact(); err != nil {
+		t.Fatalf("failed to compact: %v", err)
+	}
+	if n := countRecords(t, path, `{"task":`); n != 1 {
+		t.Errorf("expected 1 task record after explicit compaction, got %d", n)
+	}
+	if n := countRecords(t, path, `{"audit":`); n != 26 {
+		t.Errorf("expected the 26 history entries to survive compaction, got %d", n)
+	}
+	s.Close()
+
+	reopened, _ := store.NewFileStore(opts)
+	defer reopened.Close()
+	got, err := reopened.Get(ctx, task.ID)
+	if err != nil || got.Version != 26 {
+		t.Errorf("expected version 26 after compaction and restart, got %+v (%v)", got, err)
+	}
+}
+
+func TestFileStoreCrashRecovery(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tasks.jsonl")
+	ctx := context.Background()
+	s, _ := store.NewFileStore(store.FileStoreOptions{Path: path})
+	task, _ := s.Create(ctx, models.Task{Title: "Survivor", Status: "pending"})
+	s.Close()
+
+	// Crash no meio de uma escrita e de uma compactação
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	f.WriteString(`{"task":{"id":"` + task.ID + `","title":"Half`)
+	f.Close()
+	os.WriteFile(path+".tmp", []byte(`{"task":`), 0o644)
+
+	reopened, err := store.NewFileStore(store.FileStoreOptions{Path: path})
+	if err != nil {
+		t.Fatalf("expected recovery, got %v", err)
+	}
+	got, err := reopened.Get(ctx, task.ID)
+	if err != nil || got.Title != "Survivor" {
+		t.Errorf("expected last complete state, got %+v (%v)", got, err)
+	}
+	if _, err := reopened.Update(ctx, task.ID, map[string]interface{}{"title": "After crash"}); err != nil {
+		t.Errorf("expected writes after recovery, got %v", err)
+	}
+	reopened.Close()
+	if n := countLines(t, path); n != 2 {
+		t.Errorf("expected partial record to be dropped, got %d records", n)
+	}
+
+	// Uma linha completa inválida é corrupção, não crash: a abertura falha
+	os.WriteFile(path, []byte("not json\n"), 0o644)
+	if _, err := store.NewFileStore(store.FileStoreOptions{Path: path}); err == nil {
+		t.Error("expected error for corrupted log")
+	}
+}
@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"example.com/tasksapi/models"
+)
+
+func TestParseFilters(t *testing.T) {
+	t.Run("multi-value and negation", func(t *testing.T) {
+		values, _ := url.ParseQuery("status=pending,in_progress&priority!=low&due_date!=null")
+		filters, err := models.ParseFilters(values)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(filters) != 3 {
+			t.Fatalf("expected 3 filters, got %d", len(filters))
+		}
+		if filters[0].Op != models.FilterIn || len(filters[0].Values) != 2 {
+			t.Errorf("expected status in [pending in_progress], got %+v", filters[0])
+		}
+		if filters[1].Op != models.FilterNotIn || filters[1].Values[0] != "low" {
+			t.Errorf("expected priority not in [low], got %+v", filters[1])
+		}
+		if filters[2].Op != models.FilterNotNull {
+			t.Errorf("expected due_date not null, got %+v", filters[2])
+		}
+	})
+
+	t.Run("ranges", func(t *testing.T) {
+		values, _ := url.ParseQuery("due_before=2027-01-01&created_after=2026-05-01T10:00:00Z&updated_since=2026-05-01")
+		filters, err := models.ParseFilters(values)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(filters) != 3 {
+			t.Fatalf("expected 3 filters, got %d", len(filters))
+		}
+	})
+
+	t.Run("invalid values", func(t *testing.T) {
+		for _, raw := range []string{"due_before=tomorrow", "created_after=2026/05/01", "due_date=2026-13-01,null"} {
+			values, _ := url.ParseQuery(raw)
+			if _, err := models.ParseFilters(values); err == nil {
+				t.Errorf("%s: expected validation error", raw)
+			}
+		}
+	})
+}
+
+func TestTaskFilterMatch(t *testing.T) {
+	due := models.NewDate(2027, 1, 15)
+	updated := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	task := models.Task{
+		Status:    "in_progress",
+		DueDate:   &due,
+		CreatedAt: time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC),
+		UpdatedAt: &updated,
+	}
+
+	cases := []struct {
+		filter   models.TaskFilter
+		expected bool
+	}{
+		{models.TaskFilter{Field: "status", Op: models.FilterIn, Values: []string{"pending", "in_progress"}}, true},
+		{models.TaskFilter{Field: "status", Op: models.FilterNotIn, Values: []string{"completed"}}, true},
+		{models.TaskFilter{Field: "priority", Op: models.FilterIn, Values: []string{"high", "null"}}, true},
+		{models.TaskFilter{Field: "priority", Op: models.FilterNotIn, Values: []string{"null"}}, false},
+		{models.TaskFilter{Field: "due_date", Op: models.FilterLt, Value: "2027-01-15"}, false},
+		{models.TaskFilter{Field: "due_date", Op: models.FilterGt, Value: "2027-01-14"}, true},
+		{models.TaskFilter{Field: "created_at", Op: models.FilterGt, Value: "2026-05-01T09:00:00Z"}, false},
+		{models.TaskFilter{Field: "updated_at", Op: models.FilterGte, Value: "2026-06-01T12:00:00Z"}, true},
+		{models.TaskFilter{Field: "priority", Op: models.FilterLt, Value: "high"}, false},
+	}
+	for _, c := range cases {
+		if got := c.filter.Match(task); got != c.expected {
+			t.Errorf("%+v: expected %v, got %v", c.filter, c.expected, got)
+		}
+	}
+}
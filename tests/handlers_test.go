@@ -2,14 +2,19 @@ package tests
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 
 	"example.com/tasksapi/handlers"
 	"example.com/tasksapi/models"
 	"example.com/tasksapi/store"
+	"github.com/gorilla/mux"
 )
 
 func TestListTasksReturns200(t *testing.T) {
@@ -219,3 +224,294 @@ func TestListTasksFilterByNullPriority(t *testing.T) {
 		t.Errorf("expected total_items=1, got %d", response.TotalItems)
 	}
 }
+
+// failingStore simula um backend indisponível nas operações de escrita
+type failingStore struct {
+	store.Store
+}
+
+func (f *failingStore) Create(ctx context.Context, t models.Task) (models.Task, error) {
+	return models.Task{}, errors.New("connection refused")
+}
+
+func (f *failingStore) Delete(ctx context.Context, id string) error {
+	return context.DeadlineExceeded
+}
+
+func TestCreateTaskStoreFailureReturns500(t *testing.T) {
+	h := handlers.NewAPI(&failingStore{Store: store.New()}, &models.NoOpLogger{})
+
+	taskJSON := `{"title":"Test Task","status":"pending"}`
+	r := httptest.NewRequest("POST", "/tasks", bytes.NewBufferString(taskJSON))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	h.CreateTask(w, r)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500, got %d", w.Code)
+	}
+
+	var errResp models.APIError
+	json.NewDecoder(w.Body).Decode(&errResp)
+	if errResp.Code != 500 {
+		t.Errorf("expected error code 500, got %d", errResp.Code)
+	}
+}
+
+func TestDeleteTaskStoreTimeoutReturns504(t *testing.T) {
+	h := handlers.NewAPI(&failingStore{Store: store.New()}, &models.NoOpLogger{})
+
+	r := httptest.NewRequest("DELETE", "/tasks/any-id", nil)
+	w := httptest.NewRecorder()
+
+	h.DeleteTask(w, r)
+
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("expected 504, got %d", w.Code)
+	}
+}
+
+func createTaskViaAPI(t *testing.T, h *handlers.API, taskJSON string) models.Task {
+	t.Helper()
+	r := httptest.NewRequest("POST", "/tasks", bytes.NewBufferString(taskJSON))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.CreateTask(w, r)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("failed to create task: %d %s", w.Code, w.Body.String())
+	}
+	var task models.Task
+	json.NewDecoder(w.Body).Decode(&task)
+	return task
+}
+
+func listTasksViaAPI(t *testing.T, h *handlers.API, query string) models.TaskListResponse {
+	t.Helper()
+	r := httptest.NewRequest("GET", "/tasks"+query, nil)
+	w := httptest.NewRecorder()
+	h.ListTasks(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("list failed: %d %s", w.Code, w.Body.String())
+	}
+	var response models.TaskListResponse
+	json.NewDecoder(w.Body).Decode(&response)
+	return response
+}
+
+func TestListTasksCursorPagination(t *testing.T) {
+	s := store.New()
+	h := handlers.NewAPI(s, &models.NoOpLogger{})
+
+	var created []models.Task
+	for _, title := range []string{"Page 1", "Page 2", "Page 3", "Page 4", "Page 5"} {
+		created = append(created, createTaskViaAPI(t, h, `{"title":"`+title+`","status":"pending"}`))
+	}
+
+	first := listTasksViaAPI(t, h, "?limit=2")
+	if len(first.Tasks) != 2 || first.TotalItems != 5 {
+		t.Fatalf("expected 2 of 5 tasks, got %d of %d", len(first.Tasks), first.TotalItems)
+	}
+	if first.NextCursor == "" {
+		t.Fatal("expected next_cursor on first page")
+	}
+
+	// Alterações concorrentes não podem duplicar nem pular tarefas já existentes
+	if err := s.Delete(context.Background(), created[0].ID); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	createTaskViaAPI(t, h, `{"title":"Page 6","status":"pending"}`)
+
+	seen := []string{first.Tasks[0].Title, first.Tasks[1].Title}
+	cursor := first.NextCursor
+	for cursor != "" {
+		page := listTasksViaAPI(t, h, "?limit=2&cursor="+cursor)
+		for _, task := range page.Tasks {
+			seen = append(seen, task.Title)
+		}
+		cursor = page.NextCursor
+	}
+
+	expected := []string{"Page 1", "Page 2", "Page 3", "Page 4", "Page 5", "Page 6"}
+	if len(seen) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, seen)
+	}
+	for i := range expected {
+		if seen[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected, seen)
+			break
+		}
+	}
+}
+
+func TestListTasksInvalidCursor(t *testing.T) {
+	h := handlers.NewAPI(store.New(), &models.NoOpLogger{})
+
+	r := httptest.NewRequest("GET", "/tasks?cursor=not-a-cursor", nil)
+	w := httptest.NewRecorder()
+	h.ListTasks(w, r)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid cursor, got %d", w.Code)
+	}
+}
+
+func TestListTasksSort(t *testing.T) {
+	h := handlers.NewAPI(store.New(), &models.NoOpLogger{})
+	createTaskViaAPI(t, h, `{"title":"Low","status":"pending","priority":"low","due_date":"2027-03-01"}`)
+	createTaskViaAPI(t, h, `{"title":"High","status":"pending","priority":"high","due_date":"2027-02-01"}`)
+	createTaskViaAPI(t, h, `{"title":"None","status":"pending"}`)
+	createTaskViaAPI(t, h, `{"title":"Medium","status":"pending","priority":"medium","due_date":"2027-01-01"}`)
+
+	titles := func(resp models.TaskListResponse) string {
+		out := make([]string, len(resp.Tasks))
+		for i, task := range resp.Tasks {
+			out[i] = task.Title
+		}
+		return strings.Join(out, ",")
+	}
+
+	cases := []struct {
+		query    string
+		expected string
+	}{
+		{"?sort=-priority", "High,Medium,Low,None"},
+		{"?sort=priority", "Low,Medium,High,None"},
+		{"?sort=priority&nulls=first", "None,Low,Medium,High"},
+		{"?sort=due_date", "Medium,High,Low,None"},
+		{"?sort=-due_date&nulls=first", "None,Low,High,Medium"},
+		{"?sort=status,-title", "None,Medium,Low,High"},
+	}
+	for _, c := range cases {
+		if got := titles(listTasksViaAPI(t, h, c.query)); got != c.expected {
+			t.Errorf("%s: expected %s, got %s", c.query, c.expected, got)
+		}
+	}
+
+	// Cursor percorre a mesma ordenação página a página
+	var pages []string
+	query := "?sort=-priority&limit=1"
+	for {
+		resp := listTasksViaAPI(t, h, query)
+		pages = append(pages, titles(resp))
+		if resp.NextCursor == "" {
+			break
+		}
+		query = "?sort=-priority&limit=1&cursor=" + resp.NextCursor
+	}
+	if got := strings.Join(pages, ","); got != "High,Medium,Low,None" {
+		t.Errorf("expected paged sort High,Medium,Low,None, got %s", got)
+	}
+}
+
+func TestListTasksSortValidation(t *testing.T) {
+	h := handlers.NewAPI(store.New(), &models.NoOpLogger{})
+	for _, query := range []string{"?sort=unknown", "?sort=title,-title", "?nulls=middle"} {
+		r := httptest.NewRequest("GET", "/tasks"+query, nil)
+		w := httptest.NewRecorder()
+		h.ListTasks(w, r)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, w.Code)
+		}
+	}
+
+	// Cursor gerado para outra ordenação é rejeitado
+	createTaskViaAPI(t, h, `{"title":"One","status":"pending"}`)
+	createTaskViaAPI(t, h, `{"title":"Two","status":"pending"}`)
+	resp := listTasksViaAPI(t, h, "?sort=title&limit=1")
+	r := httptest.NewRequest("GET", "/tasks?sort=-title&cursor="+resp.NextCursor, nil)
+	w := httptest.NewRecorder()
+	h.ListTasks(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for cursor from another sort, got %d", w.Code)
+	}
+}
+
+func TestListTasksMultiValueAndRangeFilters(t *testing.T) {
+	h := handlers.NewAPI(store.New(), &models.NoOpLogger{})
+	createTaskViaAPI(t, h, `{"title":"Pending","status":"pending","due_date":"2027-01-10"}`)
+	createTaskViaAPI(t, h, `{"title":"Running","status":"in_progress","due_date":"2027-02-10"}`)
+	createTaskViaAPI(t, h, `{"title":"Done","status":"completed","due_date":"2027-03-10"}`)
+	createTaskViaAPI(t, h, `{"title":"Undated","status":"cancelled"}`)
+
+	cases := []struct {
+		query    string
+		expected int
+	}{
+		{"?status=pending,in_progress", 2},
+		{"?status=pending&status=completed", 2},
+		{"?status!=completed", 3},
+		{"?status!=completed,cancelled", 2},
+		{"?due_before=2027-02-10", 1},
+		{"?due_after=2027-01-10&due_before=2027-12-31", 2},
+		{"?due_date=2027-01-10,null", 2},
+		{"?created_after=2000-01-01", 4},
+		{"?updated_since=2000-01-01", 0},
+	}
+	for _, c := range cases {
+		resp := listTasksViaAPI(t, h, c.query)
+		if resp.TotalItems != c.expected {
+			t.Errorf("%s: expected %d tasks, got %d", c.query, c.expected, resp.TotalItems)
+		}
+	}
+}
+
+func TestUpdateTaskFormClearsEmptyFields(t *testing.T) {
+	s := store.New()
+	h := handlers.NewAPI(s, &models.NoOpLogger{})
+	task := createTaskViaAPI(t, h, `{"title":"Form task","description":"Old","status":"pending","priority":"low","due_date":"2030-03-01"}`)
+
+	// Campo enviado vazio no formulário limpa o valor, como null no JSON
+	form := url.Values{"title": {"Form task"}, "status": {"in_progress"}, "description": {""}, "priority": {"high"}, "due_date": {""}}
+	r := httptest.NewRequest("PUT", "/tasks/"+task.ID, strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r = mux.SetURLVars(r, map[string]string{"id": task.ID})
+	w := httptest.NewRecorder()
+	h.UpdateTask(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var updated models.Task
+	json.NewDecoder(w.Body).Decode(&updated)
+	if updated.Description != "" || updated.DueDate != nil || updated.Priority != "high" || updated.Status != "in_progress" {
+		t.Errorf("unexpected task after form update: %+v", updated)
+	}
+}
+
+func TestUpdateTaskAcceptsItsOwnRepresentation(t *testing.T) {
+	s := store.New()
+	h := handlers.NewAPI(s, &models.NoOpLogger{})
+	// Tarefa sem prioridade e atrasada: o GET serializa "priority": "" e um prazo no passado
+	overdue, _ := models.ParseDateOnly("2020-01-15")
+	task, err := s.Create(context.Background(), models.Task{Title: "Overdue task", Status: "pending", DueDate: &overdue})
+	if err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	body, _ := json.Marshal(task)
+
+	put := func(body []byte) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("PUT", "/tasks/"+task.ID, bytes.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		r = mux.SetURLVars(r, map[string]string{"id": task.ID})
+		w := httptest.NewRecorder()
+		h.UpdateTask(w, r)
+		return w
+	}
+
+	w := put(body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected PUT of the GET body to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+	var updated models.Task
+	json.NewDecoder(w.Body).Decode(&updated)
+	if updated.Priority != "" || updated.DueDate == nil || updated.DueDate.String() != "2020-01-15" {
+		t.Errorf("expected priority and due date to be kept, got %+v", updated)
+	}
+
+	// Mudar o prazo para outra data no passado continua proibido
+	w = put([]byte(`{"title":"Overdue task","status":"pending","due_date":"2020-02-01"}`))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 moving the due date to the past, got %d", w.Code)
+	}
+}
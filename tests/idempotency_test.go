@@ -0,0 +1,184 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"example.com/tasksapi/handlers"
+	"example.com/tasksapi/models"
+	"example.com/tasksapi/router"
+	"example.com/tasksapi/store"
+)
+
+func idempotentPost(t *testing.T, h http.Handler, key, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest("POST", "/tasks", bytes.NewBufferString(body))
+	r.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		r.Header.Set(handlers.IdempotencyKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	api := handlers.NewAPI(store.New(), &models.NoOpLogger{})
+	keys := store.NewMemoryIdempotencyStore()
+	h := handlers.NewIdempotency(keys, time.Hour, &models.NoOpLogger{}).Middleware(http.HandlerFunc(api.CreateTask))
+	body := `{"title": "Retried", "status": "pending"}`
+
+	t.Run("retry replays the original response", func(t *testing.T) {
+		first := idempotentPost(t, h, "key-1", body)
+		second := idempotentPost(t, h, "key-1", body)
+		if first.Code != http.StatusCreated || second.Code != http.StatusCreated {
+			t.Fatalf("expected 201 twice, got %d and %d", first.Code, second.Code)
+		}
+		if first.Body.String() != second.Body.String() {
+			t.Errorf("expected the same body, got %s and %s", first.Body, second.Body)
+		}
+		if second.Header().Get(handlers.ReplayedHeader) != "true" || first.Header().Get(handlers.ReplayedHeader) != "" {
+			t.Error("expected only the retry to be marked as replayed")
+		}
+		if second.Header().Get("ETag") != first.Header().Get("ETag") {
+			t.Errorf("expected the ETag to be replayed, got %q", second.Header().Get("ETag"))
+		}
+		if tasks := listTasksViaAPI(t, api, ""); tasks.TotalItems != 1 {
+			t.Errorf("expected a single task, got %d", tasks.TotalItems)
+		}
+	})
+
+	t.Run("same key with a different body", func(t *testing.T) {
+		w := idempotentPost(t, h, "key-1", `{"title": "Other", "status": "pending"}`)
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected 422, got %d", w.Code)
+		}
+	})
+
+	t.Run("errors are replayed too", func(t *testing.T) {
+		invalid := `{"title": "No", "status": "pending"}`
+		if w := idempotentPost(t, h, "key-2", invalid); w.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d", w.Code)
+		}
+		w := idempotentPost(t, h, "key-2", invalid)
+		if w.Code != http.StatusBadRequest || w.Header().Get(handlers.ReplayedHeader) != "true" {
+			t.Errorf("expected replayed 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("request still in progress", func(t *testing.T) {
+		// Reserva a chave com a mesma impressão digital, como faria uma requisição em andamento
+		inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w2 := idempotentPost(t, h, "key-3", body)
+			if w2.Code != http.StatusConflict {
+				t.Errorf("expected 409 for a concurrent retry, got %d", w2.Code)
+			}
+			api.CreateTask(w, r)
+		})
+		nested := handlers.NewIdempotency(keys, time.Hour, &models.NoOpLogger{}).Middleware(inner)
+		if w := idempotentPost(t, nested, "key-3", body); w.Code != http.StatusCreated {
+			t.Errorf("expected 201, got %d", w.Code)
+		}
+	})
+
+	t.Run("without the header nothing is stored", func(t *testing.T) {
+		before := listTasksViaAPI(t, api, "").TotalItems
+		idempotentPost(t, h, "", body)
+		idempotentPost(t, h, "", body)
+		if after := listTasksViaAPI(t, api, "").TotalItems; after != before+2 {
+			t.Errorf("expected 2 new tasks, got %d", after-before)
+		}
+	})
+}
+
+func TestIdempotencyKeyExpires(t *testing.T) {
+	api := handlers.NewAPI(store.New(), &models.NoOpLogger{})
+	h := handlers.NewIdempotency(store.NewMemoryIdempotencyStore(), 10*time.Millisecond, &models.NoOpLogger{}).Middleware(http.HandlerFunc(api.CreateTask))
+	body := `{"title": "Expiring", "status": "pending"}`
+
+	var first, second models.Task
+	json.NewDecoder(idempotentPost(t, h, "key", body).Body).Decode(&first)
+	time.Sleep(20 * time.Millisecond)
+	w := idempotentPost(t, h, "key", body)
+	json.NewDecoder(w.Body).Decode(&second)
+	if w.Header().Get(handlers.ReplayedHeader) != "" || first.ID == second.ID {
+		t.Errorf("expected an expired key to create a new task, got %s twice", first.ID)
+	}
+}
+
+func TestMemoryIdempotencyStore(t *testing.T) {
+	testIdempotencyStore(t, store.NewMemoryIdempotencyStore())
+}
+
+func testIdempotencyStore(t *testing.T, keys store.IdempotencyStore) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	rec := models.IdempotencyRecord{Key: "store-key", Fingerprint: "a", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+
+	if existing, err := keys.Reserve(ctx, rec); err != nil || existing != nil {
+		t.Fatalf("expected a free key, got %+v %v", existing, err)
+	}
+	existing, err := keys.Reserve(ctx, rec)
+	if err != nil || existing == nil || existing.Completed() {
+		t.Fatalf("expected the key in progress, got %+v %v", existing, err)
+	}
+
+	rec.Status = http.StatusCreated
+	rec.Header = http.Header{"Etag": {`"1"`}}
+	rec.Body = []byte(`{"id":"1"}`)
+	if err := keys.Complete(ctx, rec); err != nil {
+		t.Fatalf("complete failed: %v", err)
+	}
+	existing, err = keys.Reserve(ctx, rec)
+	if err != nil || existing == nil || existing.Status != http.StatusCreated || string(existing.Body) != `{"id":"1"}` || existing.Header.Get("ETag") != `"1"` {
+		t.Fatalf("expected the stored response, got %+v %v", existing, err)
+	}
+
+	if err := keys.Release(ctx, rec.Key); err != nil {
+		t.Fatalf("release failed: %v", err)
+	}
+	if existing, err := keys.Reserve(ctx, rec); err != nil || existing != nil {
+		t.Errorf("expected a released key to be free, got %+v %v", existing, err)
+	}
+
+	expired := models.IdempotencyRecord{Key: "expired-key", Fingerprint: "a", CreatedAt: now, ExpiresAt: now.Add(-time.Second)}
+	keys.Reserve(ctx, expired)
+	if existing, err := keys.Reserve(ctx, expired); err != nil || existing != nil {
+		t.Errorf("expected an expired key to be free, got %+v %v", existing, err)
+	}
+}
+
+func TestRouterIdempotencyKey(t *testing.T) {
+	t.Setenv("TRASH_RETENTION", "0")
+	t.Setenv("STORE_BACKEND", "memory")
+
+	t.Run("enabled by default", func(t *testing.T) {
+		t.Setenv("IDEMPOTENCY_TTL", "")
+		r, err := router.Open(&models.NoOpLogger{})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		first := idempotentPost(t, r, "router-key", `{"title": "Once", "status": "pending"}`)
+		second := idempotentPost(t, r, "router-key", `{"title": "Once", "status": "pending"}`)
+		if first.Body.String() != second.Body.String() || second.Header().Get(handlers.ReplayedHeader) != "true" {
+			t.Errorf("expected the retry to be replayed, got %s and %s", first.Body, second.Body)
+		}
+	})
+
+	t.Run("disabled with zero TTL", func(t *testing.T) {
+		t.Setenv("IDEMPOTENCY_TTL", "0")
+		r, err := router.Open(&models.NoOpLogger{})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		idempotentPost(t, r, "router-key", `{"title": "Twice", "status": "pending"}`)
+		if w := idempotentPost(t, r, "router-key", `{"title": "Twice", "status": "pending"}`); w.Header().Get(handlers.ReplayedHeader) != "" {
+			t.Error("expected the header to be ignored")
+		}
+	})
+}
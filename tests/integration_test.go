@@ -15,6 +15,7 @@ import (
 )
 
 func TestIntegrationUpdateCompletedTask(t *testing.T) {
+	t.Setenv("STORE_BACKEND", "memory")
 	r := router.NewWithLogger(&models.NoOpLogger{})
 
 	// Create a task
@@ -33,8 +34,8 @@ func TestIntegrationUpdateCompletedTask(t *testing.T) {
 
 	// Update to completed
 	updateToCompleted := `{"status":"completed"}`
-	req = httptest.NewRequest("PUT", "/tasks/"+created.ID, bytes.NewBufferString(updateToCompleted))
-	req.Header.Set("Content-Type", "application/json")
+	req = httptest.NewRequest("PATCH", "/tasks/"+created.ID, bytes.NewBufferString(updateToCompleted))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
 	w = httptest.NewRecorder()
 	r.ServeHTTP(w, req)
 
@@ -44,8 +45,8 @@ func TestIntegrationUpdateCompletedTask(t *testing.T) {
 
 	// Try to update completed task - should fail with 409
 	updateCompleted := `{"title":"Try to Update"}`
-	req = httptest.NewRequest("PUT", "/tasks/"+created.ID, bytes.NewBufferString(updateCompleted))
-	req.Header.Set("Content-Type", "application/json")
+	req = httptest.NewRequest("PATCH", "/tasks/"+created.ID, bytes.NewBufferString(updateCompleted))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
 	w = httptest.NewRecorder()
 	r.ServeHTTP(w, req)
 
@@ -64,6 +65,7 @@ func TestIntegrationUpdateCompletedTask(t *testing.T) {
 }
 
 func TestIntegrationCreateTaskInvalidStatus(t *testing.T) {
+	t.Setenv("STORE_BACKEND", "memory")
 	r := router.NewWithLogger(&models.NoOpLogger{})
 
 	createJSON := `{"title":"Test","status":"invalid_status"}`
@@ -78,6 +80,7 @@ func TestIntegrationCreateTaskInvalidStatus(t *testing.T) {
 }
 
 func TestIntegrationFullCRUD(t *testing.T) {
+	t.Setenv("STORE_BACKEND", "memory")
 	r := router.NewWithLogger(&models.NoOpLogger{})
 
 	// Create
@@ -104,7 +107,7 @@ func TestIntegrationFullCRUD(t *testing.T) {
 	}
 
 	// Update
-	updateJSON := `{"title":"Updated Title"}`
+	updateJSON := `{"title":"Updated Title","status":"pending","priority":"high"}`
 	req = httptest.NewRequest("PUT", "/tasks/"+task.ID, bytes.NewBufferString(updateJSON))
 	req.Header.Set("Content-Type", "application/json")
 	w = httptest.NewRecorder()
@@ -148,7 +151,7 @@ func TestIntegrationTaskWithDueDate(t *testing.T) {
 	r := mux.NewRouter()
 	r.HandleFunc("/tasks", api.CreateTask).Methods("POST")
 	r.HandleFunc("/tasks/{id}", api.GetTask).Methods("GET")
-	r.HandleFunc("/tasks/{id}", api.UpdateTask).Methods("PUT")
+	r.HandleFunc("/tasks/{id}", api.PatchTask).Methods("PATCH")
 
 	// Create task with due_date
 	createJSON := `{"title":"Task with Due Date","status":"pending","due_date":"2026-12-31"}`
@@ -188,8 +191,8 @@ func TestIntegrationTaskWithDueDate(t *testing.T) {
 
 	// Update due_date
 	updateJSON := `{"due_date":"2027-01-15"}`
-	req = httptest.NewRequest("PUT", "/tasks/"+task.ID, bytes.NewBufferString(updateJSON))
-	req.Header.Set("Content-Type", "application/json")
+	req = httptest.NewRequest("PATCH", "/tasks/"+task.ID, bytes.NewBufferString(updateJSON))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
 	w = httptest.NewRecorder()
 	r.ServeHTTP(w, req)
 
@@ -275,3 +278,191 @@ func TestIntegrationFilterByDueDateAndPriority(t *testing.T) {
 		t.Errorf("expected total_items=1, got %d", response.TotalItems)
 	}
 }
+
+func TestIntegrationOptimisticConcurrency(t *testing.T) {
+	s := store.New()
+	api := handlers.NewAPI(s, &models.NoOpLogger{})
+
+	r := mux.NewRouter()
+	r.HandleFunc("/tasks", api.CreateTask).Methods("POST")
+	r.HandleFunc("/tasks/{id}", api.GetTask).Methods("GET")
+	r.HandleFunc("/tasks/{id}", api.UpdateTask).Methods("PUT")
+	r.HandleFunc("/tasks/{id}", api.DeleteTask).Methods("DELETE")
+
+	req := httptest.NewRequest("POST", "/tasks", bytes.NewBufferString(`{"title":"Versioned","status":"pending"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var task models.Task
+	json.NewDecoder(w.Body).Decode(&task)
+	if task.Version != 1 || w.Header().Get("ETag") != `"1"` {
+		t.Fatalf("expected version 1 and ETag \"1\", got %d and %s", task.Version, w.Header().Get("ETag"))
+	}
+
+	// Primeiro cliente atualiza com a versão correta
+	req = httptest.NewRequest("PUT", "/tasks/"+task.ID, bytes.NewBufferString(`{"title":"First writer","status":"pending"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", `"1"`)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if w.Header().Get("ETag") != `"2"` {
+		t.Errorf("expected ETag \"2\" after update, got %s", w.Header().Get("ETag"))
+	}
+
+	// Segundo cliente ainda tem a versão 1
+	req = httptest.NewRequest("PUT", "/tasks/"+task.ID, bytes.NewBufferString(`{"title":"Second writer","status":"pending"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", `"1"`)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusPreconditionFailed {
+		t.Errorf("expected 412 for stale If-Match, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("DELETE", "/tasks/"+task.ID, nil)
+	req.Header.Set("If-Match", `"1"`)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusPreconditionFailed {
+		t.Errorf("expected 412 for stale delete, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/tasks/"+task.ID, nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var current models.Task
+	json.NewDecoder(w.Body).Decode(&current)
+	if current.Title != "First writer" || current.Version != 2 {
+		t.Errorf("expected first writer's update at version 2, got %q at %d", current.Title, current.Version)
+	}
+
+	req = httptest.NewRequest("DELETE", "/tasks/"+task.ID, nil)
+	req.Header.Set("If-Match", w.Header().Get("ETag"))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("expected 204 for delete with current ETag, got %d", w.Code)
+	}
+}
+
+func TestIntegrationPatchAndReplace(t *testing.T) {
+	s := store.New()
+	api := handlers.NewAPI(s, &models.NoOpLogger{})
+
+	r := mux.NewRouter()
+	r.HandleFunc("/tasks", api.CreateTask).Methods("POST")
+	r.HandleFunc("/tasks/{id}", api.UpdateTask).Methods("PUT")
+	r.HandleFunc("/tasks/{id}", api.PatchTask).Methods("PATCH")
+
+	send := func(method, path, contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("POST", "/tasks", "application/json", `{"title":"Patch me","description":"Details","status":"pending","priority":"high","due_date":"2030-01-10"}`)
+	var task models.Task
+	json.NewDecoder(w.Body).Decode(&task)
+
+	// Merge patch: null limpa o campo, campos ausentes ficam como estão
+	w = send("PATCH", "/tasks/"+task.ID, models.MergePatchContentType, `{"due_date":null,"status":"in_progress"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 for merge patch, got %d: %s", w.Code, w.Body.String())
+	}
+	var patched models.Task
+	json.NewDecoder(w.Body).Decode(&patched)
+	if patched.DueDate != nil || patched.Status != "in_progress" || patched.Priority != "high" {
+		t.Errorf("unexpected task after merge patch: %+v", patched)
+	}
+
+	// JSON Patch: test falha contra o estado atual
+	w = send("PATCH", "/tasks/"+task.ID, models.JSONPatchContentType, `[{"op":"test","path":"/status","value":"pending"},{"op":"remove","path":"/priority"}]`)
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected 409 for failed test operation, got %d", w.Code)
+	}
+	w = send("PATCH", "/tasks/"+task.ID, models.JSONPatchContentType, `[{"op":"test","path":"/status","value":"in_progress"},{"op":"remove","path":"/priority"},{"op":"replace","path":"/title","value":"Patched"}]`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 for json patch, got %d: %s", w.Code, w.Body.String())
+	}
+	json.NewDecoder(w.Body).Decode(&patched)
+	if patched.Priority != "" || patched.Title != "Patched" {
+		t.Errorf("unexpected task after json patch: %+v", patched)
+	}
+
+	w = send("PATCH", "/tasks/"+task.ID, "application/json", `{"title":"Plain JSON"}`)
+	if w.Code != http.StatusUnsupportedMediaType || w.Header().Get("Accept-Patch") == "" {
+		t.Errorf("expected 415 with Accept-Patch, got %d", w.Code)
+	}
+
+	// PUT substitui a tarefa inteira: description ausente é limpa
+	w = send("PUT", "/tasks/"+task.ID, "application/json", `{"title":"Replaced","status":"pending"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 for replacement, got %d: %s", w.Code, w.Body.String())
+	}
+	var replaced models.Task
+	json.NewDecoder(w.Body).Decode(&replaced)
+	if replaced.Title != "Replaced" || replaced.Description != "" || replaced.CreatedAt.IsZero() {
+		t.Errorf("unexpected task after replacement: %+v", replaced)
+	}
+	w = send("PUT", "/tasks/"+task.ID, "application/json", `{"description":"No title"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for replacement without title, got %d", w.Code)
+	}
+}
+
+func TestIntegrationTrashRestoreAndPurge(t *testing.T) {
+	t.Setenv("STORE_BACKEND", "memory")
+	r := router.NewWithLogger(&models.NoOpLogger{})
+
+	send := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	req := httptest.NewRequest("POST", "/tasks", bytes.NewBufferString(`{"title":"Mistake","status":"pending"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var task models.Task
+	json.NewDecoder(w.Body).Decode(&task)
+
+	if w = send("DELETE", "/tasks/"+task.ID); w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 on delete, got %d", w.Code)
+	}
+	if w = send("GET", "/tasks/"+task.ID); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for trashed task, got %d", w.Code)
+	}
+
+	var trash models.TaskListResponse
+	w = send("GET", "/tasks/trash")
+	json.NewDecoder(w.Body).Decode(&trash)
+	found := false
+	for _, tt := range trash.Tasks {
+		found = found || tt.ID == task.ID
+	}
+	if w.Code != http.StatusOK || !found {
+		t.Fatalf("expected task in trash, got %d %+v", w.Code, trash.Tasks)
+	}
+
+	if w = send("POST", "/tasks/"+task.ID+"/restore"); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 on restore, got %d", w.Code)
+	}
+	if w = send("GET", "/tasks/"+task.ID); w.Code != http.StatusOK {
+		t.Errorf("expected restored task to be visible, got %d", w.Code)
+	}
+
+	if w = send("DELETE", "/tasks/"+task.ID+"?purge=true"); w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 on purge, got %d", w.Code)
+	}
+	if w = send("POST", "/tasks/"+task.ID+"/restore"); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 restoring a purged task, got %d", w.Code)
+	}
+}
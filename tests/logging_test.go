@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"context"
 	"strings"
 	"testing"
 
@@ -47,6 +48,7 @@ func TestLoggingStore(t *testing.T) {
 	mockLogger := &MockLogger{logs: make([]string, 0)}
 	baseStore := store.New()
 	loggingStore := store.NewLoggingStore(baseStore, mockLogger)
+	ctx := context.Background()
 
 	t.Run("logs create operation", func(t *testing.T) {
 		mockLogger.logs = nil // Reset logs
@@ -55,7 +57,7 @@ func TestLoggingStore(t *testing.T) {
 			Status: "pending",
 		}
 
-		loggingStore.Create(task)
+		loggingStore.Create(ctx, task)
 
 		if !mockLogger.Contains("[STORE] Creating task") {
 			t.Error("expected create start log")
@@ -68,7 +70,7 @@ func TestLoggingStore(t *testing.T) {
 	t.Run("logs list operation", func(t *testing.T) {
 		mockLogger.logs = nil
 
-		loggingStore.List()
+		loggingStore.List(ctx)
 
 		if !mockLogger.Contains("[STORE] Listing all tasks") {
 			t.Error("expected list start log")
@@ -80,10 +82,10 @@ func TestLoggingStore(t *testing.T) {
 
 	t.Run("logs get operation", func(t *testing.T) {
 		mockLogger.logs = nil
-		task := loggingStore.Create(models.Task{Title: "Get Test", Status: "pending"})
+		task, _ := loggingStore.Create(ctx, models.Task{Title: "Get Test", Status: "pending"})
 		mockLogger.logs = nil // Reset after create
 
-		loggingStore.Get(task.ID)
+		loggingStore.Get(ctx, task.ID)
 
 		if !mockLogger.Contains("[STORE] Getting task") {
 			t.Error("expected get start log")
@@ -95,11 +97,11 @@ func TestLoggingStore(t *testing.T) {
 
 	t.Run("logs update operation", func(t *testing.T) {
 		mockLogger.logs = nil
-		task := loggingStore.Create(models.Task{Title: "Update Test", Status: "pending"})
+		task, _ := loggingStore.Create(ctx, models.Task{Title: "Update Test", Status: "pending"})
 		mockLogger.logs = nil
 
 		patch := map[string]interface{}{"title": "Updated"}
-		loggingStore.Update(task.ID, patch)
+		loggingStore.Update(ctx, task.ID, patch)
 
 		if !mockLogger.Contains("[STORE] Updating task") {
 			t.Error("expected update start log")
@@ -111,10 +113,10 @@ func TestLoggingStore(t *testing.T) {
 
 	t.Run("logs delete operation", func(t *testing.T) {
 		mockLogger.logs = nil
-		task := loggingStore.Create(models.Task{Title: "Delete Test", Status: "pending"})
+		task, _ := loggingStore.Create(ctx, models.Task{Title: "Delete Test", Status: "pending"})
 		mockLogger.logs = nil
 
-		loggingStore.Delete(task.ID)
+		loggingStore.Delete(ctx, task.ID)
 
 		if !mockLogger.Contains("[STORE] Deleting task") {
 			t.Error("expected delete start log")
@@ -127,7 +129,7 @@ func TestLoggingStore(t *testing.T) {
 	t.Run("logs errors", func(t *testing.T) {
 		mockLogger.logs = nil
 
-		_, err := loggingStore.Get("nonexistent-id")
+		_, err := loggingStore.Get(ctx, "nonexistent-id")
 
 		if err == nil {
 			t.Fatal("expected error for nonexistent task")
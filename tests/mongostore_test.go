@@ -0,0 +1,153 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"example.com/tasksapi/models"
+	"example.com/tasksapi/store"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TestMongoIndexes precisa de um MongoDB: MONGO_TEST_URI=mongodb://localhost:27017
+func TestMongoIndexes(t *testing.T) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI not set")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("failed to connect to MongoDB: %v", err)
+	}
+	db := client.Database("tasksapi_test_indexes")
+	defer func() {
+		db.Drop(ctx)
+		client.Disconnect(ctx)
+	}()
+
+	// Índice criado por uma versão anterior e que não está mais declarado
+	_, err = db.Collection("tasks").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "title", Value: 1}},
+		Options: options.Index().SetName("legacy_title"),
+	})
+	if err != nil {
+		t.Fatalf("failed to create legacy index: %v", err)
+	}
+
+	s, err := store.NewMongo(ctx, uri, "tasksapi_test_indexes", "tasks")
+	if err != nil {
+		t.Fatalf("failed to open mongo store: %v", err)
+	}
+	m := s.(*store.MongoStore)
+	defer m.Close(ctx)
+
+	names := indexNames(ctx, t, db.Collection("tasks"))
+	for _, want := range []string{"tasks_id", "tasks_status_created", "tasks_text_search", "legacy_title"} {
+		if !names[want] {
+			t.Errorf("expected index %s, got %v", want, names)
+		}
+	}
+
+	// Reaplicar é idempotente
+	if err := m.Migrate(ctx); err != nil {
+		t.Fatalf("expected migrate to be idempotent, got %v", err)
+	}
+
+	dropped, err := m.DropObsoleteIndexes(ctx)
+	if err != nil {
+		t.Fatalf("failed to drop obsolete indexes: %v", err)
+	}
+	if len(dropped) != 1 || dropped[0] != "legacy_title" {
+		t.Errorf("expected only legacy_title to be dropped, got %v", dropped)
+	}
+	if names := indexNames(ctx, t, db.Collection("tasks")); names["legacy_title"] || !names["tasks_id"] {
+		t.Errorf("unexpected indexes after drop: %v", names)
+	}
+
+	// O índice único rejeita um segundo documento com o mesmo id
+	created, _ := m.Create(ctx, models.Task{Title: "Unique", Status: "pending"})
+	if _, err := db.Collection("tasks").InsertOne(ctx, bson.M{"id": created.ID, "title": "Dup"}); !mongo.IsDuplicateKeyError(err) {
+		t.Errorf("expected duplicate key error, got %v", err)
+	}
+}
+
+func indexNames(ctx context.Context, t *testing.T, col *mongo.Collection) map[string]bool {
+	t.Helper()
+	cursor, err := col.Indexes().List(ctx)
+	if err != nil {
+		t.Fatalf("failed to list indexes: %v", err)
+	}
+	var specs []bson.M
+	if err := cursor.All(ctx, &specs); err != nil {
+		t.Fatalf("failed to decode indexes: %v", err)
+	}
+	names := map[string]bool{}
+	for _, spec := range specs {
+		names[spec["name"].(string)] = true
+	}
+	return names
+}
+
+// TestMongoIdempotencyStore precisa de um MongoDB: MONGO_TEST_URI=mongodb://localhost:27017
+func TestMongoIdempotencyStore(t *testing.T) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI not set")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	s, err := store.NewMongo(ctx, uri, "tasksapi_test_idempotency", "tasks")
+	if err != nil {
+		t.Fatalf("failed to open mongo store: %v", err)
+	}
+	m := s.(*store.MongoStore)
+	defer func() {
+		client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+		if err == nil {
+			client.Database("tasksapi_test_idempotency").Drop(ctx)
+			client.Disconnect(ctx)
+		}
+		m.Close(ctx)
+	}()
+
+	keys, err := m.IdempotencyStore(ctx, "tasks_idempotency")
+	if err != nil {
+		t.Fatalf("failed to open idempotency store: %v", err)
+	}
+	testIdempotencyStore(t, keys)
+}
+
+// TestMongoProjectStore precisa de um MongoDB: MONGO_TEST_URI=mongodb://localhost:27017
+func TestMongoProjectStore(t *testing.T) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI not set")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	s, err := store.NewMongo(ctx, uri, "tasksapi_test_projects", "tasks")
+	if err != nil {
+		t.Fatalf("failed to open mongo store: %v", err)
+	}
+	m := s.(*store.MongoStore)
+	defer func() {
+		client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+		if err == nil {
+			client.Database("tasksapi_test_projects").Drop(ctx)
+			client.Disconnect(ctx)
+		}
+		m.Close(ctx)
+	}()
+
+	projects, err := m.ProjectStore(ctx, "tasks_projects")
+	if err != nil {
+		t.Fatalf("failed to open project store: %v", err)
+	}
+	testProjectStore(t, projects)
+}
@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"example.com/tasksapi/models"
+)
+
+func TestApplyJSONPatch(t *testing.T) {
+	due := models.NewDate(2030, 1, 10)
+	task := models.Task{ID: "1", Title: "Patch", Status: "pending", Priority: "low", DueDate: &due, Version: 3}
+
+	tests := []struct {
+		name    string
+		ops     string
+		want    map[string]interface{}
+		errCode int
+	}{
+		{"replace", `[{"op":"replace","path":"/title","value":"New"}]`, map[string]interface{}{"title": "New"}, 0},
+		{"remove clears", `[{"op":"remove","path":"/due_date"}]`, map[string]interface{}{"due_date": nil}, 0},
+		{"add on empty field", `[{"op":"add","path":"/description","value":"Text"}]`, map[string]interface{}{"description": "Text"}, 0},
+		{"test set field", `[{"op":"test","path":"/priority","value":"low"},{"op":"replace","path":"/priority","value":"high"}]`, map[string]interface{}{"priority": "high"}, 0},
+		{"remove absent field", `[{"op":"remove","path":"/description"}]`, nil, 400},
+		{"replace absent field", `[{"op":"replace","path":"/description","value":"Text"}]`, nil, 400},
+		{"test absent field", `[{"op":"test","path":"/description","value":null}]`, nil, 400},
+		{"test version", `[{"op":"test","path":"/version","value":3},{"op":"replace","path":"/status","value":"completed"}]`, map[string]interface{}{"status": "completed"}, 0},
+		{"failed test", `[{"op":"test","path":"/status","value":"completed"}]`, nil, 409},
+		{"read-only field", `[{"op":"replace","path":"/version","value":9}]`, nil, 400},
+		{"unsupported op", `[{"op":"move","from":"/title","path":"/description"}]`, nil, 400},
+		{"missing path", `[{"op":"replace","path":"/nope","value":1}]`, nil, 400},
+		{"nested path", `[{"op":"add","path":"/title/x","value":1}]`, nil, 400},
+		{"missing value", `[{"op":"replace","path":"/title"}]`, nil, 400},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ops []models.PatchOperation
+			if err := json.Unmarshal([]byte(tt.ops), &ops); err != nil {
+				t.Fatalf("invalid ops: %v", err)
+			}
+			patch, err := models.ApplyJSONPatch(task, ops)
+			if tt.errCode != 0 {
+				var apiErr *models.APIError
+				if !errors.As(err, &apiErr) || apiErr.Code != tt.errCode {
+					t.Fatalf("expected error %d, got %v", tt.errCode, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if len(patch) != len(tt.want) {
+				t.Fatalf("expected patch %v, got %v", tt.want, patch)
+			}
+			for k, v := range tt.want {
+				if got, ok := patch[k]; !ok || got != v {
+					t.Errorf("expected %s=%v, got %v", k, v, got)
+				}
+			}
+		})
+	}
+}
+
+func TestReplacementPatch(t *testing.T) {
+	patch, err := models.ReplacementPatch(map[string]interface{}{"id": "ignored", "title": "Full", "status": "pending"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, field := range []string{"description", "priority", "due_date"} {
+		if v, ok := patch[field]; !ok || v != nil {
+			t.Errorf("expected %s to be cleared, got %v", field, v)
+		}
+	}
+	if _, ok := patch["id"]; ok {
+		t.Error("expected read-only fields to be ignored")
+	}
+	if _, err := models.ReplacementPatch(map[string]interface{}{"title": "Full", "owner": "x"}); err == nil {
+		t.Error("expected error for unknown field")
+	}
+}
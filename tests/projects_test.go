@@ -0,0 +1,374 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"example.com/tasksapi/handlers"
+	"example.com/tasksapi/models"
+	"example.com/tasksapi/store"
+)
+
+func projectRouter(api *handlers.API) *mux.Router {
+	r := mux.NewRouter()
+	r.HandleFunc("/tasks", api.CreateTask).Methods("POST")
+	r.HandleFunc("/tasks", api.ListTasks).Methods("GET")
+	r.HandleFunc("/tasks/bulk", api.BulkCreateTasks).Methods("POST")
+	r.HandleFunc("/tasks/{id}", api.PatchTask).Methods("PATCH")
+	r.HandleFunc("/tasks/{id}", api.DeleteTask).Methods("DELETE")
+	r.HandleFunc("/tasks/{id}/restore", api.RestoreTask).Methods("POST")
+	r.HandleFunc("/projects", api.CreateProject).Methods("POST")
+	r.HandleFunc("/projects", api.ListProjects).Methods("GET")
+	r.HandleFunc("/projects/{id}", api.GetProject).Methods("GET")
+	r.HandleFunc("/projects/{id}", api.PatchProject).Methods("PATCH")
+	r.HandleFunc("/projects/{id}", api.DeleteProject).Methods("DELETE")
+	r.HandleFunc("/projects/{id}/archive", api.ArchiveProject).Methods("POST")
+	r.HandleFunc("/projects/{id}/unarchive", api.UnarchiveProject).Methods("POST")
+	r.HandleFunc("/projects/{id}/tasks", api.ListProjectTasks).Methods("GET")
+	r.HandleFunc("/projects/{id}/stats", api.ProjectStats).Methods("GET")
+	return r
+}
+
+func createProjectViaAPI(t *testing.T, r http.Handler, body string) models.Project {
+	t.Helper()
+	w := serve(r, "POST", "/projects", "application/json", body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("failed to create project: %d %s", w.Code, w.Body.String())
+	}
+	var p models.Project
+	json.NewDecoder(w.Body).Decode(&p)
+	return p
+}
+
+func listTitles(t *testing.T, r http.Handler, target string) []string {
+	t.Helper()
+	w := serve(r, "GET", target, "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s: expected 200, got %d %s", target, w.Code, w.Body.String())
+	}
+	var resp models.TaskListResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	out := make([]string, len(resp.Tasks))
+	for i, task := range resp.Tasks {
+		out[i] = task.Title
+	}
+	return out
+}
+
+func TestProjectCRUD(t *testing.T) {
+	r := projectRouter(handlers.NewAPI(store.New(), &models.NoOpLogger{}))
+
+	p := createProjectViaAPI(t, r, `{"name": "  Website  ", "description": "Relaunch"}`)
+	if p.ID == "" || p.Name != "Website" || p.Version != 1 || p.Archived() {
+		t.Fatalf("unexpected project: %+v", p)
+	}
+	for _, body := range []string{`{"name": "   "}`, `{"description": "no name"}`, `{"name": 1}`} {
+		if w := serve(r, "POST", "/projects", "application/json", body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, w.Code)
+		}
+	}
+
+	w := serve(r, "PATCH", "/projects/"+p.ID, models.MergePatchContentType, `{"name": "Site", "description": null}`)
+	var patched models.Project
+	json.NewDecoder(w.Body).Decode(&patched)
+	if w.Code != http.StatusOK || patched.Name != "Site" || patched.Description != "" || patched.Version != 2 || patched.UpdatedAt == nil {
+		t.Errorf("expected patched project, got %d %+v", w.Code, patched)
+	}
+	if w := serve(r, "PATCH", "/projects/"+p.ID, models.MergePatchContentType, `{"owner": "me"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for unknown field, got %d", w.Code)
+	}
+
+	if w := serve(r, "GET", "/projects/"+p.ID, "", ""); w.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", w.Code)
+	}
+	if w := serve(r, "DELETE", "/projects/"+p.ID, "", ""); w.Code != http.StatusNoContent {
+		t.Errorf("expected 204, got %d", w.Code)
+	}
+	for _, method := range []string{"GET", "DELETE"} {
+		if w := serve(r, method, "/projects/"+p.ID, "", ""); w.Code != http.StatusNotFound {
+			t.Errorf("%s after delete: expected 404, got %d", method, w.Code)
+		}
+	}
+}
+
+func TestProjectTasks(t *testing.T) {
+	api := handlers.NewAPI(store.New(), &models.NoOpLogger{})
+	r := projectRouter(api)
+	p := createProjectViaAPI(t, r, `{"name": "Website"}`)
+	task := createTaskViaAPI(t, api, `{"title": "Landing page", "status": "pending", "project_id": "`+p.ID+`"}`)
+	createTaskViaAPI(t, api, `{"title": "Loose task", "status": "pending"}`)
+	if task.ProjectID != p.ID {
+		t.Fatalf("expected project_id %s, got %q", p.ID, task.ProjectID)
+	}
+
+	if w := serve(r, "POST", "/tasks", "application/json", `{"title": "Orphan", "status": "pending", "project_id": "missing"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown project, got %d", w.Code)
+	}
+	if got := listTitles(t, r, "/projects/"+p.ID+"/tasks"); len(got) != 1 || got[0] != "Landing page" {
+		t.Errorf("expected the project task, got %v", got)
+	}
+	if got := listTitles(t, r, "/tasks?project_id=null"); len(got) != 1 || got[0] != "Loose task" {
+		t.Errorf("expected the task without project, got %v", got)
+	}
+	if w := serve(r, "GET", "/projects/missing/tasks", "", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown project, got %d", w.Code)
+	}
+	if w := serve(r, "DELETE", "/projects/"+p.ID, "", ""); w.Code != http.StatusConflict {
+		t.Errorf("expected 409 deleting a project with tasks, got %d", w.Code)
+	}
+
+	t.Run("archive", func(t *testing.T) {
+		w := serve(r, "POST", "/projects/"+p.ID+"/archive", "", "")
+		var archived models.Project
+		json.NewDecoder(w.Body).Decode(&archived)
+		if w.Code != http.StatusOK || !archived.Archived() {
+			t.Fatalf("expected archived project, got %d %+v", w.Code, archived)
+		}
+		if got := listTitles(t, r, "/tasks"); len(got) != 1 || got[0] != "Loose task" {
+			t.Errorf("expected archived project tasks to be hidden, got %v", got)
+		}
+		if got := listTitles(t, r, "/tasks?project_id="+p.ID); len(got) != 1 {
+			t.Errorf("expected an explicit project_id filter to show them, got %v", got)
+		}
+		if got := listTitles(t, r, "/projects/"+p.ID+"/tasks"); len(got) != 1 {
+			t.Errorf("expected the nested route to show them, got %v", got)
+		}
+		if w := serve(r, "POST", "/tasks", "application/json", `{"title": "Late task", "status": "pending", "project_id": "`+p.ID+`"}`); w.Code != http.StatusConflict {
+			t.Errorf("expected 409 adding a task to an archived project, got %d", w.Code)
+		}
+		w = serve(r, "GET", "/projects", "", "")
+		var list models.ProjectListResponse
+		json.NewDecoder(w.Body).Decode(&list)
+		if list.TotalItems != 0 {
+			t.Errorf("expected archived projects to be hidden, got %d", list.TotalItems)
+		}
+		w = serve(r, "GET", "/projects?include_archived=true", "", "")
+		json.NewDecoder(w.Body).Decode(&list)
+		if list.TotalItems != 1 {
+			t.Errorf("expected include_archived to list it, got %d", list.TotalItems)
+		}
+
+		if w := serve(r, "POST", "/projects/"+p.ID+"/unarchive", "", ""); w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", w.Code)
+		}
+		if got := listTitles(t, r, "/tasks"); len(got) != 2 {
+			t.Errorf("expected the tasks back after unarchiving, got %v", got)
+		}
+	})
+
+	t.Run("restore after project deletion", func(t *testing.T) {
+		serve(r, "DELETE", "/tasks/"+task.ID, "", "")
+		if w := serve(r, "DELETE", "/projects/"+p.ID, "", ""); w.Code != http.StatusNoContent {
+			t.Fatalf("expected 204, got %d %s", w.Code, w.Body.String())
+		}
+		w := serve(r, "POST", "/tasks/"+task.ID+"/restore", "", "")
+		var restored models.Task
+		json.NewDecoder(w.Body).Decode(&restored)
+		if w.Code != http.StatusOK || restored.ProjectID != "" {
+			t.Errorf("expected the restored task without project, got %d %q", w.Code, restored.ProjectID)
+		}
+	})
+
+	t.Run("restore without parent and project in one write", func(t *testing.T) {
+		gone := createProjectViaAPI(t, r, `{"name": "Gone"}`)
+		parent := createTaskViaAPI(t, api, `{"title": "Parent", "status": "pending"}`)
+		child := createTaskViaAPI(t, api, `{"title": "Child", "status": "pending", "parent_id": "`+parent.ID+`", "project_id": "`+gone.ID+`"}`)
+		serve(r, "DELETE", "/tasks/"+child.ID, "", "")
+		serve(r, "DELETE", "/tasks/"+parent.ID, "", "")
+		if w := serve(r, "DELETE", "/projects/"+gone.ID, "", ""); w.Code != http.StatusNoContent {
+			t.Fatalf("expected 204, got %d %s", w.Code, w.Body.String())
+		}
+		w := serve(r, "POST", "/tasks/"+child.ID+"/restore", "", "")
+		var restored models.Task
+		json.NewDecoder(w.Body).Decode(&restored)
+		if w.Code != http.StatusOK || restored.ParentID != "" || restored.ProjectID != "" {
+			t.Fatalf("expected the restored task detached, got %d %+v", w.Code, restored)
+		}
+		// criação, exclusão, restauração e uma única escrita para soltar pai e projeto
+		if restored.Version != 4 {
+			t.Errorf("expected version 4, got %d", restored.Version)
+		}
+	})
+}
+
+func TestMoveTaskToProject(t *testing.T) {
+	api := handlers.NewAPI(store.New(), &models.NoOpLogger{})
+	r := projectRouter(api)
+	p := createProjectViaAPI(t, r, `{"name": "Website"}`)
+	archived := createProjectViaAPI(t, r, `{"name": "Old"}`)
+	serve(r, "POST", "/projects/"+archived.ID+"/archive", "", "")
+	task := createTaskViaAPI(t, api, `{"title": "Movable", "status": "pending"}`)
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"unknown project", `{"project_id": "missing"}`, http.StatusBadRequest},
+		{"archived project", `{"project_id": "` + archived.ID + `"}`, http.StatusConflict},
+		{"not a string", `{"project_id": 1}`, http.StatusBadRequest},
+		{"active project", `{"project_id": "` + p.ID + `"}`, http.StatusOK},
+		{"clear", `{"project_id": null}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := serve(r, "PATCH", "/tasks/"+task.ID, models.MergePatchContentType, tt.body); w.Code != tt.want {
+				t.Errorf("expected %d, got %d %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	w := serve(r, "POST", "/tasks/bulk", "application/json", `{"tasks": [
+		{"title": "In project", "status": "pending", "project_id": "`+p.ID+`"},
+		{"title": "In archived", "status": "pending", "project_id": "`+archived.ID+`"}]}`)
+	var resp models.BulkResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Succeeded != 1 || resp.Results[1].Status != http.StatusConflict {
+		t.Errorf("expected the archived project item to fail, got %+v", resp)
+	}
+}
+
+func TestProjectStats(t *testing.T) {
+	api := handlers.NewAPI(store.New(), &models.NoOpLogger{})
+	r := projectRouter(api)
+	p := createProjectViaAPI(t, r, `{"name": "Website"}`)
+	for _, body := range []string{
+		`{"title": "Done", "status": "completed", "priority": "high"}`,
+		`{"title": "Doing", "status": "in_progress", "priority": "high"}`,
+		`{"title": "Todo", "status": "pending"}`,
+		`{"title": "Dropped", "status": "cancelled", "priority": "low"}`,
+	} {
+		createTaskViaAPI(t, api, body[:len(body)-1]+`, "project_id": "`+p.ID+`"}`)
+	}
+	createTaskViaAPI(t, api, `{"title": "Elsewhere", "status": "completed"}`)
+
+	w := serve(r, "GET", "/projects/"+p.ID+"/stats", "", "")
+	var stats models.ProjectStats
+	json.NewDecoder(w.Body).Decode(&stats)
+	if w.Code != http.StatusOK || stats.TotalTasks != 4 || stats.CompletionRate != 0.25 {
+		t.Fatalf("unexpected stats: %d %+v", w.Code, stats)
+	}
+	if stats.ByStatus["completed"] != 1 || stats.ByStatus["pending"] != 1 || stats.ByPriority["high"] != 2 || stats.ByPriority["none"] != 1 || stats.ByPriority["medium"] != 0 {
+		t.Errorf("unexpected counts: %+v", stats)
+	}
+}
+
+func TestProjectStatsOverdue(t *testing.T) {
+	due := models.NewDate(2026, time.January, 10)
+	tasks := []models.Task{
+		{Status: "pending", DueDate: &due},
+		{Status: "completed", DueDate: &due},
+		{Status: "pending"},
+	}
+	stats := models.NewProjectStats("p", tasks, time.Date(2026, time.January, 11, 0, 0, 0, 0, time.UTC))
+	if stats.Overdue != 1 {
+		t.Errorf("expected 1 overdue task, got %d", stats.Overdue)
+	}
+	if stats = models.NewProjectStats("p", tasks, time.Date(2026, time.January, 10, 23, 0, 0, 0, time.UTC)); stats.Overdue != 0 {
+		t.Errorf("expected a task due today not to be overdue, got %d", stats.Overdue)
+	}
+}
+
+func TestMemoryProjectStore(t *testing.T) {
+	testProjectStore(t, store.NewMemoryProjectStore())
+}
+
+func TestFileProjectStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "projects.jsonl")
+	projects, err := store.NewFileProjectStore(path, true)
+	if err != nil {
+		t.Fatalf("failed to open file projects: %v", err)
+	}
+	testProjectStore(t, projects)
+	projects.Close()
+
+	reopened, err := store.NewFileProjectStore(path, true)
+	if err != nil {
+		t.Fatalf("failed to reopen file projects: %v", err)
+	}
+	defer reopened.Close()
+	assertProjectsAfterReopen(t, reopened)
+}
+
+func TestSQLProjectStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.db")
+	ctx := context.Background()
+	s, err := store.NewSQL(ctx, "sqlite", path)
+	if err != nil {
+		t.Fatalf("failed to open sqlite store: %v", err)
+	}
+	projects, _ := s.ProjectStore()
+	testProjectStore(t, projects)
+	s.Close()
+
+	reopened, err := store.NewSQL(ctx, "sqlite", path)
+	if err != nil {
+		t.Fatalf("failed to reopen sqlite store: %v", err)
+	}
+	defer reopened.Close()
+	projects, _ = reopened.ProjectStore()
+	assertProjectsAfterReopen(t, projects)
+}
+
+// assertProjectsAfterReopen confere o que testProjectStore deixa gravado: só o segundo
+// projeto, já que o primeiro foi excluído
+func assertProjectsAfterReopen(t *testing.T, projects store.ProjectStore) {
+	t.Helper()
+	list, err := projects.List(context.Background())
+	if err != nil || len(list) != 1 || list[0].Name != "Second" || list[0].Description != "More" || list[0].Version != 1 {
+		t.Errorf("expected the second project after reopen, got %+v %v", list, err)
+	}
+}
+
+func testProjectStore(t *testing.T, projects store.ProjectStore) {
+	ctx := context.Background()
+	first, err := projects.Create(ctx, models.Project{Name: "First"})
+	if err != nil || first.ID == "" || first.Version != 1 {
+		t.Fatalf("create failed: %+v %v", first, err)
+	}
+	second, _ := projects.Create(ctx, models.Project{Name: "Second", Description: "More"})
+
+	got, err := projects.Get(ctx, second.ID)
+	if err != nil || got.Name != "Second" || got.Description != "More" {
+		t.Errorf("expected the stored project, got %+v %v", got, err)
+	}
+	if _, err := projects.Get(ctx, "missing"); !errors.Is(err, store.ErrProjectNotFound) {
+		t.Errorf("expected ErrProjectNotFound, got %v", err)
+	}
+
+	updated, err := projects.Update(ctx, first.ID, func(current models.Project) (models.Project, error) {
+		now := time.Now().UTC()
+		current.Name, current.ArchivedAt = "Renamed", &now
+		return current, nil
+	})
+	if err != nil || updated.Name != "Renamed" || !updated.Archived() || updated.Version != 2 || !updated.CreatedAt.Equal(first.CreatedAt) {
+		t.Errorf("unexpected update: %+v %v", updated, err)
+	}
+	failure := errors.New("rejected")
+	if _, err := projects.Update(ctx, first.ID, func(models.Project) (models.Project, error) { return models.Project{}, failure }); !errors.Is(err, failure) {
+		t.Errorf("expected the fn error, got %v", err)
+	}
+
+	list, err := projects.List(ctx)
+	if err != nil || len(list) != 2 || list[0].ID != first.ID || list[0].Name != "Renamed" {
+		t.Errorf("expected both projects by creation, got %+v %v", list, err)
+	}
+	ids, err := store.ArchivedProjectIDs(ctx, projects)
+	if err != nil || len(ids) != 1 || ids[0] != first.ID {
+		t.Errorf("expected the archived project id, got %v %v", ids, err)
+	}
+
+	if err := projects.Delete(ctx, first.ID); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	if err := projects.Delete(ctx, first.ID); !errors.Is(err, store.ErrProjectNotFound) {
+		t.Errorf("expected ErrProjectNotFound deleting twice, got %v", err)
+	}
+}
@@ -0,0 +1,225 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"example.com/tasksapi/handlers"
+	"example.com/tasksapi/models"
+	"example.com/tasksapi/router"
+	"example.com/tasksapi/store"
+)
+
+func getHealth(t *testing.T, h http.Handler) (int, models.HealthResponse) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/health", nil))
+	var resp models.HealthResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	return w.Code, resp
+}
+
+func TestRouterStoreBackend(t *testing.T) {
+	t.Setenv("TRASH_RETENTION", "0")
+
+	t.Run("error when nothing is configured", func(t *testing.T) {
+		t.Setenv("STORE_BACKEND", "")
+		t.Setenv("MONGO_URI", "")
+		if _, err := router.Open(&models.NoOpLogger{}); err == nil {
+			t.Error("expected error without STORE_BACKEND or MONGO_URI")
+		}
+	})
+
+	t.Run("explicit memory backend", func(t *testing.T) {
+		t.Setenv("STORE_BACKEND", "memory")
+		t.Setenv("MONGO_URI", "")
+		r, err := router.Open(&models.NoOpLogger{})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if code, resp := getHealth(t, r); code != http.StatusOK || resp.Backend != "memory" || resp.Fallback {
+			t.Errorf("expected healthy memory backend, got %d %+v", code, resp)
+		}
+	})
+
+	t.Run("explicit sql backend", func(t *testing.T) {
+		t.Setenv("STORE_BACKEND", "sql")
+		t.Setenv("SQL_DRIVER", "sqlite")
+		t.Setenv("SQL_DSN", filepath.Join(t.TempDir(), "tasks.db"))
+		r, err := router.Open(&models.NoOpLogger{})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if code, resp := getHealth(t, r); code != http.StatusOK || resp.Backend != "sql" || resp.Status != "ok" {
+			t.Errorf("expected healthy sql backend, got %d %+v", code, resp)
+		}
+	})
+
+	t.Run("unknown backend", func(t *testing.T) {
+		t.Setenv("STORE_BACKEND", "redis")
+		if _, err := router.Open(&models.NoOpLogger{}); err == nil {
+			t.Error("expected error for unknown backend")
+		}
+	})
+
+	t.Run("unreachable backend is fatal by default", func(t *testing.T) {
+		t.Setenv("STORE_BACKEND", "mongo")
+		t.Setenv("MONGO_URI", "not-a-mongo-uri")
+		t.Setenv("STORE_FALLBACK", "")
+		if _, err := router.Open(&models.NoOpLogger{}); err == nil {
+			t.Error("expected error when the backend is unreachable")
+		}
+		defer func() {
+			if recover() == nil {
+				t.Error("expected NewWithLogger to call Fatal")
+			}
+		}()
+		router.NewWithLogger(&models.NoOpLogger{})
+	})
+
+	t.Run("fallback to memory is opt-in", func(t *testing.T) {
+		t.Setenv("STORE_BACKEND", "mongo")
+		t.Setenv("MONGO_URI", "not-a-mongo-uri")
+		t.Setenv("STORE_FALLBACK", "memory")
+		r, err := router.Open(&models.NoOpLogger{})
+		if err != nil {
+			t.Fatalf("expected fallback, got %v", err)
+		}
+		if code, resp := getHealth(t, r); code != http.StatusOK || resp.Backend != "memory" || !resp.Fallback {
+			t.Errorf("expected health to report the fallback, got %d %+v", code, resp)
+		}
+	})
+}
+
+func TestRouterMigrate(t *testing.T) {
+	t.Run("sql backend applies migrations", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "tasks.db")
+		t.Setenv("STORE_BACKEND", "sql")
+		t.Setenv("SQL_DRIVER", "sqlite")
+		t.Setenv("SQL_DSN", path)
+		if err := router.Migrate(&models.NoOpLogger{}, false); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		s, err := store.NewSQL(context.Background(), "sqlite", path)
+		if err != nil {
+			t.Fatalf("failed to reopen sqlite store: %v", err)
+		}
+		defer s.Close()
+		if version, err := s.SchemaVersion(context.Background()); err != nil || version != 8 {
+			t.Errorf("expected schema version 8, got %d (%v)", version, err)
+		}
+	})
+
+	t.Run("memory backend has nothing to migrate", func(t *testing.T) {
+		t.Setenv("STORE_BACKEND", "memory")
+		if err := router.Migrate(&models.NoOpLogger{}, true); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+
+	t.Run("unreachable backend never falls back", func(t *testing.T) {
+		t.Setenv("STORE_BACKEND", "mongo")
+		t.Setenv("MONGO_URI", "not-a-mongo-uri")
+		t.Setenv("STORE_FALLBACK", "memory")
+		if err := router.Migrate(&models.NoOpLogger{}, false); err == nil {
+			t.Error("expected error when the backend is unreachable")
+		}
+	})
+
+	t.Run("unknown backend", func(t *testing.T) {
+		t.Setenv("STORE_BACKEND", "redis")
+		if err := router.Migrate(&models.NoOpLogger{}, false); err == nil {
+			t.Error("expected error for unknown backend")
+		}
+	})
+}
+
+type downStore struct{ store.Store }
+
+func (downStore) Ping(ctx context.Context) error { return errors.New("connection refused") }
+
+func TestHealthReportsUnavailableBackend(t *testing.T) {
+	h := handlers.NewHealthHandler("sql", false, downStore{store.New()})
+	code, resp := getHealth(t, http.HandlerFunc(h.Health))
+	if code != http.StatusServiceUnavailable || resp.Status != "unavailable" || resp.Error == "" {
+		t.Errorf("expected 503 with the ping error, got %d %+v", code, resp)
+	}
+}
+
+func TestRouterPurgeInterval(t *testing.T) {
+	t.Setenv("STORE_BACKEND", "memory")
+	t.Setenv("TRASH_RETENTION", "1h")
+
+	for _, interval := range []string{"0", "-1s"} {
+		t.Run(interval, func(t *testing.T) {
+			t.Setenv("TRASH_PURGE_INTERVAL", interval)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if _, err := router.OpenContext(ctx, &models.NoOpLogger{}); err != nil {
+				t.Fatalf("expected the default interval to be used, got %v", err)
+			}
+		})
+	}
+}
+
+func TestStartPurgerRejectsNonPositiveInterval(t *testing.T) {
+	// Com interval <= 0 o purgador não sobe (time.NewTicker entraria em pânico)
+	store.StartPurger(context.Background(), store.New(), time.Hour, 0, &models.NoOpLogger{})
+}
+
+func TestRouterDurableBackendsPersistHistoryAndProjects(t *testing.T) {
+	t.Setenv("TRASH_RETENTION", "0")
+	backends := map[string]func(t *testing.T, dir string){
+		"file": func(t *testing.T, dir string) {
+			t.Setenv("FILE_STORE_PATH", filepath.Join(dir, "tasks.jsonl"))
+		},
+		"events": func(t *testing.T, dir string) {
+			t.Setenv("EVENT_STORE_DIR", dir)
+		},
+		"sql": func(t *testing.T, dir string) {
+			t.Setenv("SQL_DRIVER", "sqlite")
+			t.Setenv("SQL_DSN", filepath.Join(dir, "tasks.db"))
+		},
+	}
+	for backend, configure := range backends {
+		t.Run(backend, func(t *testing.T) {
+			t.Setenv("STORE_BACKEND", backend)
+			configure(t, t.TempDir())
+			r, err := router.Open(&models.NoOpLogger{})
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			w := serve(r, "POST", "/projects", "application/json", `{"name": "Durable"}`)
+			var p models.Project
+			json.NewDecoder(w.Body).Decode(&p)
+			w = serve(r, "POST", "/tasks", "application/json", `{"title": "Kept", "status": "pending", "project_id": "`+p.ID+`"}`)
+			var task models.Task
+			json.NewDecoder(w.Body).Decode(&task)
+			if w.Code != http.StatusCreated {
+				t.Fatalf("failed to create task: %d %s", w.Code, w.Body.String())
+			}
+			serve(r, "PATCH", "/tasks/"+task.ID, models.MergePatchContentType, `{"priority": "high"}`)
+
+			// Um novo router sobre os mesmos arquivos simula o reinício do processo
+			r, err = router.Open(&models.NoOpLogger{})
+			if err != nil {
+				t.Fatalf("expected no error reopening, got %v", err)
+			}
+			if w := serve(r, "GET", "/projects/"+p.ID, "", ""); w.Code != http.StatusOK {
+				t.Errorf("expected the project after restart, got %d", w.Code)
+			}
+			w = serve(r, "GET", "/tasks/"+task.ID+"/history", "", "")
+			var history models.TaskHistoryResponse
+			json.NewDecoder(w.Body).Decode(&history)
+			if w.Code != http.StatusOK || len(history.Entries) != 2 {
+				t.Errorf("expected create and update in the history after restart, got %d %+v", w.Code, history.Entries)
+			}
+		})
+	}
+}
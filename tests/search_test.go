@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"example.com/tasksapi/handlers"
+	"example.com/tasksapi/models"
+	"example.com/tasksapi/store"
+)
+
+func TestSearchTerms(t *testing.T) {
+	terms := models.SearchTerms("Revisão de CÓDIGO: revisao, ação!")
+	expected := []string{"revisao", "de", "codigo", "acao"}
+	if len(terms) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, terms)
+	}
+	for i := range expected {
+		if terms[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected, terms)
+			break
+		}
+	}
+}
+
+func TestListTasksSearch(t *testing.T) {
+	s := store.New()
+	h := handlers.NewAPI(s, &models.NoOpLogger{})
+	inDescription := createTaskViaAPI(t, h, `{"title":"Deploy","description":"Revisar a configuração do servidor","status":"pending"}`)
+	createTaskViaAPI(t, h, `{"title":"Configuração do CI","status":"pending"}`)
+	createTaskViaAPI(t, h, `{"title":"Comprar café","status":"completed"}`)
+
+	t.Run("case and accent insensitive, ranked by relevance", func(t *testing.T) {
+		resp := listTasksViaAPI(t, h, "?q=CONFIGURACAO")
+		if resp.TotalItems != 2 {
+			t.Fatalf("expected 2 matches, got %d", resp.TotalItems)
+		}
+		if resp.Tasks[0].Title != "Configuração do CI" {
+			t.Errorf("expected title match first, got %s", resp.Tasks[0].Title)
+		}
+		if resp.Tasks[0].Score <= resp.Tasks[1].Score {
+			t.Errorf("expected decreasing scores, got %v and %v", resp.Tasks[0].Score, resp.Tasks[1].Score)
+		}
+	})
+
+	t.Run("combined with filters", func(t *testing.T) {
+		resp := listTasksViaAPI(t, h, "?q=cafe&status=pending")
+		if resp.TotalItems != 0 {
+			t.Errorf("expected no pending task about coffee, got %d", resp.TotalItems)
+		}
+	})
+
+	t.Run("index follows updates and deletes", func(t *testing.T) {
+		ctx := context.Background()
+		if _, err := s.Update(ctx, inDescription.ID, map[string]interface{}{"description": "Atualizar dependências"}); err != nil {
+			t.Fatalf("update failed: %v", err)
+		}
+		if resp := listTasksViaAPI(t, h, "?q=servidor"); resp.TotalItems != 0 {
+			t.Errorf("expected old words to leave the index, got %d matches", resp.TotalItems)
+		}
+		if resp := listTasksViaAPI(t, h, "?q=dependencias"); resp.TotalItems != 1 {
+			t.Errorf("expected new words in the index, got %d matches", resp.TotalItems)
+		}
+		if err := s.Delete(ctx, inDescription.ID); err != nil {
+			t.Fatalf("delete failed: %v", err)
+		}
+		if resp := listTasksViaAPI(t, h, "?q=dependencias"); resp.TotalItems != 0 {
+			t.Errorf("expected deleted task to leave the index, got %d matches", resp.TotalItems)
+		}
+	})
+
+	t.Run("score sort requires a search", func(t *testing.T) {
+		if _, err := models.ParseTaskQuery(map[string][]string{"sort": {"-score"}}); err == nil {
+			t.Error("expected error sorting by score without q")
+		}
+	})
+}
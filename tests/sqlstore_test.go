@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"example.com/tasksapi/models"
+	"example.com/tasksapi/store"
+)
+
+func TestSQLStoreMigrations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.db")
+	ctx := context.Background()
+	s, err := store.NewSQL(ctx, "sqlite", path)
+	if err != nil {
+		t.Fatalf("failed to open sqlite store: %v", err)
+	}
+	version, err := s.SchemaVersion(ctx)
+	if err != nil || version != 8 {
+		t.Fatalf("expected schema version 8, got %d (%v)", version, err)
+	}
+	due := models.NewDate(2030, 1, 15)
+	created, _ := s.Create(ctx, models.Task{Title: "Persisted", Status: "pending", DueDate: &due})
+	s.Close()
+
+	// Reabrir não reaplica migrações já registradas
+	reopened, err := store.NewSQL(ctx, "sqlite", path)
+	if err != nil {
+		t.Fatalf("failed to reopen sqlite store: %v", err)
+	}
+	defer reopened.Close()
+	if err := reopened.Migrate(ctx); err != nil {
+		t.Errorf("expected migrate to be idempotent, got %v", err)
+	}
+	got, err := reopened.Get(ctx, created.ID)
+	if err != nil || got.DueDate == nil || got.DueDate.String() != "2030-01-15" || got.UpdatedAt != nil {
+		t.Errorf("unexpected task after reopen: %+v (%v)", got, err)
+	}
+
+	if _, err := store.NewSQL(ctx, "oracle", "x"); err == nil {
+		t.Error("expected error for unsupported driver")
+	}
+}
+
+func TestSQLUpdateWithMissedVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.db")
+	ctx := context.Background()
+	s, err := store.NewSQL(ctx, "sqlite", path)
+	if err != nil {
+		t.Fatalf("failed to open sqlite store: %v", err)
+	}
+	defer s.Close()
+	created, _ := s.Create(ctx, models.Task{Title: "Raced", Status: "pending"})
+
+	// O trigger descarta o UPDATE, como se outra escrita tivesse mudado a versão entre a
+	// leitura e a gravação
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TRIGGER lose_race BEFORE UPDATE ON tasks BEGIN SELECT RAISE(IGNORE); END`); err != nil {
+		t.Fatalf("failed to create trigger: %v", err)
+	}
+	if _, err := s.Update(ctx, created.ID, map[string]interface{}{"title": "Mine"}); !errors.Is(err, store.ErrVersionConflict) {
+		t.Errorf("expected ErrVersionConflict, got %v", err)
+	}
+	if _, err := s.Update(ctx, "missing", map[string]interface{}{"title": "Mine"}); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,490 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"example.com/tasksapi/handlers"
+	"example.com/tasksapi/models"
+	"example.com/tasksapi/store"
+)
+
+func hierarchyRouter(api *handlers.API) *mux.Router {
+	r := mux.NewRouter()
+	r.HandleFunc("/tasks", api.CreateTask).Methods("POST")
+	r.HandleFunc("/tasks", api.ListTasks).Methods("GET")
+	r.HandleFunc("/tasks/trash", api.ListTrash).Methods("GET")
+	r.HandleFunc("/tasks/bulk", api.BulkUpdateTasks).Methods("PATCH")
+	r.HandleFunc("/tasks/bulk", api.BulkDeleteTasks).Methods("DELETE")
+	r.HandleFunc("/tasks/{id}", api.PatchTask).Methods("PATCH")
+	r.HandleFunc("/tasks/{id}", api.DeleteTask).Methods("DELETE")
+	r.HandleFunc("/tasks/{id}/restore", api.RestoreTask).Methods("POST")
+	r.HandleFunc("/tasks/{id}/subtasks", api.ListSubtasks).Methods("GET")
+	return r
+}
+
+func serve(r http.Handler, method, target, contentType, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestSubtaskCreation(t *testing.T) {
+	api := handlers.NewAPI(store.New(), &models.NoOpLogger{})
+	r := hierarchyRouter(api)
+	parent := createTaskViaAPI(t, api, `{"title": "Parent", "status": "pending"}`)
+
+	child := createTaskViaAPI(t, api, `{"title": "Child", "status": "pending", "parent_id": "`+parent.ID+`"}`)
+	if child.ParentID != parent.ID {
+		t.Errorf("expected parent_id %s, got %q", parent.ID, child.ParentID)
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"missing parent", `{"title": "Orphan", "status": "pending", "parent_id": "missing"}`, http.StatusBadRequest},
+		{"blank parent", `{"title": "Blank", "status": "pending", "parent_id": " "}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := serve(r, "POST", "/tasks", "application/json", tt.body); w.Code != tt.want {
+				t.Errorf("expected %d, got %d: %s", tt.want, w.Code, w.Body)
+			}
+		})
+	}
+
+	t.Run("completed parent", func(t *testing.T) {
+		done := createTaskViaAPI(t, api, `{"title": "Done", "status": "completed"}`)
+		w := serve(r, "POST", "/tasks", "application/json", `{"title": "Late", "status": "pending", "parent_id": "`+done.ID+`"}`)
+		if w.Code != http.StatusConflict {
+			t.Errorf("expected 409, got %d", w.Code)
+		}
+	})
+}
+
+func TestSubtaskCyclePrevention(t *testing.T) {
+	api := handlers.NewAPI(store.New(), &models.NoOpLogger{})
+	r := hierarchyRouter(api)
+	a := createTaskViaAPI(t, api, `{"title": "Task A", "status": "pending"}`)
+	b := createTaskViaAPI(t, api, `{"title": "Task B", "status": "pending", "parent_id": "`+a.ID+`"}`)
+	c := createTaskViaAPI(t, api, `{"title": "Task C", "status": "pending", "parent_id": "`+b.ID+`"}`)
+
+	for name, target := range map[string]string{"itself": a.ID, "descendant": c.ID} {
+		t.Run(name, func(t *testing.T) {
+			w := serve(r, "PATCH", "/tasks/"+a.ID, models.MergePatchContentType, `{"parent_id": "`+target+`"}`)
+			if w.Code != http.StatusConflict {
+				t.Errorf("expected 409, got %d: %s", w.Code, w.Body)
+			}
+		})
+	}
+
+	t.Run("move and detach", func(t *testing.T) {
+		w := serve(r, "PATCH", "/tasks/"+c.ID, models.MergePatchContentType, `{"parent_id": "`+a.ID+`"}`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+		}
+		w = serve(r, "PATCH", "/tasks/"+c.ID, models.MergePatchContentType, `{"parent_id": null}`)
+		var detached models.Task
+		json.NewDecoder(w.Body).Decode(&detached)
+		if w.Code != http.StatusOK || detached.ParentID != "" {
+			t.Errorf("expected a root task, got %d %+v", w.Code, detached)
+		}
+	})
+
+	t.Run("bulk move under a descendant", func(t *testing.T) {
+		code, resp := bulkRequest(t, api.BulkUpdateTasks, "PATCH", "/tasks/bulk", `{"ids": ["`+a.ID+`", "`+c.ID+`"], "patch": {"parent_id": "`+b.ID+`"}}`)
+		if code != http.StatusOK || resp.Results[0].Status != http.StatusConflict || resp.Results[1].Status != http.StatusOK {
+			t.Errorf("expected only the cycle to be rejected, got %d %+v", code, resp)
+		}
+	})
+}
+
+func TestCompletingParentWithOpenSubtasks(t *testing.T) {
+	api := handlers.NewAPI(store.New(), &models.NoOpLogger{})
+	r := hierarchyRouter(api)
+	parent := createTaskViaAPI(t, api, `{"title": "Parent", "status": "in_progress"}`)
+	child := createTaskViaAPI(t, api, `{"title": "Child", "status": "pending", "parent_id": "`+parent.ID+`"}`)
+	createTaskViaAPI(t, api, `{"title": "Cancelled child", "status": "cancelled", "parent_id": "`+parent.ID+`"}`)
+
+	w := serve(r, "PATCH", "/tasks/"+parent.ID, models.MergePatchContentType, `{"status": "completed"}`)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d: %s", w.Code, w.Body)
+	}
+	code, resp := bulkRequest(t, api.BulkUpdateTasks, "PATCH", "/tasks/bulk", `{"ids": ["`+parent.ID+`"], "patch": {"status": "completed"}}`)
+	if code != http.StatusOK || resp.Results[0].Status != http.StatusConflict {
+		t.Errorf("expected bulk item 409, got %d %+v", code, resp)
+	}
+
+	if w := serve(r, "PATCH", "/tasks/"+child.ID, models.MergePatchContentType, `{"status": "completed"}`); w.Code != http.StatusOK {
+		t.Fatalf("expected child completion, got %d", w.Code)
+	}
+	if w := serve(r, "PATCH", "/tasks/"+parent.ID, models.MergePatchContentType, `{"status": "completed"}`); w.Code != http.StatusOK {
+		t.Errorf("expected 200 once subtasks are closed, got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestDeleteParentTask(t *testing.T) {
+	s := store.New()
+	api := handlers.NewAPI(s, &models.NoOpLogger{})
+	r := hierarchyRouter(api)
+	parent := createTaskViaAPI(t, api, `{"title": "Parent", "status": "pending"}`)
+	child := createTaskViaAPI(t, api, `{"title": "Child", "status": "pending", "parent_id": "`+parent.ID+`"}`)
+	grandchild := createTaskViaAPI(t, api, `{"title": "Grandchild", "status": "pending", "parent_id": "`+child.ID+`"}`)
+	other := createTaskViaAPI(t, api, `{"title": "Other", "status": "pending"}`)
+
+	if w := serve(r, "DELETE", "/tasks/"+parent.ID, "", ""); w.Code != http.StatusConflict {
+		t.Fatalf("expected 409 without cascade, got %d", w.Code)
+	}
+	if w := serve(r, "DELETE", "/tasks/"+parent.ID+"?purge=true", "", ""); w.Code != http.StatusConflict {
+		t.Fatalf("expected 409 when purging without cascade, got %d", w.Code)
+	}
+
+	t.Run("bulk delete needs the whole subtree", func(t *testing.T) {
+		code, resp := bulkRequest(t, api.BulkDeleteTasks, "DELETE", "/tasks/bulk", `{"ids": ["`+child.ID+`", "`+other.ID+`"]}`)
+		if code != http.StatusOK || resp.Results[0].Status != http.StatusConflict || resp.Results[1].Status != http.StatusNoContent {
+			t.Errorf("expected only the parent to be rejected, got %d %+v", code, resp)
+		}
+	})
+
+	if w := serve(r, "DELETE", "/tasks/"+parent.ID+"?cascade=true", "", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 with cascade, got %d: %s", w.Code, w.Body)
+	}
+	for _, id := range []string{parent.ID, child.ID, grandchild.ID} {
+		if _, err := s.Get(context.Background(), id); err == nil {
+			t.Errorf("expected %s to be in the trash", id)
+		}
+	}
+
+	t.Run("restore without the parent detaches", func(t *testing.T) {
+		w := serve(r, "POST", "/tasks/"+child.ID+"/restore", "", "")
+		var restored models.Task
+		json.NewDecoder(w.Body).Decode(&restored)
+		if w.Code != http.StatusOK || restored.ParentID != "" {
+			t.Errorf("expected a root task, got %d %+v", w.Code, restored)
+		}
+	})
+}
+
+// slowReads devolve as leituras com atraso, abrindo a janela entre a checagem e a escrita
+type slowReads struct{ store.Store }
+
+func (s slowReads) Get(ctx context.Context, id string) (models.Task, error) {
+	defer time.Sleep(time.Millisecond)
+	return s.Store.Get(ctx, id)
+}
+
+func (s slowReads) Query(ctx context.Context, q models.TaskQuery) (models.TaskPage, error) {
+	defer time.Sleep(time.Millisecond)
+	return s.Store.Query(ctx, q)
+}
+
+func TestConcurrentCompleteAndAddSubtask(t *testing.T) {
+	s := slowReads{store.New()}
+	api := handlers.NewAPI(s, &models.NoOpLogger{})
+	r := hierarchyRouter(api)
+
+	// Concluir o pai e criar uma subtarefa aberta ao mesmo tempo: no máximo uma das duas passa
+	for i := 0; i < 50; i++ {
+		parent := createTaskViaAPI(t, api, `{"title": "Parent", "status": "in_progress"}`)
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			serve(r, "PATCH", "/tasks/"+parent.ID, models.MergePatchContentType, `{"status": "completed"}`)
+		}()
+		go func() {
+			defer wg.Done()
+			serve(r, "POST", "/tasks", "application/json", `{"title": "Child", "status": "pending", "parent_id": "`+parent.ID+`"}`)
+		}()
+		wg.Wait()
+
+		stored, _ := s.Get(context.Background(), parent.ID)
+		children := serve(r, "GET", "/tasks/"+parent.ID+"/subtasks", "", "")
+		var list models.TaskListResponse
+		json.NewDecoder(children.Body).Decode(&list)
+		if models.IsCompletedTask(stored.Status) && list.TotalItems > 0 {
+			t.Fatalf("completed parent ended up with %d open subtask(s)", list.TotalItems)
+		}
+	}
+}
+
+// interleaved roda hook uma vez, logo antes da próxima escrita, como uma escrita
+// concorrente que chega entre a checagem da requisição e a gravação dela
+type interleaved struct {
+	store.Store
+	hook func(s store.Store)
+}
+
+func (s *interleaved) Create(ctx context.Context, t models.Task) (models.Task, error) {
+	s.run()
+	return s.Store.Create(ctx, t)
+}
+
+func (s *interleaved) UpdateWith(ctx context.Context, id string, fn store.UpdateFunc) (models.Task, error) {
+	s.run()
+	return s.Store.UpdateWith(ctx, id, fn)
+}
+
+func (s *interleaved) Delete(ctx context.Context, id string) error {
+	s.run()
+	return s.Store.Delete(ctx, id)
+}
+
+func (s *interleaved) CreateManyAtomic(ctx context.Context, tasks []models.Task) ([]store.BulkResult, error) {
+	s.run()
+	return store.CreateManyAtomic(ctx, s.Store, tasks)
+}
+
+func (s *interleaved) UpdateManyAtomic(ctx context.Context, ids []string, fn store.UpdateFunc) ([]store.BulkResult, error) {
+	s.run()
+	return store.UpdateManyAtomic(ctx, s.Store, ids, fn)
+}
+
+func (s *interleaved) DeleteManyAtomic(ctx context.Context, ids []string) ([]store.BulkResult, error) {
+	s.run()
+	return store.DeleteManyAtomic(ctx, s.Store, ids)
+}
+
+func (s *interleaved) run() {
+	if hook := s.hook; hook != nil {
+		s.hook = nil
+		hook(s.Store)
+	}
+}
+
+func TestHierarchyRecheckedAfterWrite(t *testing.T) {
+	ctx := context.Background()
+	setup := func(t *testing.T) (*interleaved, *mux.Router, models.Task) {
+		s := &interleaved{Store: store.New()}
+		api := handlers.NewAPI(s, &models.NoOpLogger{})
+		parent := createTaskViaAPI(t, api, `{"title": "Parent", "status": "in_progress"}`)
+		return s, hierarchyRouter(api), parent
+	}
+
+	t.Run("subtask of a parent completed in between is removed", func(t *testing.T) {
+		s, r, parent := setup(t)
+		s.hook = func(inner store.Store) {
+			inner.Update(ctx, parent.ID, map[string]interface{}{"status": models.StatusCompleted})
+		}
+		w := serve(r, "POST", "/tasks", "application/json", `{"title": "Child", "status": "pending", "parent_id": "`+parent.ID+`"}`)
+		if w.Code != http.StatusConflict {
+			t.Fatalf("expected 409, got %d: %s", w.Code, w.Body)
+		}
+		page, _ := s.Query(ctx, models.TaskQuery{Filters: []models.TaskFilter{{Field: "parent_id", Op: models.FilterEq, Value: parent.ID}}})
+		if page.Total != 0 {
+			t.Errorf("expected the subtask to be removed, got %+v", page.Tasks)
+		}
+	})
+
+	t.Run("completion is undone when a subtask appears in between", func(t *testing.T) {
+		s, r, parent := setup(t)
+		s.hook = func(inner store.Store) {
+			inner.Create(ctx, models.Task{Title: "Child", Status: "pending", ParentID: parent.ID})
+		}
+		w := serve(r, "PATCH", "/tasks/"+parent.ID, models.MergePatchContentType, `{"status": "completed", "title": "Done"}`)
+		if w.Code != http.StatusConflict {
+			t.Fatalf("expected 409, got %d: %s", w.Code, w.Body)
+		}
+		stored, _ := s.Get(ctx, parent.ID)
+		if stored.Status != models.StatusInProgress || stored.Title != "Parent" {
+			t.Errorf("expected the patch to be undone, got %+v", stored)
+		}
+	})
+
+	t.Run("delete is undone when a subtask appears in between", func(t *testing.T) {
+		s, r, parent := setup(t)
+		s.hook = func(inner store.Store) {
+			inner.Create(ctx, models.Task{Title: "Child", Status: "pending", ParentID: parent.ID})
+		}
+		if w := serve(r, "DELETE", "/tasks/"+parent.ID, "", ""); w.Code != http.StatusConflict {
+			t.Fatalf("expected 409, got %d: %s", w.Code, w.Body)
+		}
+		if _, err := s.Get(ctx, parent.ID); err != nil {
+			t.Errorf("expected the parent to be restored, got %v", err)
+		}
+	})
+
+	t.Run("opposite moves do not create a cycle", func(t *testing.T) {
+		s, r, a := setup(t)
+		b, _ := s.Store.Create(ctx, models.Task{Title: "B", Status: "pending"})
+		s.hook = func(inner store.Store) {
+			inner.Update(ctx, b.ID, map[string]interface{}{"parent_id": a.ID})
+		}
+		w := serve(r, "PATCH", "/tasks/"+a.ID, models.MergePatchContentType, `{"parent_id": "`+b.ID+`"}`)
+		if w.Code != http.StatusConflict {
+			t.Fatalf("expected 409, got %d: %s", w.Code, w.Body)
+		}
+		stored, _ := s.Get(ctx, a.ID)
+		if stored.ParentID != "" {
+			t.Errorf("expected the move to be undone, got parent %q", stored.ParentID)
+		}
+	})
+
+	t.Run("atomic bulk completion is undone for every task", func(t *testing.T) {
+		s, r, parent := setup(t)
+		other, _ := s.Store.Create(ctx, models.Task{Title: "Other", Status: models.StatusInProgress})
+		s.hook = func(inner store.Store) {
+			inner.Create(ctx, models.Task{Title: "Child", Status: "pending", ParentID: parent.ID})
+		}
+		w := serve(r, "PATCH", "/tasks/bulk?atomic=true", "application/json", `{"ids": ["`+parent.ID+`", "`+other.ID+`"], "patch": {"status": "completed"}}`)
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("expected 422, got %d: %s", w.Code, w.Body)
+		}
+		for _, id := range []string{parent.ID, other.ID} {
+			if stored, _ := s.Get(ctx, id); stored.Status != models.StatusInProgress {
+				t.Errorf("expected %s to stay in progress, got %q", id, stored.Status)
+			}
+		}
+	})
+}
+
+// deleteOrder esconde os lotes do InMemoryStore (como os backends sem lote atômico) e
+// registra a ordem das exclusões
+type deleteOrder struct {
+	store.Store
+	mu  sync.Mutex
+	ids []string
+}
+
+func (d *deleteOrder) Delete(ctx context.Context, id string) error {
+	d.record(id)
+	return d.Store.Delete(ctx, id)
+}
+
+func (d *deleteOrder) DeleteIfVersion(ctx context.Context, id string, version int64) error {
+	d.record(id)
+	return d.Store.DeleteIfVersion(ctx, id, version)
+}
+
+func (d *deleteOrder) record(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.ids = append(d.ids, id)
+}
+
+func TestCascadeDeleteWithoutAtomicBatches(t *testing.T) {
+	s := &deleteOrder{Store: store.New()}
+	api := handlers.NewAPI(s, &models.NoOpLogger{})
+	r := hierarchyRouter(api)
+	parent := createTaskViaAPI(t, api, `{"title": "Parent", "status": "pending"}`)
+	child := createTaskViaAPI(t, api, `{"title": "Child", "status": "pending", "parent_id": "`+parent.ID+`"}`)
+	createTaskViaAPI(t, api, `{"title": "Grandchild", "status": "pending", "parent_id": "`+child.ID+`"}`)
+
+	if w := serve(r, "DELETE", "/tasks/"+parent.ID+"?cascade=true", "", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 with cascade, got %d: %s", w.Code, w.Body)
+	}
+	// O pai só vai para a lixeira depois das subtarefas
+	if len(s.ids) != 3 || s.ids[2] != parent.ID {
+		t.Errorf("expected the parent to be deleted last, got %v", s.ids)
+	}
+}
+
+// purgeOrder registra a ordem das remoções definitivas
+type purgeOrder struct {
+	store.Store
+	ids []string
+}
+
+func (p *purgeOrder) Purge(ctx context.Context, id string) error {
+	p.ids = append(p.ids, id)
+	return p.Store.Purge(ctx, id)
+}
+
+func TestPurgeSubtree(t *testing.T) {
+	ctx := context.Background()
+	s := &purgeOrder{Store: store.New()}
+	api := handlers.NewAPI(s, &models.NoOpLogger{})
+	r := hierarchyRouter(api)
+	parent := createTaskViaAPI(t, api, `{"title": "Parent", "status": "pending"}`)
+	child := createTaskViaAPI(t, api, `{"title": "Child", "status": "pending", "parent_id": "`+parent.ID+`"}`)
+	grandchild := createTaskViaAPI(t, api, `{"title": "Grandchild", "status": "pending", "parent_id": "`+child.ID+`"}`)
+	trashed := createTaskViaAPI(t, api, `{"title": "Trashed", "status": "pending", "parent_id": "`+parent.ID+`"}`)
+	s.Delete(ctx, grandchild.ID)
+	s.Delete(ctx, trashed.ID)
+
+	if w := serve(r, "DELETE", "/tasks/missing?purge=true&cascade=true", "", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a missing task, got %d", w.Code)
+	}
+	if w := serve(r, "DELETE", "/tasks/"+parent.ID+"?purge=true&cascade=true", "", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", w.Code, w.Body)
+	}
+	// As subtarefas, inclusive as que estavam na lixeira, saem antes; a raiz por último
+	position := map[string]int{}
+	for i, id := range s.ids {
+		position[id] = i
+	}
+	if len(s.ids) != 4 || s.ids[3] != parent.ID || position[grandchild.ID] > position[child.ID] {
+		t.Errorf("expected descendants first and the root last, got %v", s.ids)
+	}
+	trash, _ := s.Query(ctx, models.TaskQuery{Deleted: true})
+	if trash.Total != 0 {
+		t.Errorf("expected the trashed subtasks to be purged, got %+v", trash.Tasks)
+	}
+}
+
+func TestListSubtasksAndTree(t *testing.T) {
+	api := handlers.NewAPI(store.New(), &models.NoOpLogger{})
+	r := hierarchyRouter(api)
+	root := createTaskViaAPI(t, api, `{"title": "Root", "status": "pending"}`)
+	first := createTaskViaAPI(t, api, `{"title": "First", "status": "pending", "parent_id": "`+root.ID+`"}`)
+	createTaskViaAPI(t, api, `{"title": "Second", "status": "completed", "parent_id": "`+root.ID+`"}`)
+	createTaskViaAPI(t, api, `{"title": "Nested", "status": "pending", "parent_id": "`+first.ID+`"}`)
+	createTaskViaAPI(t, api, `{"title": "Lonely", "status": "pending"}`)
+
+	t.Run("direct subtasks", func(t *testing.T) {
+		w := serve(r, "GET", "/tasks/"+root.ID+"/subtasks?status=pending", "", "")
+		var resp models.TaskListResponse
+		json.NewDecoder(w.Body).Decode(&resp)
+		if w.Code != http.StatusOK || resp.TotalItems != 1 || resp.Tasks[0].ID != first.ID {
+			t.Errorf("expected the pending direct subtask, got %d %+v", w.Code, resp)
+		}
+		if w := serve(r, "GET", "/tasks/missing/subtasks", "", ""); w.Code != http.StatusNotFound {
+			t.Errorf("expected 404, got %d", w.Code)
+		}
+	})
+
+	t.Run("tree", func(t *testing.T) {
+		w := serve(r, "GET", "/tasks?tree=true", "", "")
+		var resp models.TaskTreeResponse
+		json.NewDecoder(w.Body).Decode(&resp)
+		if w.Code != http.StatusOK || resp.TotalItems != 2 {
+			t.Fatalf("expected 2 root tasks, got %d %+v", w.Code, resp)
+		}
+		top := resp.Tasks[0]
+		if top.ID != root.ID || len(top.Subtasks) != 2 || top.Subtasks[0].ID != first.ID {
+			t.Fatalf("unexpected tree: %+v", top)
+		}
+		if len(top.Subtasks[0].Subtasks) != 1 || top.Subtasks[0].Subtasks[0].Title != "Nested" {
+			t.Errorf("expected the nested subtask, got %+v", top.Subtasks[0])
+		}
+		if len(resp.Tasks[1].Subtasks) != 0 {
+			t.Errorf("expected a leaf, got %+v", resp.Tasks[1])
+		}
+	})
+
+	t.Run("roots filter", func(t *testing.T) {
+		if resp := listTasksViaAPI(t, api, "?parent_id=null"); resp.TotalItems != 2 {
+			t.Errorf("expected 2 root tasks, got %d", resp.TotalItems)
+		}
+	})
+
+	t.Run("tree is not available in the trash", func(t *testing.T) {
+		if w := serve(r, "GET", "/tasks/trash?tree=true", "", ""); w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d", w.Code)
+		}
+	})
+}
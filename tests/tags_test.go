@@ -0,0 +1,142 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"example.com/tasksapi/handlers"
+	"example.com/tasksapi/models"
+	"example.com/tasksapi/store"
+)
+
+func tagRouter(api *handlers.API) *mux.Router {
+	r := mux.NewRouter()
+	r.HandleFunc("/tasks", api.CreateTask).Methods("POST")
+	r.HandleFunc("/tasks", api.ListTasks).Methods("GET")
+	r.HandleFunc("/tasks/{id}", api.UpdateTask).Methods("PUT")
+	r.HandleFunc("/tasks/{id}", api.PatchTask).Methods("PATCH")
+	r.HandleFunc("/tasks/{id}", api.DeleteTask).Methods("DELETE")
+	r.HandleFunc("/tags", api.ListTags).Methods("GET")
+	return r
+}
+
+func TestTagNormalization(t *testing.T) {
+	api := handlers.NewAPI(store.New(), &models.NoOpLogger{})
+	r := tagRouter(api)
+
+	task := createTaskViaAPI(t, api, `{"title": "Tagged", "status": "pending", "tags": [" Backend", "backend", "URGENT"]}`)
+	if !reflect.DeepEqual(task.Tags, []string{"backend", "urgent"}) {
+		t.Errorf("expected normalized tags, got %v", task.Tags)
+	}
+
+	w := serve(r, "PATCH", "/tasks/"+task.ID, models.MergePatchContentType, `{"tags": ["Ops", "ops", "v1.2"]}`)
+	var patched models.Task
+	json.NewDecoder(w.Body).Decode(&patched)
+	if w.Code != http.StatusOK || !reflect.DeepEqual(patched.Tags, []string{"ops", "v1.2"}) {
+		t.Errorf("expected patched tags, got %d %v", w.Code, patched.Tags)
+	}
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"blank tag", `{"title": "Bad tags", "status": "pending", "tags": ["  "]}`},
+		{"comma", `{"title": "Bad tags", "status": "pending", "tags": ["a,b"]}`},
+		{"space", `{"title": "Bad tags", "status": "pending", "tags": ["two words"]}`},
+		{"not a string", `{"title": "Bad tags", "status": "pending", "tags": [1]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := serve(r, "POST", "/tasks", "application/json", tt.body); w.Code != http.StatusBadRequest {
+				t.Errorf("expected 400, got %d", w.Code)
+			}
+		})
+	}
+
+	t.Run("clear", func(t *testing.T) {
+		w := serve(r, "PATCH", "/tasks/"+task.ID, models.MergePatchContentType, `{"tags": []}`)
+		var cleared models.Task
+		json.NewDecoder(w.Body).Decode(&cleared)
+		if w.Code != http.StatusOK || cleared.Tags != nil {
+			t.Errorf("expected no tags, got %d %v", w.Code, cleared.Tags)
+		}
+	})
+}
+
+func TestUpdateTaskFormTags(t *testing.T) {
+	api := handlers.NewAPI(store.New(), &models.NoOpLogger{})
+	r := tagRouter(api)
+	task := createTaskViaAPI(t, api, `{"title": "Form tags", "status": "pending", "tags": ["backend"]}`)
+
+	form := "application/x-www-form-urlencoded"
+	w := serve(r, "PUT", "/tasks/"+task.ID, form, "title=Form+tags&status=pending&tags=Backend&tags=urgent")
+	var updated models.Task
+	json.NewDecoder(w.Body).Decode(&updated)
+	if w.Code != http.StatusOK || !reflect.DeepEqual(updated.Tags, []string{"backend", "urgent"}) {
+		t.Errorf("expected form PUT to replace the tags, got %d %v", w.Code, updated.Tags)
+	}
+
+	// Sem o campo, o PUT limpa as tags como os demais campos opcionais
+	w = serve(r, "PUT", "/tasks/"+task.ID, form, "title=Form+tags&status=pending")
+	updated = models.Task{}
+	json.NewDecoder(w.Body).Decode(&updated)
+	if w.Code != http.StatusOK || len(updated.Tags) != 0 {
+		t.Errorf("expected form PUT without tags to clear them, got %d %v", w.Code, updated.Tags)
+	}
+}
+
+func TestFilterByTags(t *testing.T) {
+	api := handlers.NewAPI(store.New(), &models.NoOpLogger{})
+	r := tagRouter(api)
+	createTaskViaAPI(t, api, `{"title": "Backend urgent", "status": "pending", "tags": ["backend", "urgent"]}`)
+	createTaskViaAPI(t, api, `{"title": "Backend", "status": "pending", "tags": ["backend"]}`)
+	createTaskViaAPI(t, api, `{"title": "Frontend", "status": "pending", "tags": ["frontend"]}`)
+	createTaskViaAPI(t, api, `{"title": "Untagged", "status": "pending"}`)
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"?tag=Backend", 2},
+		{"?tag=backend&tag=urgent", 2},
+		{"?tag=backend,frontend", 3},
+		{"?tag=backend&tag=urgent&tag_match=all", 1},
+		{"?tag!=urgent", 3},
+		{"?tag=null", 1},
+		{"?tag=backend&tag!=urgent", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if resp := listTasksViaAPI(t, api, tt.query); resp.TotalItems != tt.want {
+				t.Errorf("expected %d tasks, got %d", tt.want, resp.TotalItems)
+			}
+		})
+	}
+
+	for _, query := range []string{"?tag=a&tag_match=some", "?tag=null&tag=x&tag_match=all", "?tag=bad%20tag"} {
+		if w := serve(r, "GET", "/tasks"+query, "", ""); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, w.Code)
+		}
+	}
+}
+
+func TestListTags(t *testing.T) {
+	api := handlers.NewAPI(store.New(), &models.NoOpLogger{})
+	r := tagRouter(api)
+	createTaskViaAPI(t, api, `{"title": "First", "status": "pending", "tags": ["ops", "backend"]}`)
+	createTaskViaAPI(t, api, `{"title": "Second", "status": "pending", "tags": ["backend"]}`)
+	deleted := createTaskViaAPI(t, api, `{"title": "Deleted", "status": "pending", "tags": ["backend", "legacy"]}`)
+	serve(r, "DELETE", "/tasks/"+deleted.ID, "", "")
+
+	w := serve(r, "GET", "/tags", "", "")
+	var resp models.TagListResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	want := []models.TagCount{{Tag: "backend", Count: 2}, {Tag: "ops", Count: 1}}
+	if w.Code != http.StatusOK || resp.TotalItems != 2 || !reflect.DeepEqual(resp.Tags, want) {
+		t.Errorf("expected %v, got %d %+v", want, w.Code, resp)
+	}
+}